@@ -0,0 +1,32 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package earlybird
+
+const (
+	earlybirdConfigFile = "earlybird.json"
+	rulesDir            = "rules"
+	falsePositivesDir   = "falsepositives"
+	labelsDir           = "labels"
+	solutionsDir        = "solutions"
+
+	// Same defaults as the CLI flags
+	defaultWorkerCount = 100
+	defaultReaderCount = 4
+	defaultWorkLength  = 2500
+	defaultMaxFileSize = 10240000
+	defaultVerifyRate  = 5
+)
@@ -0,0 +1,213 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package earlybird embeds the Earlybird secret scanner in Go programs. Unlike the CLI it reads no flags,
+// never exits the process and returns errors to the caller.
+//
+//	scanner, err := earlybird.New(earlybird.WithConfigDir("/etc/earlybird"), earlybird.WithModules("password-secret"))
+//	if err != nil {
+//		return err
+//	}
+//	report, err := scanner.ScanPath(ctx, "./src")
+package earlybird
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/americanexpress/earlybird/v4/pkg/buildflags"
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/file"
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+	"github.com/americanexpress/earlybird/v4/pkg/utils"
+)
+
+// New loads the Earlybird configuration and rules and returns a Scanner using them
+func New(opts ...Option) (*Scanner, error) {
+	o := options{
+		workerCount: defaultWorkerCount,
+		readerCount: defaultReaderCount,
+		maxFileSize: defaultMaxFileSize,
+		verifyRate:  defaultVerifyRate,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.configDir == "" {
+		o.configDir = utils.GetConfigDir()
+	}
+
+	var settings cfgreader.Configs
+	if err := cfgreader.LoadConfig(&settings, filepath.Join(o.configDir, earlybirdConfigFile)); err != nil {
+		return nil, fmt.Errorf("failed to load Earlybird config: %v", err)
+	}
+
+	cfg := cfgreader.EarlybirdConfig{
+		Version:                    buildflags.Version,
+		ConfigDir:                  o.configDir,
+		RulesConfigDir:             filepath.Join(o.configDir, rulesDir),
+		FalsePositivesConfigDir:    filepath.Join(o.configDir, falsePositivesDir),
+		LabelsConfigDir:            filepath.Join(o.configDir, labelsDir),
+		SolutionsConfigDir:         filepath.Join(o.configDir, solutionsDir),
+		LevelMap:                   settings.GetLevelMap(),
+		AnnotationsToSkipLine:      settings.AnnotationsToSkip,
+		ExtensionsToSkipScan:       settings.ExtensionsToSkipTextScan,
+		AdjustedSeverityCategories: settings.AdjustedSeverityCategories,
+		WorkerCount:                o.workerCount,
+		ReaderCount:                o.readerCount,
+		ShardFiles:                 o.shardFiles,
+		WorkLength:                 defaultWorkLength,
+		MaxFileSize:                o.maxFileSize,
+		Timeout:                    o.timeout,
+		IgnoreFile:                 o.ignoreFile,
+		UseGitignore:               o.useGitignore,
+		BaselineFile:               o.baselineFile,
+		SuppressionsFile:           o.suppressionsFile,
+		Verify:                     o.verify,
+		VerifyEndpointOverride:     o.verifyEndpointOverride,
+		VerifyRate:                 o.verifyRate,
+		VerifyTrustedHosts:         settings.VerifyTrustedHosts,
+		Suppress:                   o.suppress,
+		SkipComments:               o.skipComments,
+		IgnoreFPRules:              o.ignoreFPRules,
+		ShowSolutions:              o.showSolutions,
+		VerboseEnabled:             o.verbose,
+		OutputFormat:               "json",
+		HideMeta:                   true,
+	}
+
+	// Unset levels fall back to the thresholds of earlybird.json, like the CLI flag defaults
+	var err error
+	levels := []struct {
+		name, fallback string
+		level          *int
+	}{
+		{o.displaySeverity, settings.TranslateLevelID(settings.DisplayThreshold), &cfg.SeverityDisplayLevel},
+		{o.displayConfidence, settings.TranslateLevelID(settings.DisplayConfidenceThreshold), &cfg.ConfidenceDisplayLevel},
+	}
+	for _, l := range levels {
+		if l.name == "" {
+			l.name = l.fallback
+		}
+		var ok bool
+		if *l.level, ok = cfg.LevelMap[l.name]; !ok {
+			return nil, fmt.Errorf("unknown level %q, use one of %s", l.name, utils.GetDisplayList(settings.GetLevelNames()))
+		}
+	}
+
+	// The rule sources of earlybird.json are layered over the config directory like for the CLI
+	if cfg.RuleSources, err = cfgreader.ResolveRuleSources(o.configDir, settings.RuleSources); err != nil {
+		return nil, fmt.Errorf("error in rule_sources: %v", err)
+	}
+	cfg.RuleModulesFilenameMap, cfg.AvailableModules, err = cfgreader.GetRuleModules(o.configDir, cfg.RuleSources)
+	if err != nil {
+		return nil, fmt.Errorf("error getting rule modules: %v", err)
+	}
+	for _, moduleName := range o.modules {
+		if _, ok := cfg.RuleModulesFilenameMap[moduleName]; !ok {
+			return nil, fmt.Errorf("unknown module %q, use one of %s", moduleName, utils.GetDisplayList(cfg.AvailableModules))
+		}
+	}
+	cfg.EnabledModulesMap = utils.GetEnabledModulesMap(o.modules, cfg.RuleModulesFilenameMap)
+	for moduleName := range cfg.EnabledModulesMap {
+		cfg.EnabledModules = append(cfg.EnabledModules, moduleName)
+	}
+
+	engine, err := scan.NewEngine(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Scanner{config: cfg, engine: engine}, nil
+}
+
+// ScanPath scans the file or every file below the directory at path, honouring .ge_ignore files
+func (s *Scanner) ScanPath(ctx context.Context, path string) (scan.Report, error) {
+	start := time.Now()
+	if _, err := os.Stat(path); err != nil {
+		return scan.Report{}, err
+	}
+	cfg := s.config
+	cfg.SearchDir = path
+	fileContext, err := file.GetFiles(path, cfg.IgnoreFile, cfg.UseGitignore, cfg.VerboseEnabled, cfg.MaxFileSize)
+	if err != nil {
+		return scan.Report{}, fmt.Errorf("failed to load scan files: %v", err)
+	}
+	return s.search(ctx, &cfg, fileContext, start)
+}
+
+// ScanReader scans the content of r as a file called name, the name decides which rules apply
+func (s *Scanner) ScanReader(ctx context.Context, name string, r io.Reader) (scan.Report, error) {
+	start := time.Now()
+	cfg := s.config
+	curFile, err := file.ReaderToScanFile(name, r, &cfg)
+	if err != nil {
+		return scan.Report{}, fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	return s.search(ctx, &cfg, file.Context{Files: []scan.File{curFile}}, start)
+}
+
+// ScanBytes scans content as a file called name, the name decides which rules apply
+func (s *Scanner) ScanBytes(ctx context.Context, name string, content []byte) (scan.Report, error) {
+	return s.ScanReader(ctx, name, bytes.NewReader(content))
+}
+
+// search runs the engine over the files and collects the hits into a report. If ctx ends first the hits
+// found so far are returned in a truncated report along with the context error.
+func (s *Scanner) search(ctx context.Context, cfg *cfgreader.EarlybirdConfig, fileContext file.Context, start time.Time) (scan.Report, error) {
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	fileContext.Suppressions = &scan.SuppressionLog{}
+	ctx = scan.WithSuppressions(ctx, fileContext.Suppressions)
+	fileContext.Unread = &scan.SkipLog{}
+	ctx = scan.WithSkipLog(ctx, fileContext.Unread)
+	var hits []scan.Hit
+	hitChannel := make(chan scan.Hit)
+	go s.engine.SearchFiles(ctx, cfg, fileContext.Files, fileContext.CompressPaths, fileContext.ConvertPaths, hitChannel)
+	for hit := range hitChannel {
+		hits = append(hits, hit)
+	}
+
+	report := scan.Report{
+		Hits:          hits,
+		HitCount:      len(hits),
+		Truncated:     cfg.ScanTruncated,
+		Commits:       scan.HitCommits(hits),
+		Skipped:       fileContext.Skipped(),
+		Ignore:        fileContext.IgnorePatterns,
+		Version:       cfg.Version,
+		Modules:       cfg.EnabledModules,
+		Threshold:     cfg.SeverityDisplayLevel,
+		FilesScanned:  len(fileContext.Files),
+		RulesObserved: len(s.engine.Rules),
+		StartTime:     start.UTC().Format(time.RFC3339),
+		EndTime:       time.Now().UTC().Format(time.RFC3339),
+		Duration:      fmt.Sprintf("%d ms", time.Since(start)/time.Millisecond),
+		Suppressed:    fileContext.Suppressions.Entries(),
+	}
+	if cfg.ScanTruncated {
+		return report, ctx.Err()
+	}
+	return report, nil
+}
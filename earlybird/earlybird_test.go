@@ -0,0 +1,235 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package earlybird
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/americanexpress/earlybird/v4/pkg/file"
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+)
+
+const (
+	testConfigDir = "../config"
+	secretLine    = "password = 'SecretValue1673'\n"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{
+			name: "Load all modules",
+			opts: []Option{WithConfigDir(testConfigDir)},
+		},
+		{
+			name: "Load selected modules and levels",
+			opts: []Option{WithConfigDir(testConfigDir), WithModules("password-secret"), WithDisplaySeverity("high"), WithDisplayConfidence("low")},
+		},
+		{
+			name:    "Reject a missing config dir",
+			opts:    []Option{WithConfigDir(filepath.Join(testConfigDir, "missing"))},
+			wantErr: true,
+		},
+		{
+			name:    "Reject an unknown module",
+			opts:    []Option{WithConfigDir(testConfigDir), WithModules("unknown")},
+			wantErr: true,
+		},
+		{
+			name:    "Reject an unknown level",
+			opts:    []Option{WithConfigDir(testConfigDir), WithDisplaySeverity("urgent")},
+			wantErr: true,
+		},
+		{
+			name:    "Reject a missing baseline",
+			opts:    []Option{WithConfigDir(testConfigDir), WithBaseline(filepath.Join(t.TempDir(), "missing.json"))},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, err := New(tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(scanner.engine.Rules) == 0 {
+				t.Errorf("New() loaded no rules")
+			}
+		})
+	}
+}
+
+func TestScanner_Scan(t *testing.T) {
+	scanner, err := New(WithConfigDir(testConfigDir), WithModules("password-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err = os.WriteFile(filepath.Join(dir, "config.py"), []byte("host = 'localhost'\n"+secretLine), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name          string
+		scan          func(ctx context.Context) (scan.Report, error)
+		wantHits      int
+		wantTruncated bool
+		wantErr       bool
+	}{
+		{
+			name: "Scan a directory",
+			scan: func(ctx context.Context) (scan.Report, error) {
+				return scanner.ScanPath(ctx, dir)
+			},
+			wantHits: 1,
+		},
+		{
+			name: "Scan a reader",
+			scan: func(ctx context.Context) (scan.Report, error) {
+				return scanner.ScanReader(ctx, "config.py", strings.NewReader("host = 'localhost'\n"+secretLine))
+			},
+			wantHits: 1,
+		},
+		{
+			name: "Scan bytes without secrets",
+			scan: func(ctx context.Context) (scan.Report, error) {
+				return scanner.ScanBytes(ctx, "config.py", []byte("host = 'localhost'\n"))
+			},
+		},
+		{
+			name: "Return an error for a missing path",
+			scan: func(ctx context.Context) (scan.Report, error) {
+				return scanner.ScanPath(ctx, filepath.Join(dir, "missing"))
+			},
+			wantErr: true,
+		},
+		{
+			name: "Return a truncated report once the context is done",
+			scan: func(ctx context.Context) (scan.Report, error) {
+				return scanner.ScanBytes(cancelled, "config.py", []byte(secretLine))
+			},
+			wantTruncated: true,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := tt.scan(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scan error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if report.Truncated != tt.wantTruncated {
+				t.Errorf("scan truncated = %v, want %v", report.Truncated, tt.wantTruncated)
+			}
+			if !tt.wantTruncated && report.HitCount != tt.wantHits {
+				t.Errorf("scan found %d hits, want %d: %+v", report.HitCount, tt.wantHits, report.Hits)
+			}
+			for _, hit := range report.Hits {
+				if !strings.HasSuffix(hit.Filename, "config.py") {
+					t.Errorf("scan hit in %s, want config.py", hit.Filename)
+				}
+			}
+		})
+	}
+}
+
+func TestScanner_search_unreadableFile(t *testing.T) {
+	scanner, err := New(WithConfigDir(testConfigDir), WithModules("password-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	readable, missing := filepath.Join(dir, "config.py"), filepath.Join(dir, "removed.py")
+	if err = os.WriteFile(readable, []byte(secretLine), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The file is listed but gone by the time the scan opens it
+	cfg := scanner.config
+	cfg.SearchDir = dir
+	files := []scan.File{{Name: "config.py", Path: readable}, {Name: "removed.py", Path: missing}}
+	report, err := scanner.search(context.Background(), &cfg, file.Context{Files: files}, time.Now())
+	if err != nil {
+		t.Fatalf("search() error = %v", err)
+	}
+	if report.HitCount != 1 {
+		t.Errorf("search() found %d hits, want 1", report.HitCount)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0] != missing {
+		t.Errorf("search() skipped %v, want [%s]", report.Skipped, missing)
+	}
+}
+
+func TestNew_ruleSources(t *testing.T) {
+	configDir := t.TempDir()
+	if err := os.CopyFS(configDir, os.DirFS(testConfigDir)); err != nil {
+		t.Fatal(err)
+	}
+	settingsPath := filepath.Join(configDir, earlybirdConfigFile)
+	content, err := os.ReadFile(settingsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var settings map[string]interface{}
+	if err = json.Unmarshal(content, &settings); err != nil {
+		t.Fatal(err)
+	}
+	settings["rule_sources"] = []map[string]interface{}{{"name": "team", "type": "dir", "path": "team", "priority": 10}}
+	if content, err = json.Marshal(settings); err != nil {
+		t.Fatal(err)
+	}
+	rule := "rules:\n  - Code: %d\n    Pattern: \"token=\\\\w+\"\n    Caption: %s\n    Severity: 2\n    Confidence: 2\n"
+	files := map[string]string{
+		settingsPath: string(content),
+		filepath.Join(configDir, "team", "rules", "password-secret.yaml"): fmt.Sprintf(rule, 3001, "Team password"),
+		filepath.Join(configDir, "team", "rules", "team.yaml"):            fmt.Sprintf(rule, 9100, "Team token"),
+	}
+	for name, content := range files {
+		if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scanner, err := New(WithConfigDir(configDir), WithModules("password-secret", "team"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	captions := make(map[int][]string)
+	for _, rule := range scanner.engine.Rules {
+		captions[rule.Code] = append(captions[rule.Code], rule.Caption)
+	}
+	want := map[int]string{3001: "Team password", 9100: "Team token"}
+	for code, caption := range want {
+		if len(captions[code]) != 1 || captions[code][0] != caption {
+			t.Errorf("New() rules %d = %v, want [%s]", code, captions[code], caption)
+		}
+	}
+}
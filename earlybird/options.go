@@ -0,0 +1,162 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package earlybird
+
+import "time"
+
+// WithConfigDir loads earlybird.json and the rules, false positives, labels and solutions from dir instead of the
+// default Earlybird config directory
+func WithConfigDir(dir string) Option {
+	return func(o *options) {
+		o.configDir = dir
+	}
+}
+
+// WithModules only enables the named rule modules (e.g. content, password-secret), by default all modules are enabled
+func WithModules(modules ...string) Option {
+	return func(o *options) {
+		o.modules = append(o.modules, modules...)
+	}
+}
+
+// WithDisplaySeverity sets the lowest severity level (e.g. high) reported
+func WithDisplaySeverity(level string) Option {
+	return func(o *options) {
+		o.displaySeverity = level
+	}
+}
+
+// WithDisplayConfidence sets the lowest confidence level (e.g. medium) reported
+func WithDisplayConfidence(level string) Option {
+	return func(o *options) {
+		o.displayConfidence = level
+	}
+}
+
+// WithWorkers sets the number of workers matching lines against the rules
+func WithWorkers(count int) Option {
+	return func(o *options) {
+		o.workerCount = count
+	}
+}
+
+// WithReaders sets the number of files read at the same time
+func WithReaders(count int) Option {
+	return func(o *options) {
+		o.readerCount = count
+	}
+}
+
+// WithFileSharding has each worker read and scan whole files, the readers setting is then not used
+func WithFileSharding() Option {
+	return func(o *options) {
+		o.shardFiles = true
+	}
+}
+
+// WithMaxFileSize skips files larger than size bytes when scanning a path
+func WithMaxFileSize(size int64) Option {
+	return func(o *options) {
+		o.maxFileSize = size
+	}
+}
+
+// WithTimeout stops every scan after the timeout, the hits found until then are returned in a truncated report
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithIgnoreFile reads extra ignore patterns (like .ge_ignore) for path scans from the file
+func WithIgnoreFile(path string) Option {
+	return func(o *options) {
+		o.ignoreFile = path
+	}
+}
+
+// WithGitignore also leaves out the files matched by the .gitignore files of scanned directories
+func WithGitignore() Option {
+	return func(o *options) {
+		o.useGitignore = true
+	}
+}
+
+// WithBaseline leaves out the hits whose fingerprints are recorded in the baseline file
+func WithBaseline(path string) Option {
+	return func(o *options) {
+		o.baselineFile = path
+	}
+}
+
+// WithSuppressionsFile applies the suppressions file to every scan, instead of the one at the root of a scanned directory
+func WithSuppressionsFile(path string) Option {
+	return func(o *options) {
+		o.suppressionsFile = path
+	}
+}
+
+// WithVerify checks found credentials against their issuing service, at most rate requests per second.
+// Only use it when sending the credentials over the network is acceptable.
+func WithVerify(rate int) Option {
+	return func(o *options) {
+		o.verify = true
+		o.verifyRate = rate
+	}
+}
+
+// WithVerifyEndpointOverride sends the verification requests to endpoint instead of the real services
+func WithVerifyEndpointOverride(endpoint string) Option {
+	return func(o *options) {
+		o.verifyEndpointOverride = endpoint
+	}
+}
+
+// WithSuppress masks the secret values in the reported hits
+func WithSuppress() Option {
+	return func(o *options) {
+		o.suppress = true
+	}
+}
+
+// WithSkipComments skips comment lines in the content module
+func WithSkipComments() Option {
+	return func(o *options) {
+		o.skipComments = true
+	}
+}
+
+// WithoutFalsePositiveRules reports hits that the false positive rules would drop
+func WithoutFalsePositiveRules() Option {
+	return func(o *options) {
+		o.ignoreFPRules = true
+	}
+}
+
+// WithSolutions adds the recommended solution to each hit
+func WithSolutions() Option {
+	return func(o *options) {
+		o.showSolutions = true
+	}
+}
+
+// WithVerbose logs the files read and the ignore patterns used
+func WithVerbose() Option {
+	return func(o *options) {
+		o.verbose = true
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package earlybird
+
+import (
+	"time"
+
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+)
+
+// Scanner searches paths, readers and byte slices for secrets with the rules it was created with.
+// A Scanner is safe for concurrent use.
+type Scanner struct {
+	config cfgreader.EarlybirdConfig
+	engine *scan.Engine
+}
+
+// Option configures a Scanner created with New
+type Option func(*options)
+
+// options are the raw Option values, level names are only resolved once earlybird.json is loaded
+type options struct {
+	configDir              string
+	modules                []string
+	displaySeverity        string
+	displayConfidence      string
+	workerCount            int
+	readerCount            int
+	shardFiles             bool
+	maxFileSize            int64
+	timeout                time.Duration
+	ignoreFile             string
+	baselineFile           string
+	useGitignore           bool
+	suppressionsFile       string
+	verify                 bool
+	verifyEndpointOverride string
+	verifyRate             int
+	suppress               bool
+	skipComments           bool
+	ignoreFPRules          bool
+	showSolutions          bool
+	verbose                bool
+}
@@ -38,6 +38,9 @@ func main() {
 	ptr.HTTPS = flag.String("https", "", "Listen IP and Port for HTTPS/2 API e.g. 127.0.0.1:8080 (Don't forget the https-cert and https-key flags)")
 	ptr.HTTPSCert = flag.String("https-cert", "", "Certificate file for TLS")
 	ptr.HTTPSKey = flag.String("https-key", "", "Private key file for TLS")
+	ptr.HTTPSClientCA = flag.String("https-client-ca", "", "CA certificates file for verifying TLS client certificates (mTLS)")
+	ptr.HTTPAuthFile = flag.String("http-auth-file", "", "Clients file with the API keys, HMAC secrets and client certificates allowed to use the HTTP API, and their scopes")
+	ptr.HTTPMetrics = flag.Bool("http-metrics", false, "Serve the scan metrics on /metrics of the HTTP API in the Prometheus exposition format")
 	//Define Git cli params
 	gitcfg.Project = flag.String("git-project", "", "Full URL to a github organization to scan e.g. github.com/org")
 	gitcfg.Repo = flag.String("git", "", "Full URL to a git repo to scan e.g. github.com/user/repo")
@@ -17,6 +17,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -33,6 +34,26 @@ import (
 
 //Scan uses the Earlybird config to search uploaded multipart files for secrets
 func Scan(cfg cfgreader.EarlybirdConfig) http.HandlerFunc {
+	return scanUpload(cfg, writeReport)
+}
+
+//ScanStream is Scan sending every hit as soon as it is found, followed by a summary, see streamHits
+func ScanStream(cfg cfgreader.EarlybirdConfig) http.HandlerFunc {
+	return scanUpload(cfg, streamHits)
+}
+
+//GITScan searches for secrets in git repositories based off the Earlybird config, supports authentication via env variables "gituser" and "gitpassword"
+func GITScan(cfg cfgreader.EarlybirdConfig) http.HandlerFunc {
+	return scanGit(cfg, writeReport)
+}
+
+//GITScanStream is GITScan sending every hit as soon as it is found, followed by a summary, see streamHits
+func GITScanStream(cfg cfgreader.EarlybirdConfig) http.HandlerFunc {
+	return scanGit(cfg, streamHits)
+}
+
+//scanUpload scans the uploaded multipart files and hands the hits to respond
+func scanUpload(cfg cfgreader.EarlybirdConfig, respond hitResponder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		err := r.ParseMultipartForm(1024 << 20) // 1GB upload limit
 		if err != nil {
@@ -40,6 +61,15 @@ func Scan(cfg cfgreader.EarlybirdConfig) http.HandlerFunc {
 			return
 		}
 		start := time.Now()
+		mycfg := cfg
+
+		//Stop scanning when the client goes away or the timeout passes
+		ctx, cancel, err := scanContext(r, cfg.Timeout)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer cancel()
 
 		//Get files from req
 		formdata := r.MultipartForm
@@ -49,44 +79,17 @@ func Scan(cfg cfgreader.EarlybirdConfig) http.HandlerFunc {
 			return
 		}
 
-		// Define our result objects and start scan process
-		var Hits []scan.Hit
+		// Start the scan process, the responder reads the hits
+		fileContext := file.Context{Files: fileList, Suppressions: &scan.SuppressionLog{}}
 		HitChannel := make(chan scan.Hit)
-		go scan.SearchFiles(&cfg, fileList, []string{}, []string{}, HitChannel)
-
-		for hit := range HitChannel {
-			Hits = append(Hits, hit)
-		}
-
-		//Format our results into an Earlybird report
-		report := scan.Report{
-			Hits:          Hits,
-			HitCount:      len(Hits),
-			Version:       cfg.Version,
-			Modules:       cfg.EnabledModules,
-			Threshold:     cfg.SeverityDisplayLevel,
-			FilesScanned:  len(fileList),
-			RulesObserved: len(scan.CombinedRules),
-			StartTime:     start.UTC().Format(time.RFC3339),
-			EndTime:       time.Now().UTC().Format(time.RFC3339),
-			Duration:      fmt.Sprintf("%d ms", time.Since(start)/time.Millisecond),
-		}
-
-		//Encode and send JSON response
-		response, err := json.MarshalIndent(report, "", "\t")
-		if err != nil {
-			http.Error(w, "Failed to encode JSON response: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-		fmt.Fprint(w, string(response))
+		go scan.SearchFiles(scan.WithSuppressions(ctx, fileContext.Suppressions), &mycfg, fileList, []string{}, []string{}, HitChannel)
+		respond(w, r, &mycfg, fileContext, HitChannel, start)
 	}
 }
 
-//GITScan searches for secrets in git repositories based off the Earlybird config, supports authentication via env variables "gituser" and "gitpassword"
-func GITScan(cfg cfgreader.EarlybirdConfig) http.HandlerFunc {
+//scanGit clones the repository in the `url` query param, scans it and hands the hits to respond
+func scanGit(cfg cfgreader.EarlybirdConfig, respond hitResponder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var err error
-		var blank string
 		start := time.Now()
 		mycfg := cfg
 
@@ -96,58 +99,115 @@ func GITScan(cfg cfgreader.EarlybirdConfig) http.HandlerFunc {
 			return
 		}
 
-		giturl := giturls[0]
+		ctx, cancel, err := scanContext(r, cfg.Timeout)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer cancel()
+
+		//Only clone repositories the git URL policy of webserver.json allows
+		giturl, err := git.CheckCloneURL(ctx, giturls[0], cfg.GitURLPolicy)
+		if err != nil {
+			http.Error(w, "Repository URL not allowed: "+err.Error(), http.StatusBadRequest)
+			return
+		}
 		gitbranch := r.URL.Query().Get("branch")
-		utils.GetGitURL(&giturl, &blank)
 		mycfg.SearchDir, err = git.CloneGitRepos([]string{giturl}, os.Getenv("gituser"), os.Getenv("gitpassword"), gitbranch, (cfg.OutputFormat == "json"))
+		//Delete our tmp directory when done
+		defer utils.DeleteGit(giturl, mycfg.SearchDir)
 		if err != nil {
 			if err == transport.ErrAuthenticationRequired {
 				http.Error(w, "Failed to clone, repository is private. Please enter a public repository URL.", http.StatusInternalServerError)
 			} else {
 				http.Error(w, "Failed to clone, please verify your repository is available", http.StatusInternalServerError)
 			}
-			utils.DeleteGit(giturl, mycfg.SearchDir)
 			return
 		}
 
-		fileContext, err := file.GetFiles(mycfg.SearchDir, mycfg.IgnoreFile, mycfg.VerboseEnabled, cfg.MaxFileSize)
+		fileContext, err := file.GetFiles(mycfg.SearchDir, mycfg.IgnoreFile, mycfg.UseGitignore, mycfg.VerboseEnabled, cfg.MaxFileSize)
 		if err != nil {
 			http.Error(w, "Failed to load scan files: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		//Delete our tmp directory when done
-		defer utils.DeleteGit(giturl, mycfg.SearchDir)
-		// Start building a list of hits.  The module go routines will all dump back to this
-		var Hits []scan.Hit
+		fileContext.Suppressions = &scan.SuppressionLog{}
+		fileContext.Unread = &scan.SkipLog{}
+		ctx = scan.WithSkipLog(scan.WithSuppressions(ctx, fileContext.Suppressions), fileContext.Unread)
+		// The module go routines will all dump back to this channel
 		HitChannel := make(chan scan.Hit)
 		//Create pointer to reduce memory overhead
-		go scan.SearchFiles(&mycfg, fileContext.Files, fileContext.CompressPaths, fileContext.ConvertPaths, HitChannel)
+		go scan.SearchFiles(ctx, &mycfg, fileContext.Files, fileContext.CompressPaths, fileContext.ConvertPaths, HitChannel)
+		respond(w, r, &mycfg, fileContext, HitChannel, start)
+	}
+}
 
-		for hit := range HitChannel {
-			Hits = append(Hits, hit)
-		}
+//writeReport collects all the hits and responds with the Earlybird report once the scan is done
+func writeReport(w http.ResponseWriter, _ *http.Request, cfg *cfgreader.EarlybirdConfig, fileContext file.Context, hits <-chan scan.Hit, start time.Time) {
+	var Hits []scan.Hit
+	for hit := range hits {
+		Hits = append(Hits, hit)
+	}
 
-		report := scan.Report{
-			Hits:          Hits,
-			HitCount:      len(Hits),
-			Skipped:       fileContext.SkippedFiles,
-			Ignore:        fileContext.IgnorePatterns,
-			Version:       cfg.Version,
-			Modules:       cfg.EnabledModules,
-			Threshold:     mycfg.SeverityDisplayLevel,
-			FilesScanned:  len(fileContext.Files),
-			RulesObserved: len(scan.CombinedRules),
-			StartTime:     start.UTC().Format(time.RFC3339),
-			EndTime:       time.Now().UTC().Format(time.RFC3339),
-			Duration:      fmt.Sprintf("%d ms", time.Since(start)/time.Millisecond),
-		}
+	//Format our results into an Earlybird report
+	report := newReport(cfg, Hits, fileContext, start)
 
-		response, err := json.MarshalIndent(report, "", "\t")
-		if err != nil {
-			http.Error(w, "Failed to encode JSON response: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-		fmt.Fprint(w, string(response))
+	//Encode and send JSON response
+	response, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		http.Error(w, "Failed to encode JSON response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	fmt.Fprint(w, string(response))
+}
+
+//scanContext is cancelled when the client disconnects or after the `timeout` query param (e.g. 30s), which defaults to the -timeout flag
+func scanContext(r *http.Request, defaultTimeout time.Duration) (context.Context, context.CancelFunc, error) {
+	timeout, err := requestTimeout(r, defaultTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := timeoutContext(r.Context(), timeout)
+	return ctx, cancel, nil
+}
+
+//requestTimeout parses the `timeout` query param, which defaults to the -timeout flag
+func requestTimeout(r *http.Request, defaultTimeout time.Duration) (time.Duration, error) {
+	param := r.URL.Query().Get("timeout")
+	if param == "" {
+		return defaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(param)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("Invalid timeout %q, use a duration such as 30s or 5m", param)
+	}
+	return timeout, nil
+}
+
+//timeoutContext derives a context from parent that ends after the timeout, a zero timeout never ends
+func timeoutContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+//newReport formats the hits of a finished scan into an Earlybird report
+func newReport(cfg *cfgreader.EarlybirdConfig, hits []scan.Hit, fileContext file.Context, start time.Time) scan.Report {
+	return scan.Report{
+		Hits:          hits,
+		HitCount:      len(hits),
+		Truncated:     cfg.ScanTruncated,
+		Skipped:       fileContext.Skipped(),
+		Ignore:        fileContext.IgnorePatterns,
+		Version:       cfg.Version,
+		Modules:       cfg.EnabledModules,
+		Threshold:     cfg.SeverityDisplayLevel,
+		FilesScanned:  len(fileContext.Files),
+		RulesObserved: len(scan.CurrentEngine().Rules),
+		StartTime:     start.UTC().Format(time.RFC3339),
+		EndTime:       time.Now().UTC().Format(time.RFC3339),
+		Duration:      fmt.Sprintf("%d ms", time.Since(start)/time.Millisecond),
+		Suppressed:    fileContext.Suppressions.Entries(),
 	}
 }
 
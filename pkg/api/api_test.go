@@ -23,6 +23,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path"
 	"testing"
@@ -92,6 +93,40 @@ func TestScan(t *testing.T) {
 	}
 }
 
+func TestScan_timeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    string
+		wantStatus int
+	}{
+		{name: "Valid timeout", timeout: "1m", wantStatus: http.StatusOK},
+		{name: "Invalid timeout", timeout: "soon", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile("scan", "sample.py")
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write([]byte(`password="SampleFinding678#"`))
+			writer.Close()
+
+			req, err := http.NewRequest("POST", "/scan?timeout="+tt.timeout, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			rr := httptest.NewRecorder()
+			http.HandlerFunc(Scan(cfg)).ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Scan returned wrong status code: got %v want %v", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
 func TestGITScan(t *testing.T) {
 	if os.Getenv("local") == "" {
 		t.Skip("If test cases not running locally, skip cloning external repositories for CI/CD purposes.")
@@ -121,6 +156,28 @@ func TestGITScan(t *testing.T) {
 	}
 }
 
+func TestGITScan_urlPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "Loopback address", url: "https://127.0.0.1/org/repo"},
+		{name: "Cloud metadata address", url: "https://169.254.169.254/latest"},
+		{name: "Plain http", url: "http://github.com/org/repo"},
+		{name: "Credentials in the URL", url: "https://user@github.com/org/repo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/scan/git?url="+url.QueryEscape(tt.url), nil)
+			rr := httptest.NewRecorder()
+			GITScan(cfg).ServeHTTP(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("GITScan returned %v for %s, want %v", rr.Code, tt.url, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
 func TestLabels(t *testing.T) {
 	fmt.Println("Testing Labels")
 	//fetch all labels per category
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package api
+
+// Scan job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobCancelled = "cancelled"
+	JobFailed    = "failed"
+)
+
+const (
+	sourceUpload = "upload"
+	sourceGit    = "git"
+	//retryAfterSeconds is suggested to clients when the scan queue is full
+	retryAfterSeconds = 30
+)
+
+// Streaming scan formats and events
+const (
+	formatSSE              = "sse"
+	contentTypeEventStream = "text/event-stream"
+	contentTypeNDJSON      = "application/x-ndjson"
+	eventHit               = "hit"
+	eventSummary           = "summary"
+)
@@ -0,0 +1,337 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/americanexpress/earlybird/v4/pkg/auth"
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/file"
+	"github.com/americanexpress/earlybird/v4/pkg/git"
+	"github.com/americanexpress/earlybird/v4/pkg/metrics"
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+	"github.com/americanexpress/earlybird/v4/pkg/utils"
+	"github.com/gorilla/mux"
+)
+
+// NewJobQueue starts the workers which run the scans submitted to POST /scans, at most queueSize scans wait for a
+// worker. Finished jobs are forgotten after the retention period.
+func NewJobQueue(cfg cfgreader.EarlybirdConfig, queueSize, workers int, retention time.Duration) *JobQueue {
+	q := &JobQueue{
+		cfg:       cfg,
+		queue:     make(chan *Job, queueSize),
+		jobs:      make(map[string]*Job),
+		retention: retention,
+	}
+	for w := 0; w < workers; w++ {
+		go func() {
+			for job := range q.queue {
+				q.run(job)
+			}
+		}()
+	}
+	return q
+}
+
+// ScanJobScope is the auth scope needed to create a scan job, git repository scans need their own scope
+func ScanJobScope(r *http.Request) string {
+	if r.URL.Query().Get("url") != "" {
+		return auth.ScopeScanGit
+	}
+	return auth.ScopeScan
+}
+
+// Create queues a scan of the uploaded multipart files, or of the git repository in the `url` query param, and returns its job
+func (q *JobQueue) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timeout, err := requestTimeout(r, q.cfg.Timeout)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		job := &Job{
+			Status:    JobQueued,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+			timeout:   timeout,
+			progress:  &scan.Progress{},
+		}
+		if client, ok := auth.ClientFromContext(r.Context()); ok {
+			job.owner = client.Name
+		}
+		if giturl := r.URL.Query().Get("url"); giturl != "" {
+			//Only clone repositories the git URL policy of webserver.json allows
+			job.Source = sourceGit
+			job.gitURL, err = git.CheckCloneURL(r.Context(), giturl, q.cfg.GitURLPolicy)
+			if err != nil {
+				http.Error(w, "Repository URL not allowed: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			job.gitBranch = r.URL.Query().Get("branch")
+		} else {
+			err = r.ParseMultipartForm(1024 << 20) // 1GB upload limit
+			if err != nil {
+				http.Error(w, "File upload too large: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			//The upload is read now, the request body is gone once the job is queued
+			job.Source = sourceUpload
+			job.files, err = file.MultipartToScanFiles(r.MultipartForm.File["scan"], q.cfg)
+			if err != nil {
+				http.Error(w, "Failed to parse file upload: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			job.FilesTotal = len(job.files)
+		}
+		job.ID, err = newJobID()
+		if err != nil {
+			http.Error(w, "Failed to create scan job: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		q.mu.Lock()
+		q.evictExpired()
+		//Counted before the send, so a worker picking the job up never takes the gauge below zero
+		metrics.JobsQueued.Inc()
+		select {
+		case q.queue <- job:
+			q.jobs[job.ID] = job
+		default:
+			metrics.JobsQueued.Dec()
+			q.mu.Unlock()
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+			http.Error(w, "Scan queue is full, try again later", http.StatusServiceUnavailable)
+			return
+		}
+		status := job.snapshot()
+		q.mu.Unlock()
+
+		w.Header().Set("Location", "/scans/"+job.ID)
+		writeJSON(w, http.StatusAccepted, status)
+	}
+}
+
+// Status returns the state and progress of a scan job
+func (q *JobQueue) Status() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q.mu.Lock()
+		job, ok := q.lookup(r)
+		if !ok {
+			q.mu.Unlock()
+			http.Error(w, "Scan job not found", http.StatusNotFound)
+			return
+		}
+		status := job.snapshot()
+		q.mu.Unlock()
+		writeJSON(w, http.StatusOK, status)
+	}
+}
+
+// Report returns the Earlybird report of a finished scan job, cancelled and timed out jobs have a truncated report
+func (q *JobQueue) Report() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q.mu.Lock()
+		job, ok := q.lookup(r)
+		if !ok {
+			q.mu.Unlock()
+			http.Error(w, "Scan job not found", http.StatusNotFound)
+			return
+		}
+		status, report := job.Status, job.report
+		errMessage := job.Error
+		q.mu.Unlock()
+
+		switch {
+		case status == JobFailed:
+			http.Error(w, "Scan failed: "+errMessage, http.StatusInternalServerError)
+		case report == nil:
+			http.Error(w, "Scan is "+status+", the report is not ready yet", http.StatusConflict)
+		default:
+			writeJSON(w, http.StatusOK, report)
+		}
+	}
+}
+
+// Cancel stops a queued or running scan job, a finished job is deleted along with its report
+func (q *JobQueue) Cancel() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q.mu.Lock()
+		job, ok := q.lookup(r)
+		if !ok {
+			q.mu.Unlock()
+			http.Error(w, "Scan job not found", http.StatusNotFound)
+			return
+		}
+		switch job.Status {
+		case JobQueued:
+			//The worker skips the job when it gets to it
+			job.Status = JobCancelled
+			job.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+			job.finished = time.Now()
+		case JobRunning:
+			job.cancelled = true
+			job.cancel()
+		default:
+			delete(q.jobs, job.ID)
+		}
+		status := job.snapshot()
+		q.mu.Unlock()
+		writeJSON(w, http.StatusOK, status)
+	}
+}
+
+// lookup finds the job in the request path, clients only see the jobs they created. The caller holds q.mu.
+func (q *JobQueue) lookup(r *http.Request) (*Job, bool) {
+	job, ok := q.jobs[mux.Vars(r)["id"]]
+	if !ok {
+		return nil, false
+	}
+	if client, authenticated := auth.ClientFromContext(r.Context()); authenticated && client.Name != job.owner {
+		return nil, false
+	}
+	return job, true
+}
+
+// run scans the files of the job and stores the report
+func (q *JobQueue) run(job *Job) {
+	metrics.JobsQueued.Dec()
+	q.mu.Lock()
+	if job.Status != JobQueued {
+		q.mu.Unlock()
+		return
+	}
+	start := time.Now()
+	ctx, cancel := timeoutContext(context.Background(), job.timeout)
+	defer cancel()
+	job.Status = JobRunning
+	job.StartedAt = start.UTC().Format(time.RFC3339)
+	job.cancel = cancel
+	q.mu.Unlock()
+	metrics.JobsRunning.Inc()
+	defer metrics.JobsRunning.Dec()
+
+	mycfg := q.cfg
+	fileContext := file.Context{Files: job.files}
+	if job.Source == sourceGit {
+		var err error
+		fileContext, err = q.cloneFiles(&mycfg, job)
+		defer utils.DeleteGit(job.gitURL, mycfg.SearchDir)
+		if err != nil {
+			q.finish(job, nil, err)
+			return
+		}
+	}
+
+	q.mu.Lock()
+	job.FilesTotal = len(fileContext.Files)
+	q.mu.Unlock()
+
+	fileContext.Suppressions = &scan.SuppressionLog{}
+	fileContext.Unread = &scan.SkipLog{}
+	ctx = scan.WithSkipLog(scan.WithSuppressions(scan.WithProgress(ctx, job.progress), fileContext.Suppressions), fileContext.Unread)
+	var hits []scan.Hit
+	hitChannel := make(chan scan.Hit)
+	go scan.SearchFiles(ctx, &mycfg, fileContext.Files, fileContext.CompressPaths, fileContext.ConvertPaths, hitChannel)
+	for hit := range hitChannel {
+		hits = append(hits, hit)
+	}
+
+	report := newReport(&mycfg, hits, fileContext, start)
+	q.finish(job, &report, nil)
+}
+
+// cloneFiles clones the repository of a git job and lists the files to scan
+func (q *JobQueue) cloneFiles(mycfg *cfgreader.EarlybirdConfig, job *Job) (fileContext file.Context, err error) {
+	mycfg.SearchDir, err = git.CloneGitRepos([]string{job.gitURL}, os.Getenv("gituser"), os.Getenv("gitpassword"), job.gitBranch, true)
+	if err != nil {
+		return fileContext, fmt.Errorf("failed to clone, please verify your repository is available: %v", err)
+	}
+	fileContext, err = file.GetFiles(mycfg.SearchDir, mycfg.IgnoreFile, mycfg.UseGitignore, mycfg.VerboseEnabled, mycfg.MaxFileSize)
+	if err != nil {
+		return fileContext, fmt.Errorf("failed to load scan files: %v", err)
+	}
+	return fileContext, nil
+}
+
+// finish records the outcome of a job and drops the uploaded files
+func (q *JobQueue) finish(job *Job, report *scan.Report, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch {
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+	case job.cancelled:
+		job.Status = JobCancelled
+	default:
+		job.Status = JobDone
+	}
+	job.report = report
+	job.files = nil
+	job.finished = time.Now()
+	job.FinishedAt = job.finished.UTC().Format(time.RFC3339)
+}
+
+// evictExpired forgets the jobs that finished longer than the retention period ago, the caller holds q.mu
+func (q *JobQueue) evictExpired() {
+	for id, job := range q.jobs {
+		if !job.finished.IsZero() && time.Since(job.finished) > q.retention {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// snapshot copies the job for the status response, the caller holds q.mu
+func (job *Job) snapshot() Job {
+	status := *job
+	status.FilesDone = int(job.progress.FilesDone.Load())
+	if job.report != nil {
+		status.HitCount = job.report.HitCount
+		status.Truncated = job.report.Truncated
+	}
+	return status
+}
+
+// newJobID returns a random job identifier
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// writeJSON encodes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	response, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		http.Error(w, "Failed to encode JSON response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/americanexpress/earlybird/v4/pkg/auth"
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+	"github.com/gorilla/mux"
+)
+
+// jobRouter serves the scan job end points of q
+func jobRouter(q *JobQueue) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/scans", q.Create()).Methods("POST")
+	r.HandleFunc("/scans/{id}", q.Status()).Methods("GET")
+	r.HandleFunc("/scans/{id}/report", q.Report()).Methods("GET")
+	r.HandleFunc("/scans/{id}", q.Cancel()).Methods("DELETE")
+	return r
+}
+
+// submitUpload posts a file containing a secret to /scans
+func submitUpload(t *testing.T, r http.Handler) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, uploadRequest(t, "/scans"))
+	return rr
+}
+
+// uploadRequest is a multipart upload of a file containing a secret
+func uploadRequest(t *testing.T, target string) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("scan", "sample.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(`password="SampleFinding678#"`))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", target, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// serve sends a request without a body to r
+func serve(r http.Handler, method, target string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(method, target, nil))
+	return rr
+}
+
+func TestJobQueue(t *testing.T) {
+	r := jobRouter(NewJobQueue(cfg, 10, 1, time.Hour))
+
+	rr := submitUpload(t, r)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("POST /scans returned %v, want %v: %s", rr.Code, http.StatusAccepted, rr.Body)
+	}
+	var job Job
+	if err := json.NewDecoder(rr.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == "" || rr.Header().Get("Location") != "/scans/"+job.ID {
+		t.Fatalf("POST /scans returned job %+v at %s", job, rr.Header().Get("Location"))
+	}
+
+	// Poll until the worker has finished the scan
+	deadline := time.Now().Add(10 * time.Second)
+	for job.Status == JobQueued || job.Status == JobRunning {
+		if time.Now().After(deadline) {
+			t.Fatalf("scan job still %s", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		rr = serve(r, "GET", "/scans/"+job.ID)
+		if err := json.NewDecoder(rr.Body).Decode(&job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Status != JobDone || job.FilesTotal != 1 || job.FilesDone != 1 || job.HitCount == 0 {
+		t.Errorf("GET /scans/{id} = %+v, want a done job with 1 of 1 files and hits", job)
+	}
+
+	rr = serve(r, "GET", "/scans/"+job.ID+"/report")
+	var report scan.Report
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("GET /scans/{id}/report returned %v: %v", rr.Code, err)
+	}
+	if report.HitCount != job.HitCount || report.FilesScanned != 1 {
+		t.Errorf("GET /scans/{id}/report = %d hits in %d files, want %d hits in 1 file", report.HitCount, report.FilesScanned, job.HitCount)
+	}
+
+	// Deleting a finished job forgets it
+	if rr = serve(r, "DELETE", "/scans/"+job.ID); rr.Code != http.StatusOK {
+		t.Errorf("DELETE /scans/{id} returned %v, want %v", rr.Code, http.StatusOK)
+	}
+	if rr = serve(r, "GET", "/scans/"+job.ID); rr.Code != http.StatusNotFound {
+		t.Errorf("GET /scans/{id} of a deleted job returned %v, want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestJobQueue_queued(t *testing.T) {
+	// Without workers the jobs stay queued
+	r := jobRouter(NewJobQueue(cfg, 1, 0, time.Hour))
+
+	var job Job
+	rr := submitUpload(t, r)
+	if err := json.NewDecoder(rr.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if rr = submitUpload(t, r); rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("POST /scans to a full queue returned %v, want %v with Retry-After", rr.Code, http.StatusServiceUnavailable)
+	}
+	if rr = serve(r, "GET", "/scans/"+job.ID+"/report"); rr.Code != http.StatusConflict {
+		t.Errorf("GET /scans/{id}/report of a queued job returned %v, want %v", rr.Code, http.StatusConflict)
+	}
+
+	rr = serve(r, "DELETE", "/scans/"+job.ID)
+	if err := json.NewDecoder(rr.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobCancelled {
+		t.Errorf("DELETE /scans/{id} left the job %s, want %s", job.Status, JobCancelled)
+	}
+	if rr = serve(r, "GET", "/scans/unknown"); rr.Code != http.StatusNotFound {
+		t.Errorf("GET /scans/{id} of an unknown job returned %v, want %v", rr.Code, http.StatusNotFound)
+	}
+	if rr = serve(r, "POST", "/scans?timeout=soon"); rr.Code != http.StatusBadRequest {
+		t.Errorf("POST /scans with an invalid timeout returned %v, want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestJobQueue_owner(t *testing.T) {
+	guard, err := auth.NewGuard([]auth.Client{
+		{Name: "portal", Scopes: []string{auth.ScopeScan}, HMACSecret: "portal-secret"},
+		{Name: "other", Scopes: []string{auth.ScopeScan}, HMACSecret: "other-secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewJobQueue(cfg, 1, 0, time.Hour)
+	r := mux.NewRouter()
+	r.Handle("/scans", guard.RequireFunc(ScanJobScope, q.Create())).Methods("POST")
+	r.Handle("/scans/{id}", guard.Require("", q.Status())).Methods("GET")
+
+	// signed sends the request signed by the client
+	signed := func(req *http.Request, client, secret string) *httptest.ResponseRecorder {
+		if err := auth.Sign(req, client, secret, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := signed(httptest.NewRequest("POST", "/scans?url=https://example.com/repo.git", nil), "portal", "portal-secret"); rr.Code != http.StatusForbidden {
+		t.Errorf("POST /scans of a git repository without the scope returned %v, want %v", rr.Code, http.StatusForbidden)
+	}
+	rr := signed(uploadRequest(t, "/scans"), "portal", "portal-secret")
+	var job Job
+	if err = json.NewDecoder(rr.Body).Decode(&job); err != nil {
+		t.Fatalf("POST /scans returned %v: %v", rr.Code, err)
+	}
+	if rr = signed(httptest.NewRequest("GET", "/scans/"+job.ID, nil), "portal", "portal-secret"); rr.Code != http.StatusOK {
+		t.Errorf("GET /scans/{id} by the owner returned %v, want %v", rr.Code, http.StatusOK)
+	}
+	if rr = signed(httptest.NewRequest("GET", "/scans/"+job.ID, nil), "other", "other-secret"); rr.Code != http.StatusNotFound {
+		t.Errorf("GET /scans/{id} by another client returned %v, want %v", rr.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+)
+
+// Reload loads the rules, false positives, labels and solutions from the config directories again and swaps them in for the next scans.
+// A config that doesn't load is answered with 422 and its problems, the current rules stay active.
+func Reload(cfg cfgreader.EarlybirdConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := ReloadResponse{Version: cfg.Version}
+		status := http.StatusOK
+		engine, err := scan.Reload(cfg)
+		if err != nil {
+			log.Println("Failed to reload the config:", err)
+			status = http.StatusUnprocessableEntity
+			resp.Error = err.Error()
+			var configErr *scan.ConfigError
+			if errors.As(err, &configErr) {
+				resp.Error = "the config has problems, the current rules stay active"
+				resp.Problems = configErr.Problems
+			}
+		} else {
+			log.Printf("Reloaded %d rules", len(engine.Rules))
+			resp.Reloaded = true
+			resp.Rules = len(engine.Rules)
+			resp.FalsePositives = len(engine.FalsePositiveRules)
+			resp.Labels = len(engine.Labels)
+			resp.Solutions = len(engine.SolutionConfigs)
+		}
+
+		response, err := json.MarshalIndent(resp, "", "\t")
+		if err != nil {
+			http.Error(w, "Failed to encode JSON response: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(response)
+	}
+}
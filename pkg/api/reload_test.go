@@ -0,0 +1,77 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+)
+
+func TestReload(t *testing.T) {
+	brokenRules := t.TempDir()
+	err := os.WriteFile(filepath.Join(brokenRules, "content.yaml"), []byte("rules:\n  - Code: 9001\n    Pattern: \"token=([\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	brokenCfg := cfg
+	brokenCfg.RulesConfigDir = brokenRules
+	brokenCfg.EnabledModulesMap = map[string]string{"content": "content.yaml"}
+
+	tests := []struct {
+		name       string
+		reloadCfg  bool
+		wantStatus int
+	}{
+		{name: "Reload the same config", reloadCfg: true, wantStatus: http.StatusOK},
+		{name: "Keep the rules when the config is broken", reloadCfg: false, wantStatus: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rulesBefore := len(scan.CurrentEngine().Rules)
+			reloadCfg := brokenCfg
+			if tt.reloadCfg {
+				reloadCfg = cfg
+			}
+			req := httptest.NewRequest("POST", "/admin/reload", nil)
+			rr := httptest.NewRecorder()
+			Reload(reloadCfg).ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Reload returned status %v, want %v: %s", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+
+			var resp ReloadResponse
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to parse result from Reload: %v", err)
+			}
+			if resp.Reloaded != tt.reloadCfg {
+				t.Errorf("Reload reloaded = %v, want %v", resp.Reloaded, tt.reloadCfg)
+			}
+			if tt.reloadCfg && resp.Rules != rulesBefore {
+				t.Errorf("Reload loaded %d rules, want %d", resp.Rules, rulesBefore)
+			}
+			if !tt.reloadCfg && (len(resp.Problems) != 1 || len(scan.CurrentEngine().Rules) != rulesBefore) {
+				t.Errorf("Reload of a broken config returned %v and left %d rules, want 1 problem and %d rules", resp.Problems, len(scan.CurrentEngine().Rules), rulesBefore)
+			}
+		})
+	}
+}
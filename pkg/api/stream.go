@@ -0,0 +1,93 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/file"
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+)
+
+// hitResponder answers a scan request with the hits of the running scan, the hits channel is closed when the scan is done
+type hitResponder func(w http.ResponseWriter, r *http.Request, cfg *cfgreader.EarlybirdConfig, fileContext file.Context, hits <-chan scan.Hit, start time.Time)
+
+// streamHits sends every hit to the client as soon as it is found and ends with a summary, which is the
+// Earlybird report without the hits. Hits are sent as Server-Sent Events when the client accepts
+// text/event-stream or asks for `format=sse`, and as newline delimited JSON otherwise.
+func streamHits(w http.ResponseWriter, r *http.Request, cfg *cfgreader.EarlybirdConfig, fileContext file.Context, hits <-chan scan.Hit, start time.Time) {
+	sse := wantsEventStream(r)
+	// A long scan outlives the server write timeout, the scan timeout bounds the stream instead
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	if sse {
+		w.Header().Set("Content-Type", contentTypeEventStream)
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Content-Type", contentTypeNDJSON)
+	}
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	hitCount := 0
+	for hit := range hits {
+		hitCount++
+		// Keep draining the hits when the client is gone, the scan stops on its own once the request context ends
+		if writeEvent(w, sse, eventHit, hit) == nil {
+			rc.Flush()
+		}
+	}
+
+	summary := newReport(cfg, nil, fileContext, start)
+	summary.HitCount = hitCount
+	if writeEvent(w, sse, eventSummary, summary) == nil {
+		rc.Flush()
+	}
+}
+
+// writeEvent writes one event, as an SSE event or as a JSON line holding the event name and data
+func writeEvent(w io.Writer, sse bool, event string, data interface{}) error {
+	if sse {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, encoded)
+		return err
+	}
+	encoded, err := json.Marshal(StreamEvent{Event: event, Data: data})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", encoded)
+	return err
+}
+
+// wantsEventStream reports whether the client asked for Server-Sent Events rather than newline delimited JSON
+func wantsEventStream(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == formatSSE
+	}
+	return strings.Contains(r.Header.Get("Accept"), contentTypeEventStream)
+}
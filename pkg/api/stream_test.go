@@ -0,0 +1,109 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScanStream(t *testing.T) {
+	tests := []struct {
+		name            string
+		target          string
+		accept          string
+		wantContentType string
+	}{
+		{name: "Stream newline delimited JSON by default", target: "/scan/stream", wantContentType: contentTypeNDJSON},
+		{name: "Stream events when the client accepts them", target: "/scan/stream", accept: "text/event-stream", wantContentType: contentTypeEventStream},
+		{name: "Stream events when asked for with the format param", target: "/scan/stream?format=sse", wantContentType: contentTypeEventStream},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile("scan", "sample.py")
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write([]byte("password=\"SampleFinding678#\"\nsecret=\"SampleFinding679#\"\n"))
+			writer.Close()
+
+			req := httptest.NewRequest("POST", tt.target, body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.Header.Set("Accept", tt.accept)
+			rr := httptest.NewRecorder()
+			ScanStream(cfg).ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != tt.wantContentType {
+				t.Fatalf("ScanStream returned %v as %s, want %v as %s", rr.Code, rr.Header().Get("Content-Type"), http.StatusOK, tt.wantContentType)
+			}
+
+			events, data := readStream(t, rr.Body.String(), tt.wantContentType == contentTypeEventStream)
+			if len(events) < 2 || events[len(events)-1] != eventSummary {
+				t.Fatalf("ScanStream sent events %v, want hits followed by a summary", events)
+			}
+			var summary struct {
+				HitCount     int `json:"hit_count"`
+				FilesScanned int `json:"files_scanned"`
+			}
+			if err = json.Unmarshal(data[len(data)-1], &summary); err != nil {
+				t.Fatal(err)
+			}
+			if summary.HitCount != len(events)-1 || summary.FilesScanned != 1 {
+				t.Errorf("ScanStream summary = %+v, want %d hits in 1 file", summary, len(events)-1)
+			}
+			for _, event := range events[:len(events)-1] {
+				if event != eventHit {
+					t.Errorf("ScanStream sent %s before the summary, want %s", event, eventHit)
+				}
+			}
+		})
+	}
+}
+
+// readStream splits a streamed response into its event names and data
+func readStream(t *testing.T, body string, sse bool) (events []string, data []json.RawMessage) {
+	t.Helper()
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case sse && strings.HasPrefix(line, "event: "):
+			events = append(events, strings.TrimPrefix(line, "event: "))
+		case sse && strings.HasPrefix(line, "data: "):
+			data = append(data, json.RawMessage(strings.TrimPrefix(line, "data: ")))
+		case !sse && line != "":
+			var event struct {
+				Event string          `json:"event"`
+				Data  json.RawMessage `json:"data"`
+			}
+			if err := json.Unmarshal([]byte(line), &event); err != nil {
+				t.Fatalf("invalid JSON line %q: %v", line, err)
+			}
+			events = append(events, event.Event)
+			data = append(data, event.Data)
+		}
+	}
+	return events, data
+}
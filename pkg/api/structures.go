@@ -16,6 +16,15 @@
 
 package api
 
+import (
+	"context"
+	"sync"
+	"time"
+
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+)
+
 //CategoryLabelsResponse is the format of API results from label per category end point
 type CategoryLabelsResponse struct {
 	Version        string              `json:"version"`
@@ -33,3 +42,57 @@ type LabelsResponse struct {
 	Version string   `json:"version"`
 	Labels  []string `json:"labels"`
 }
+
+//ReloadResponse is the format of API results from the reload end point, Problems lists why a reload failed
+type ReloadResponse struct {
+	Version        string               `json:"version"`
+	Reloaded       bool                 `json:"reloaded"`
+	Rules          int                  `json:"rules"`
+	FalsePositives int                  `json:"falsepositives"`
+	Labels         int                  `json:"labels"`
+	Solutions      int                  `json:"solutions"`
+	Error          string               `json:"error,omitempty"`
+	Problems       []scan.ConfigProblem `json:"problems,omitempty"`
+}
+
+//StreamEvent is a line of the newline delimited JSON returned by the streaming scan end points, Event is
+//either "hit" with a scan.Hit as Data or "summary" with a scan.Report without hits
+type StreamEvent struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
+//JobQueue runs the scans submitted to POST /scans in the background with a fixed number of workers
+type JobQueue struct {
+	cfg       cfgreader.EarlybirdConfig
+	queue     chan *Job
+	retention time.Duration
+	mu        sync.Mutex
+	jobs      map[string]*Job
+}
+
+//Job is a background scan, it is also the format of API results from the scan job end points
+type Job struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	Source     string `json:"source"`
+	FilesTotal int    `json:"files_total"`
+	FilesDone  int    `json:"files_done"`
+	HitCount   int    `json:"hit_count"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	Error      string `json:"error,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	StartedAt  string `json:"started_at,omitempty"`
+	FinishedAt string `json:"finished_at,omitempty"`
+
+	owner     string
+	files     []scan.File
+	gitURL    string
+	gitBranch string
+	timeout   time.Duration
+	progress  *scan.Progress
+	cancel    context.CancelFunc
+	cancelled bool
+	finished  time.Time
+	report    *scan.Report
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Authenticate looks up the client by the hash of its API key
+func (a *APIKey) Authenticate(r *http.Request) (*Client, error) {
+	key := r.Header.Get(HeaderAPIKey)
+	if authorization := r.Header.Get("Authorization"); key == "" && strings.HasPrefix(authorization, bearerPrefix) {
+		key = strings.TrimPrefix(authorization, bearerPrefix)
+	}
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+	for keyHash, client := range a.clients {
+		if subtle.ConstantTimeCompare([]byte(keyHash), []byte(hash)) == 1 {
+			return client, nil
+		}
+	}
+	return nil, errors.New("invalid API key")
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/utils"
+)
+
+// LoadGuard reads the clients file and returns a Guard authenticating them
+func LoadGuard(path string) (*Guard, error) {
+	var clients Clients
+	if err := cfgreader.LoadConfig(&clients, path); err != nil {
+		return nil, fmt.Errorf("failed to load clients file: %v", err)
+	}
+	return NewGuard(clients.Clients)
+}
+
+// NewGuard validates the clients and returns a Guard authenticating them by API key, HMAC signature or TLS client certificate
+func NewGuard(clients []Client) (*Guard, error) {
+	apiKey := &APIKey{clients: make(map[string]*Client)}
+	hmacAuth := &HMAC{clients: make(map[string]*Client)}
+	clientCert := &ClientCert{clients: make(map[string]*Client)}
+	names := make(map[string]bool)
+	for i := range clients {
+		client := &clients[i]
+		if client.Name == "" {
+			return nil, fmt.Errorf("client %d has no name", i+1)
+		}
+		if names[client.Name] {
+			return nil, fmt.Errorf("client %s is defined twice", client.Name)
+		}
+		names[client.Name] = true
+		for _, scope := range client.Scopes {
+			if !utils.Contains(knownScopes, scope) {
+				return nil, fmt.Errorf("client %s has unknown scope %q, use one of %s", client.Name, scope, utils.GetDisplayList(knownScopes))
+			}
+		}
+		if client.KeySHA256 == "" && client.HMACSecret == "" && client.CertCommonName == "" {
+			return nil, fmt.Errorf("client %s has no key_sha256, hmac_secret or cert_common_name", client.Name)
+		}
+
+		if client.KeySHA256 != "" {
+			if sum, err := hex.DecodeString(client.KeySHA256); err != nil || len(sum) != 32 {
+				return nil, fmt.Errorf("client %s key_sha256 is not a hex encoded SHA-256 hash", client.Name)
+			}
+			apiKey.clients[strings.ToLower(client.KeySHA256)] = client
+		}
+		if client.HMACSecret != "" {
+			hmacAuth.clients[client.Name] = client
+		}
+		if client.CertCommonName != "" {
+			if _, ok := clientCert.clients[client.CertCommonName]; ok {
+				return nil, fmt.Errorf("certificate common name %s belongs to more than one client", client.CertCommonName)
+			}
+			clientCert.clients[client.CertCommonName] = client
+		}
+	}
+	return &Guard{authenticators: []Authenticator{clientCert, apiKey, hmacAuth}}, nil
+}
+
+// Require only passes requests on to next when they come from a client granted the scope, an empty scope lets any known client through
+func (g *Guard) Require(scope string, next http.Handler) http.Handler {
+	return g.RequireFunc(func(*http.Request) string { return scope }, next)
+}
+
+// RequireFunc is Require for handlers whose scope depends on the request
+func (g *Guard) RequireFunc(scope func(r *http.Request) string, next http.Handler) http.Handler {
+	if g == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, err := g.Authenticate(r)
+		// The body may have been replaced by one spooled to a temporary file, which net/http doesn't close
+		if r.Body != nil {
+			defer r.Body.Close()
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="earlybird"`)
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if required := scope(r); required != "" && !client.HasScope(required) {
+			log.Printf("Client %s denied %s %s, it lacks scope %s", client.Name, r.Method, r.URL.Path, required)
+			http.Error(w, "Forbidden: missing scope "+required, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKey{}, client)))
+	})
+}
+
+// Authenticate identifies the client with the first Authenticator that finds its credentials on the request
+func (g *Guard) Authenticate(r *http.Request) (*Client, error) {
+	for _, authenticator := range g.authenticators {
+		client, err := authenticator.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return client, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// HasScope reports whether the client was granted the scope
+func (c *Client) HasScope(scope string) bool {
+	return utils.Contains(c.Scopes, scope) || utils.Contains(c.Scopes, ScopeAll)
+}
+
+// ClientFromContext returns the client authenticated for the request, there is none when the server runs without authentication
+func ClientFromContext(ctx context.Context) (*Client, bool) {
+	client, ok := ctx.Value(clientKey{}).(*Client)
+	return client, ok
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// keyHash is the key_sha256 of an API key
+func keyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+var testClients = []Client{
+	{Name: "portal", Scopes: []string{ScopeScan, ScopeRead}, KeySHA256: keyHash("portal-key")},
+	{Name: "ci", Scopes: []string{ScopeAll}, HMACSecret: "ci-secret"},
+	{Name: "scanner", Scopes: []string{ScopeScanGit}, CertCommonName: "scanner.example.com"},
+}
+
+func TestNewGuard(t *testing.T) {
+	tests := []struct {
+		name    string
+		clients []Client
+		wantErr bool
+	}{
+		{name: "Valid clients", clients: testClients},
+		{name: "Missing name", clients: []Client{{KeySHA256: keyHash("key")}}, wantErr: true},
+		{name: "Duplicate name", clients: []Client{{Name: "a", KeySHA256: keyHash("a")}, {Name: "a", KeySHA256: keyHash("b")}}, wantErr: true},
+		{name: "No credentials", clients: []Client{{Name: "a", Scopes: []string{ScopeScan}}}, wantErr: true},
+		{name: "Unknown scope", clients: []Client{{Name: "a", Scopes: []string{"write"}, KeySHA256: keyHash("a")}}, wantErr: true},
+		{name: "Plain key instead of a hash", clients: []Client{{Name: "a", KeySHA256: "secret"}}, wantErr: true},
+		{name: "Shared certificate", clients: []Client{{Name: "a", CertCommonName: "x"}, {Name: "b", CertCommonName: "x"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewGuard(tt.clients); (err != nil) != tt.wantErr {
+				t.Errorf("NewGuard() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadGuard(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clients.yaml")
+	content := "clients:\n  - name: portal\n    scopes: [scan]\n    key_sha256: " + keyHash("portal-key") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	guard, err := LoadGuard(path)
+	if err != nil {
+		t.Fatalf("LoadGuard() error = %v", err)
+	}
+	req := httptest.NewRequest("POST", "/scan", nil)
+	req.Header.Set("Authorization", "Bearer portal-key")
+	if client, err := guard.Authenticate(req); err != nil || client.Name != "portal" {
+		t.Errorf("LoadGuard() guard authenticated %v, %v, want portal", client, err)
+	}
+	if _, err = LoadGuard(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("LoadGuard() of a missing file succeeded")
+	}
+}
+
+func TestGuard_Require(t *testing.T) {
+	guard, err := NewGuard(testClients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		guard      *Guard
+		scope      string
+		key        string
+		wantStatus int
+		wantClient string
+	}{
+		{name: "Allow a client with the scope", guard: guard, scope: ScopeScan, key: "portal-key", wantStatus: http.StatusOK, wantClient: "portal"},
+		{name: "Allow any client without a scope", guard: guard, key: "portal-key", wantStatus: http.StatusOK, wantClient: "portal"},
+		{name: "Forbid a client without the scope", guard: guard, scope: ScopeScanGit, key: "portal-key", wantStatus: http.StatusForbidden},
+		{name: "Reject an unknown key", guard: guard, scope: ScopeScan, key: "other-key", wantStatus: http.StatusUnauthorized},
+		{name: "Reject a request without credentials", guard: guard, scope: ScopeScan, wantStatus: http.StatusUnauthorized},
+		{name: "Let everything through without a guard", scope: ScopeScanGit, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotClient string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if client, ok := ClientFromContext(r.Context()); ok {
+					gotClient = client.Name
+				}
+			})
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.key != "" {
+				req.Header.Set(HeaderAPIKey, tt.key)
+			}
+			rr := httptest.NewRecorder()
+			tt.guard.Require(tt.scope, next).ServeHTTP(rr, req)
+			if rr.Code != tt.wantStatus || gotClient != tt.wantClient {
+				t.Errorf("Require() = %v for client %q, want %v for client %q", rr.Code, gotClient, tt.wantStatus, tt.wantClient)
+			}
+		})
+	}
+}
+
+func TestClient_HasScope(t *testing.T) {
+	if !testClients[0].HasScope(ScopeScan) || testClients[0].HasScope(ScopeScanGit) {
+		t.Errorf("HasScope() doesn't follow the client scopes")
+	}
+	if !testClients[1].HasScope(ScopeScanGit) {
+		t.Errorf("HasScope() doesn't grant every scope to %s", ScopeAll)
+	}
+}
@@ -0,0 +1,35 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Authenticate looks up the client by the common name of the certificate the TLS handshake verified
+func (c *ClientCert) Authenticate(r *http.Request) (*Client, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+	commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	client, ok := c.clients[commonName]
+	if !ok {
+		return nil, errors.New("unknown client certificate " + commonName)
+	}
+	return client, nil
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientCert_Authenticate(t *testing.T) {
+	c := &ClientCert{clients: map[string]*Client{"scanner.example.com": &testClients[2]}}
+	tests := []struct {
+		name       string
+		state      *tls.ConnectionState
+		wantClient string
+		wantErr    bool
+	}{
+		{name: "Known certificate", state: verifiedState("scanner.example.com"), wantClient: "scanner"},
+		{name: "Unknown certificate", state: verifiedState("other.example.com"), wantErr: true},
+		{name: "No certificate", state: &tls.ConnectionState{}, wantErr: true},
+		{name: "Plain HTTP", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.TLS = tt.state
+			client, err := c.Authenticate(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && client.Name != tt.wantClient {
+				t.Errorf("Authenticate() = %s, want %s", client.Name, tt.wantClient)
+			}
+		})
+	}
+}
+
+// verifiedState is the TLS state of a connection whose client certificate for commonName was verified
+func verifiedState(commonName string) *tls.ConnectionState {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+	return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+}
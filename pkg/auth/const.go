@@ -0,0 +1,63 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	//ScopeScan allows scanning uploaded files
+	ScopeScan string = "scan"
+	//ScopeScanGit allows scanning git repositories, which the server clones with its own credentials
+	ScopeScanGit string = "scan:git"
+	//ScopeRead allows reading the labels and categories
+	ScopeRead string = "read"
+	//ScopeAdmin allows administering the server, like reloading the rules
+	ScopeAdmin string = "admin"
+	//ScopeAll allows everything
+	ScopeAll string = "*"
+
+	//HeaderAPIKey carries an API key, as an alternative to `Authorization: Bearer <key>`
+	HeaderAPIKey string = "X-Api-Key"
+	//HeaderClient names the client signing a request
+	HeaderClient string = "X-Earlybird-Client"
+	//HeaderTimestamp is the Unix time at which a request was signed
+	HeaderTimestamp string = "X-Earlybird-Timestamp"
+	//HeaderNonce is a random value which makes every signed request unique, so it can't be replayed
+	HeaderNonce string = "X-Earlybird-Nonce"
+	//HeaderContentSHA256 is the hex encoded SHA-256 of the body of a signed request
+	HeaderContentSHA256 string = "X-Earlybird-Content-Sha256"
+	//HeaderSignature is the hex encoded HMAC-SHA256 signature of a request
+	HeaderSignature string = "X-Earlybird-Signature"
+
+	maxClockSkew    time.Duration = 5 * time.Minute
+	maxSignedBody   int64         = 1 << 30 // Same as the upload limit
+	maxMemoryBody   int64         = 1 << 20 // Larger signed bodies are spooled to disk while their hash is checked
+	nonceLength     int           = 16
+	maxNonceLength  int           = 128
+	bodyFilePattern string        = "earlybird-body-*"
+	bearerPrefix    string        = "Bearer "
+)
+
+var (
+	//ErrNoCredentials is returned by an Authenticator when the request carries none of its credentials
+	ErrNoCredentials = errors.New("no credentials")
+
+	knownScopes = []string{ScopeScan, ScopeScanGit, ScopeRead, ScopeAdmin, ScopeAll}
+)
@@ -0,0 +1,197 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Sign adds the HMAC headers for the client to the request, signed at the given time. The body is read and replaced.
+func Sign(r *http.Request, clientName, secret string, at time.Time) error {
+	bodyHash, err := hashBody(r)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, nonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	r.Header.Set(HeaderClient, clientName)
+	r.Header.Set(HeaderTimestamp, timestamp)
+	r.Header.Set(HeaderNonce, hex.EncodeToString(nonce))
+	r.Header.Set(HeaderContentSHA256, bodyHash)
+	r.Header.Set(HeaderSignature, hex.EncodeToString(signature(secret, r.Method, r.URL.RequestURI(), timestamp, r.Header.Get(HeaderNonce), bodyHash)))
+	return nil
+}
+
+// Authenticate checks the signature of the request against the secret of the client it names.
+// The signature covers the hash of the body rather than the body, so it is checked before the body is read.
+func (h *HMAC) Authenticate(r *http.Request) (*Client, error) {
+	clientName := r.Header.Get(HeaderClient)
+	if clientName == "" {
+		return nil, ErrNoCredentials
+	}
+	client, ok := h.clients[clientName]
+	if !ok {
+		return nil, errors.New("unknown client " + clientName)
+	}
+
+	// Signatures only stay valid for a while, so the nonces only need to be remembered for as long
+	timestamp := r.Header.Get(HeaderTimestamp)
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header", HeaderTimestamp)
+	}
+	now := time.Now
+	if h.now != nil {
+		now = h.now
+	}
+	if skew := now().Sub(time.Unix(signedAt, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return nil, errors.New("request signature expired")
+	}
+	nonce := r.Header.Get(HeaderNonce)
+	if len(nonce) < 2*nonceLength || len(nonce) > maxNonceLength {
+		return nil, fmt.Errorf("invalid %s header", HeaderNonce)
+	}
+	bodyHash := r.Header.Get(HeaderContentSHA256)
+	if len(bodyHash) != 2*sha256.Size {
+		return nil, fmt.Errorf("invalid %s header", HeaderContentSHA256)
+	}
+
+	got, err := hex.DecodeString(r.Header.Get(HeaderSignature))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header", HeaderSignature)
+	}
+	if !hmac.Equal(got, signature(client.HMACSecret, r.Method, r.URL.RequestURI(), timestamp, nonce, bodyHash)) {
+		return nil, errors.New("invalid request signature")
+	}
+	if !h.firstUse(clientName+":"+nonce, time.Unix(signedAt, 0).Add(maxClockSkew), now()) {
+		return nil, errors.New("request replayed")
+	}
+	if err := readSignedBody(r, bodyHash); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// firstUse records the nonce until it expires, and reports whether it wasn't seen before
+func (h *HMAC) firstUse(nonce string, expires, now time.Time) bool {
+	h.nonceMutex.Lock()
+	defer h.nonceMutex.Unlock()
+	if h.nonces == nil {
+		h.nonces = make(map[string]time.Time)
+	}
+	for seen, seenExpires := range h.nonces {
+		if seenExpires.Before(now) {
+			delete(h.nonces, seen)
+		}
+	}
+	if _, seen := h.nonces[nonce]; seen {
+		return false
+	}
+	h.nonces[nonce] = expires
+	return true
+}
+
+// signature is the HMAC-SHA256 of the method, request URI, timestamp, nonce and body hash, separated by new lines
+func signature(secret, method, requestURI, timestamp, nonce, bodyHash string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	io.WriteString(mac, method+"\n"+requestURI+"\n"+timestamp+"\n"+nonce+"\n"+bodyHash)
+	return mac.Sum(nil)
+}
+
+// hashBody returns the hex encoded SHA-256 of the request body and puts the body back for the request to be sent
+func hashBody(r *http.Request) (string, error) {
+	if r.Body == nil {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// readSignedBody reads the body of a request whose signature checked out, and only hands it to the handler when it
+// matches the signed hash. Bodies larger than maxMemoryBody are spooled to a temporary file rather than kept in memory.
+func readSignedBody(r *http.Request, bodyHash string) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		if sum := sha256.Sum256(nil); hex.EncodeToString(sum[:]) != bodyHash {
+			return errors.New("request body doesn't match its signed hash")
+		}
+		return nil
+	}
+	defer r.Body.Close()
+	digest := sha256.New()
+	source := io.TeeReader(io.LimitReader(r.Body, maxSignedBody+1), digest)
+
+	var inMemory bytes.Buffer
+	size, err := io.CopyN(&inMemory, source, maxMemoryBody+1)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	body := io.ReadCloser(io.NopCloser(&inMemory))
+	if size > maxMemoryBody {
+		spooled, err := os.CreateTemp("", bodyFilePattern)
+		if err != nil {
+			return err
+		}
+		body = &spooledBody{spooled}
+		rest, err := io.Copy(spooled, io.MultiReader(&inMemory, source))
+		if err == nil {
+			_, err = spooled.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			body.Close()
+			return err
+		}
+		size = rest
+	}
+	if size > maxSignedBody {
+		body.Close()
+		return errors.New("request body too large")
+	}
+	if hex.EncodeToString(digest.Sum(nil)) != bodyHash {
+		body.Close()
+		return errors.New("request body doesn't match its signed hash")
+	}
+	r.Body = body
+	return nil
+}
+
+// Close removes the temporary file along with closing it
+func (b *spooledBody) Close() error {
+	err := b.File.Close()
+	os.Remove(b.File.Name())
+	return err
+}
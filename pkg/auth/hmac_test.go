@@ -0,0 +1,166 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHMAC_Authenticate(t *testing.T) {
+	now := time.Date(2021, 5, 4, 10, 0, 0, 0, time.UTC)
+	h := &HMAC{clients: map[string]*Client{"ci": &testClients[1]}, now: func() time.Time { return now }}
+	tests := []struct {
+		name     string
+		client   string
+		secret   string
+		signedAt time.Time
+		body     string
+		change   string
+		wantFail bool
+	}{
+		{name: "Valid signature", client: "ci", secret: "ci-secret", signedAt: now, body: "scan=content"},
+		{name: "Wrong secret", client: "ci", secret: "other", signedAt: now, body: "scan=content", wantFail: true},
+		{name: "Unknown client", client: "nobody", secret: "ci-secret", signedAt: now, wantFail: true},
+		{name: "Expired signature", client: "ci", secret: "ci-secret", signedAt: now.Add(-time.Hour), wantFail: true},
+		{name: "Changed body", client: "ci", secret: "ci-secret", signedAt: now, body: "scan=content", change: "scan=other", wantFail: true},
+		{name: "Body spooled to disk", client: "ci", secret: "ci-secret", signedAt: now, body: strings.Repeat("scan=content\n", int(maxMemoryBody)/10)},
+		{name: "Changed spooled body", client: "ci", secret: "ci-secret", signedAt: now, body: strings.Repeat("scan=content\n", int(maxMemoryBody)/10), change: strings.Repeat("scan=other\n", int(maxMemoryBody)/10), wantFail: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/scan?timeout=1m", strings.NewReader(tt.body))
+			if err := Sign(req, tt.client, tt.secret, tt.signedAt); err != nil {
+				t.Fatal(err)
+			}
+			if tt.change != "" {
+				req.Body = io.NopCloser(strings.NewReader(tt.change))
+			}
+			client, err := h.Authenticate(req)
+			if (err != nil) != tt.wantFail {
+				t.Fatalf("Authenticate() error = %v, wantFail %v", err, tt.wantFail)
+			}
+			if tt.wantFail {
+				return
+			}
+			if client.Name != tt.client {
+				t.Errorf("Authenticate() = %s, want %s", client.Name, tt.client)
+			}
+			// The handler still gets the whole body
+			body, _ := io.ReadAll(req.Body)
+			req.Body.Close()
+			if string(body) != tt.body {
+				t.Errorf("Authenticate() left a body of %d bytes, want %d", len(body), len(tt.body))
+			}
+		})
+	}
+
+	if _, err := h.Authenticate(httptest.NewRequest("GET", "/", nil)); err != ErrNoCredentials {
+		t.Errorf("Authenticate() of an unsigned request = %v, want %v", err, ErrNoCredentials)
+	}
+}
+
+func TestHMAC_Authenticate_replay(t *testing.T) {
+	now := time.Date(2021, 5, 4, 10, 0, 0, 0, time.UTC)
+	h := &HMAC{clients: map[string]*Client{"ci": &testClients[1]}, now: func() time.Time { return now }}
+	req := httptest.NewRequest("GET", "/labels", nil)
+	if err := Sign(req, "ci", "ci-secret", now); err != nil {
+		t.Fatal(err)
+	}
+	replay := req.Clone(req.Context())
+	if _, err := h.Authenticate(req); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if _, err := h.Authenticate(replay); err == nil {
+		t.Error("Authenticate() accepted a replayed request")
+	}
+
+	// Nonces are forgotten once the signature has expired anyway
+	now = now.Add(2 * maxClockSkew)
+	fresh := httptest.NewRequest("GET", "/labels", nil)
+	if err := Sign(fresh, "ci", "ci-secret", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Authenticate(fresh); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if len(h.nonces) != 1 {
+		t.Errorf("Authenticate() remembers %d nonces, want 1", len(h.nonces))
+	}
+}
+
+// countingReader counts the bytes read from the body
+type countingReader struct {
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.n += len(p)
+	return len(p), nil
+}
+
+func TestHMAC_Authenticate_bodyNotReadUnsigned(t *testing.T) {
+	now := time.Date(2021, 5, 4, 10, 0, 0, 0, time.UTC)
+	h := &HMAC{clients: map[string]*Client{"ci": &testClients[1]}, now: func() time.Time { return now }}
+	req := httptest.NewRequest("POST", "/scan", strings.NewReader("scan=content"))
+	if err := Sign(req, "other-secret-holder", "wrong", now); err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(HeaderClient, "ci")
+	body := &countingReader{}
+	req.Body = io.NopCloser(body)
+	if _, err := h.Authenticate(req); err == nil {
+		t.Fatal("Authenticate() accepted a request with a wrong signature")
+	}
+	if body.n > 0 {
+		t.Errorf("Authenticate() read %d bytes of the body before checking the signature", body.n)
+	}
+}
+
+func TestGuard_RequireFunc_spooledBodyRemoved(t *testing.T) {
+	now := time.Date(2021, 5, 4, 10, 0, 0, 0, time.UTC)
+	guard := &Guard{authenticators: []Authenticator{&HMAC{clients: map[string]*Client{"ci": &testClients[1]}, now: func() time.Time { return now }}}}
+	req := httptest.NewRequest("POST", "/scan", strings.NewReader(strings.Repeat("scan=content\n", int(maxMemoryBody)/10)))
+	if err := Sign(req, "ci", "ci-secret", now); err != nil {
+		t.Fatal(err)
+	}
+
+	// Like the scan handlers, read the body without closing it
+	var spooled string
+	handler := guard.RequireFunc(func(*http.Request) string { return "" }, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := r.Body.(*spooledBody)
+		if !ok {
+			t.Fatalf("RequireFunc() handed a %T body, want it spooled to a file", r.Body)
+		}
+		spooled = body.Name()
+		io.Copy(io.Discard, r.Body)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("RequireFunc() = %d, want 200", w.Code)
+	}
+	if _, err := os.Stat(spooled); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("RequireFunc() left the spooled body %s behind, stat error %v", spooled, err)
+	}
+}
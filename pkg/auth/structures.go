@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package auth
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Client is an API client from the clients file, with the credentials it authenticates with and the scopes it is granted
+type Client struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// KeySHA256 is the hex encoded SHA-256 hash of the client's API key, so the file doesn't hold the key itself
+	KeySHA256 string `json:"key_sha256"`
+	// HMACSecret signs the client's requests
+	HMACSecret string `json:"hmac_secret"`
+	// CertCommonName is the subject common name of the client's TLS certificate
+	CertCommonName string `json:"cert_common_name"`
+}
+
+// Clients is the result of the clients file
+type Clients struct {
+	Clients []Client `json:"clients"`
+}
+
+// Authenticator identifies the client making a request
+type Authenticator interface {
+	// Authenticate returns the client, ErrNoCredentials when the request carries none of the credentials this
+	// Authenticator handles, or another error when the credentials are invalid
+	Authenticate(r *http.Request) (*Client, error)
+}
+
+// Guard authenticates requests with its Authenticators and checks the scopes of the client. A nil Guard lets every request through.
+type Guard struct {
+	authenticators []Authenticator
+}
+
+// APIKey authenticates clients by the API key in the `Authorization: Bearer` or X-Api-Key header
+type APIKey struct {
+	clients map[string]*Client // SHA-256 of the key:client
+}
+
+// HMAC authenticates clients by the signature over the method, URI, timestamp, nonce and body hash of a request, see Sign
+type HMAC struct {
+	clients    map[string]*Client // name:client
+	now        func() time.Time
+	nonceMutex sync.Mutex
+	nonces     map[string]time.Time // client:nonce:expiry of the signature
+}
+
+// spooledBody is a request body spooled to a temporary file, which is removed when the body is closed
+type spooledBody struct {
+	*os.File
+}
+
+// ClientCert authenticates clients by the verified TLS client certificate of the connection
+type ClientCert struct {
+	clients map[string]*Client // certificate common name:client
+}
+
+// clientKey is the context key of the authenticated client
+type clientKey struct{}
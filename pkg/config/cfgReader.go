@@ -19,8 +19,12 @@ package cfgreader
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"unicode"
 
 	"github.com/ghodss/yaml"
@@ -60,6 +64,23 @@ func LoadConfig(cfg interface{}, path string) (err error) {
 	return err
 }
 
+//LoadConfigStrict parses the json or yaml configuration file like LoadConfig, but fails on fields the structure doesn't have
+func LoadConfigStrict(cfg interface{}, path string) (err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	byteValue, err := toJson(data)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(byteValue))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(cfg)
+}
+
 // toJson returns json from data. If data is already json this fn is a noop, if data is yaml
 // the yaml is converted to json
 func toJson(data []byte) ([]byte, error) {
@@ -119,3 +140,78 @@ func (cfg *Configs) GetLevelMap() (levelMap map[string]int) {
 	}
 	return levelMap
 }
+
+//GetRuleModules walks the rules directory of the config directory and of every rule source and maps the module names
+//to their rules file name, a module in several of them loads the rules of each
+func GetRuleModules(configDir string, sources []RuleSource) (filenameMap map[string]string, moduleNames []string, err error) {
+	rulesPaths := []string{filepath.Join(configDir, "rules")}
+	for _, source := range sources {
+		sourceRulesPath := filepath.Join(source.Dir, "rules")
+		if _, err = os.Stat(sourceRulesPath); err != nil {
+			log.Println("Rule source", source.Name, "has no rules, run with -update to fetch it")
+			continue
+		}
+		rulesPaths = append(rulesPaths, sourceRulesPath)
+	}
+
+	filenameMap = make(map[string]string)
+	for _, rulesPath := range rulesPaths {
+		err = filepath.Walk(rulesPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+
+			moduleName := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+			if _, ok := filenameMap[moduleName]; !ok {
+				filenameMap[moduleName] = info.Name()
+				moduleNames = append(moduleNames, moduleName)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return filenameMap, moduleNames, nil
+}
+
+//ResolveRuleSources checks the rule sources and sets the directory each one is read from: a local directory source
+//is read in place, relative to the config directory, and the others from where -update fetched them
+func ResolveRuleSources(configDir string, sources []RuleSource) ([]RuleSource, error) {
+	resolved := make([]RuleSource, len(sources))
+	names := make(map[string]bool)
+	for i, source := range sources {
+		if source.Name == "" || source.Name != filepath.Base(source.Name) || strings.HasPrefix(source.Name, ".") {
+			return nil, fmt.Errorf("rule source %d has an invalid name %q", i, source.Name)
+		}
+		if names[source.Name] {
+			return nil, fmt.Errorf("rule source %s is listed twice", source.Name)
+		}
+		names[source.Name] = true
+		switch source.Type {
+		case SourceGit, SourceTarball:
+			if source.URL == "" {
+				return nil, fmt.Errorf("rule source %s has no url", source.Name)
+			}
+			if source.Pin == "" {
+				return nil, fmt.Errorf("rule source %s has no pin", source.Name)
+			}
+			source.Dir = filepath.Join(configDir, SourcesDir, source.Name)
+		case SourceDir:
+			if source.Path == "" {
+				return nil, fmt.Errorf("rule source %s has no path", source.Name)
+			}
+			source.Dir = source.Path
+			if !filepath.IsAbs(source.Dir) {
+				source.Dir = filepath.Join(configDir, source.Dir)
+			}
+		default:
+			return nil, fmt.Errorf("rule source %s has unknown type %q, use %s, %s or %s", source.Name, source.Type, SourceGit, SourceTarball, SourceDir)
+		}
+		resolved[i] = source
+	}
+	return resolved, nil
+}
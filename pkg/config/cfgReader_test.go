@@ -19,6 +19,8 @@ package cfgreader
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/americanexpress/earlybird/v4/pkg/utils"
@@ -42,6 +44,34 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
+func TestLoadConfigStrict(t *testing.T) {
+	type rule struct {
+		Code    int
+		Pattern string
+	}
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "Known fields in yaml", content: "code: 1\npattern: secret\n", wantErr: false},
+		{name: "Known fields in json", content: `{"Code": 1, "Pattern": "secret"}`, wantErr: false},
+		{name: "Misspelled field", content: "code: 1\npatern: secret\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rule.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var got rule
+			if err := LoadConfigStrict(&got, path); (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfigStrict() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestTranslateLevelID(t *testing.T) {
 	result := "critical"
 	if got := config.TranslateLevelID(1); got != result {
@@ -142,3 +172,34 @@ this:
 		})
 	}
 }
+
+func TestResolveRuleSources(t *testing.T) {
+	configDir := filepath.Join("home", ".go-earlybird")
+	tests := []struct {
+		name    string
+		sources []RuleSource
+		wantDir string
+		wantErr bool
+	}{
+		{name: "Git source", sources: []RuleSource{{Name: "upstream", Type: SourceGit, URL: "https://example.com/rules.git", Pin: "v1.0.0"}}, wantDir: filepath.Join(configDir, SourcesDir, "upstream")},
+		{name: "Tarball source", sources: []RuleSource{{Name: "internal", Type: SourceTarball, URL: "https://example.com/rules.tar.gz", Pin: "sha256:00"}}, wantDir: filepath.Join(configDir, SourcesDir, "internal")},
+		{name: "Relative directory", sources: []RuleSource{{Name: "local", Type: SourceDir, Path: "custom"}}, wantDir: filepath.Join(configDir, "custom")},
+		{name: "Absolute directory", sources: []RuleSource{{Name: "local", Type: SourceDir, Path: "/opt/rules"}}, wantDir: "/opt/rules"},
+		{name: "Unknown type", sources: []RuleSource{{Name: "oci", Type: "oci", URL: "registry.example.com/rules"}}, wantErr: true},
+		{name: "Name with a path", sources: []RuleSource{{Name: "../rules", Type: SourceGit, URL: "https://example.com/rules.git"}}, wantErr: true},
+		{name: "Missing URL", sources: []RuleSource{{Name: "upstream", Type: SourceGit, Pin: "v1.0.0"}}, wantErr: true},
+		{name: "Missing pin", sources: []RuleSource{{Name: "upstream", Type: SourceGit, URL: "https://example.com/rules.git"}}, wantErr: true},
+		{name: "Listed twice", sources: []RuleSource{{Name: "local", Type: SourceDir, Path: "a"}, {Name: "local", Type: SourceDir, Path: "b"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveRuleSources(configDir, tt.sources)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveRuleSources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got[0].Dir != tt.wantDir {
+				t.Errorf("ResolveRuleSources() dir = %v, want %v", got[0].Dir, tt.wantDir)
+			}
+		})
+	}
+}
@@ -16,13 +16,52 @@
 
 package cfgreader
 
-import "regexp"
+import (
+	"regexp"
+	"time"
+)
 
 // ServerConfig is the timeout configuration for the Earlybird REST API server
 type ServerConfig struct {
 	WriteTimeout int `json:"write-timeout"`
 	ReadTimeout  int `json:"read-timeout"`
 	IdleTimeout  int `json:"idle-timeout"`
+	// Scans submitted to POST /scans wait in a queue of ScanQueueSize for one of ScanWorkers workers
+	ScanQueueSize int `json:"scan-queue-size"`
+	ScanWorkers   int `json:"scan-workers"`
+	// JobRetention is how long (in seconds) finished scan jobs and their reports are kept
+	JobRetention int          `json:"job-retention"`
+	GitURLPolicy GitURLPolicy `json:"git-url-policy"`
+}
+
+// GitURLPolicy limits the repositories the REST API clones. Hosts and organizations match case-insensitively, a host
+// entry of `*.example.com` matches every subdomain and an organization entry is either `org` or `host/org`.
+type GitURLPolicy struct {
+	// AllowedSchemes defaults to https only
+	AllowedSchemes       []string `json:"allowed-schemes"`
+	AllowedHosts         []string `json:"allowed-hosts"`
+	DeniedHosts          []string `json:"denied-hosts"`
+	AllowedOrganizations []string `json:"allowed-organizations"`
+	DeniedOrganizations  []string `json:"denied-organizations"`
+	// AllowPrivateNetworks lets the server clone from loopback, private and link-local addresses
+	AllowPrivateNetworks bool `json:"allow-private-networks"`
+}
+
+// RuleSource is a rule pack layered over the rules of the config directory. A source is a git repository cloned at
+// the Pin (tag, branch or commit), a .tar.gz bundle at a URL whose Pin is its `sha256:<hex>` digest, or a local
+// directory. Sources with a higher Priority override the rules of the others that have the same Code, the config
+// directory itself has priority 0.
+type RuleSource struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	URL      string `json:"url"`
+	Path     string `json:"path"`
+	Pin      string `json:"pin"`
+	Priority int    `json:"priority"`
+	// PublicKey is the key the pack is signed with, when it isn't the rule_pack_public_key
+	PublicKey string `json:"public_key"`
+	// Dir is where the rules, falsepositives, labels and solutions directories of the source are read from
+	Dir string `json:"-"`
 }
 
 type AdjustedSeverityCategory struct {
@@ -43,7 +82,8 @@ type Configs struct {
 		Name string `json:"level_name"`
 		ID   int    `json:"level_id"`
 	} `json:"finding_levels"`
-	AnnotationsToSkip          []string                   `json:"text_ignore_patterns"`
+	AnnotationsToSkip []string `json:"text_ignore_patterns"`
+	// Deprecated: ConfigBaseUrl and ConfigFileURL are not used, -update only installs the signed rule pack at RulePackURL
 	ConfigBaseUrl              string                     `json:"config_base_url"`
 	ExtensionsToSkipTextScan   []string                   `json:"filename_skip_text_scanning_extensions"`
 	FailThreshold              int                        `json:"fail_threshold_level"`
@@ -52,6 +92,13 @@ type Configs struct {
 	ConfigFileURL              string                     `json:"earlybird_config_url"`
 	Version                    string                     `json:"version"`
 	AdjustedSeverityCategories []AdjustedSeverityCategory `json:"adjusted_severity_categories_patterns"`
+	// RulePackURL is where the manifest.json of the signed rule pack and its files are downloaded from by -update
+	RulePackURL string `json:"rule_pack_url"`
+	// RulePackPublicKey is the PEM file, relative to the config directory, of the Ed25519 key the rule packs are signed with
+	RulePackPublicKey string       `json:"rule_pack_public_key"`
+	RuleSources       []RuleSource `json:"rule_sources"`
+	// VerifyTrustedHosts are the hosts -verify may send a credential to when the scanned content names the host, e.g., a JWT issuer or database
+	VerifyTrustedHosts []string `json:"verify_trusted_hosts"`
 }
 
 // Config from -module-config-file flag
@@ -79,7 +126,19 @@ type EarlybirdConfig struct {
 	OutputFormat               string
 	OutputFile                 string
 	IgnoreFile                 string
+	UseGitignore               bool
 	IgnoreFailure              bool
+	BaselineFile               string
+	WriteBaselineFile          string
+	SuppressionsFile           string
+	MetricsFile                string
+	ProfileRules               bool
+	TestRules                  bool
+	LintConfig                 bool
+	Verify                     bool
+	VerifyEndpointOverride     string
+	VerifyRate                 int
+	VerifyTrustedHosts         []string
 	SeverityFailLevel          int
 	SeverityDisplayLevel       int
 	ConfidenceFailLevel        int
@@ -93,9 +152,16 @@ type EarlybirdConfig struct {
 	Suppress                   bool
 	VerboseEnabled             bool
 	GitStream                  bool
+	GitHistory                 bool
+	GitSince                   string
+	GitRevRange                string
+	GitScanRemoved             bool
 	MaxFileSize                int64
 	ShowFullLine               bool
 	FailScan                   bool
+	ScanTruncated              bool
+	Timeout                    time.Duration
+	GitURLPolicy               GitURLPolicy
 	RulesOnly                  bool
 	ExtensionsToSkipScan       []string
 	AnnotationsToSkipLine      []string
@@ -104,9 +170,12 @@ type EarlybirdConfig struct {
 	ShowSolutions              bool
 	Version                    string
 	WorkerCount                int
+	ReaderCount                int
+	ShardFiles                 bool
 	WorkLength                 int
 	HideMeta                   bool
 	StrictJKS                  bool
 	ModuleConfigs              ModuleConfigs
 	AdjustedSeverityCategories []AdjustedSeverityCategory
+	RuleSources                []RuleSource
 }
@@ -21,6 +21,7 @@ import (
 	"os"
 
 	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
 	"github.com/americanexpress/earlybird/v4/pkg/utils"
 )
 
@@ -49,26 +50,45 @@ var (
 	ptrStreamInput                = flag.Bool("stream", false, "Use stream IO as input instead of file(s)")
 	enableFlags                   arrayFlags
 	ptrUpdateFlag                 = flag.Bool("update", false, "Update module configurations")
+	ptrUpdateRollback             = flag.Bool("update-rollback", false, "Restore the module configurations which the last -update replaced")
 	ptrGitStreamInput             = flag.Bool("git-commit-stream", false, "Use stream IO of Git commit log as input instead of file(s) -- e.g., 'cat secrets.text > go-earlybird'")
+	ptrGitHistory                 = flag.Bool("git-history", false, "Scan the lines added by every commit in the git history of the -path directory or -git repository")
+	ptrGitSince                   = flag.String("git-since", "", "With -git-history, only scan commits made since this date -- e.g., 2021-01-31")
+	ptrGitRevRange                = flag.String("git-rev-range", "", "With -git-history, only scan commits in this revision range -- e.g., 'main..feature' (defaults to HEAD)")
+	ptrGitScanRemoved             = flag.Bool("git-scan-removed", false, "With -git-commit-stream or -git-history, also scan the lines each commit removed")
 	ptrVerbose                    = flag.Bool("verbose", false, "Reports details about file reads")
 	ptrSuppressSecret             = flag.Bool("suppress", false, "Suppress reporting of the secret found (important if output is going to Slack or other logs)")
 	ptrStrictJKS                  = flag.Bool("strict-jks", false, "Checks for private keys in the JKS file and return hits only if found")
 	ptrWorkerCount                = flag.Int("workers", 100, "Set number of workers.")
+	ptrReaderCount                = flag.Int("readers", 4, "Set number of files read at the same time.")
+	ptrShardFiles                 = flag.Bool("shard-files", false, "Have each worker read and scan whole files instead of sharing lines through one queue (-readers is not used)")
+	ptrTimeout                    = flag.Duration("timeout", 0, "Stop the scan after this long and report what was found so far as truncated -- e.g., 10m (no limit by default)")
 	ptrWorkLength                 = flag.Int("worksize", 2500, "Set Line Wrap Length.")
 	ptrMaxFileSize                = flag.Int64("max-file-size", 10240000, "Maximum file size to scan (in bytes)")
 	ptrShowFullLine               = flag.Bool("show-full-line", false, "Display the full line where the pattern match was found (warning: this can be dangerous with minified script files)")
 	ptrConfigDir                  = flag.String("config", utils.GetConfigDir(), "Directory where configuration files are stored")
 	ptrRulesOnly                  = flag.Bool("show-rules-only", false, "Display rules that would be run, but do not execute a scan")
+	ptrLintConfig                 = flag.Bool("lint-config", false, "Check the rules, false positives, labels and solutions files for mistakes, then exit")
+	ptrTestRules                  = flag.Bool("test-rules", false, "Check that every rule reports its Example and Examples and none of its NegativeExamples, then exit")
 	ptrSkipComments               = flag.Bool("skip-comments", false, "Skip scanning comments in files -- applies only to the 'content' module")
 	ptrIgnoreFPRules              = flag.Bool("ignore-fp-rules", false, "Ignore the false positive post-process rules")
 	ptrShowSolutions              = flag.Bool("show-solutions", false, "Display recommended solution for each finding")
 	ptrGitStagedFlag              = flag.Bool("git-staged", false, "Scan only git staged files")
 	ptrGitTrackedFlag             = flag.Bool("git-tracked", false, "Scan only git tracked files")
 	ptrPath                       = flag.String("path", utils.MustGetWD(), "Directory to scan (defaults to CWD) -- ABSOLUTE PATH ONLY")
-	ptrOutputFormat               = flag.String("format", "console", "Output format [ console | json | csv ]")
+	ptrOutputFormat               = flag.String("format", "console", "Output format [ console | json | csv | sarif ]")
 	ptrWithConsole                = flag.Bool("with-console", false, "While using --format, this flag will help to print findings in console")
 	ptrOutputFile                 = flag.String("file", "", "Output file -- e.g., 'go-earlybird --file=/home/jdoe/myfile.csv'")
 	ptrIgnoreFile                 = flag.String("ignorefile", userHomeDir+string(os.PathSeparator)+".ge_ignore", "Patterns File (including wildcards) for files to ignore.  (e.g. *.jpg)")
+	ptrUseGitignore               = flag.Bool("gitignore", false, "Also leave out the files matched by the .gitignore files of the scanned directory")
+	ptrBaselineFile               = flag.String("baseline", "", "Baseline file of known findings to leave out of the report and the fail threshold")
+	ptrWriteBaselineFile          = flag.String("write-baseline", "", "Record the fingerprints of all current findings in a baseline file")
+	ptrSuppressionsFile           = flag.String("suppressions", "", "Suppressions file of accepted findings, with their justification, owner and expiry (defaults to "+scan.SuppressionsFileName+" in the scanned directory)")
+	ptrMetricsFile                = flag.String("metrics-file", "", "Write scan metrics in the Prometheus text exposition format to this file when the scan is done")
+	ptrProfileRules               = flag.Bool("profile-rules", false, "Record the time each rule takes and how many of its matches are false positives, and print the most expensive and noisiest rules to stderr after the scan")
+	ptrVerify                     = flag.Bool("verify", false, "Try found credentials against their provider and report them as verified, invalid or unknown")
+	ptrVerifyEndpointOverride     = flag.String("verify-endpoint-override", "", "Send every -verify request to this scheme and host instead of the provider -- e.g., http://localhost:8080")
+	ptrVerifyRate                 = flag.Int("verify-rate", 5, "Maximum number of -verify requests per second")
 	ptrIgnoreFailure              = flag.Bool("ignore-failure", false, "Avoid the exit code 1 in case of scanner finds valid findings and meets fail threshold")
 	ptrFailSeverityThreshold      = flag.String("fail-severity", cfgreader.Settings.TranslateLevelID(cfgreader.Settings.FailThreshold), "Lowest severity level at which to fail "+levelOptions)
 	ptrDisplaySeverityThreshold   = flag.String("display-severity", cfgreader.Settings.TranslateLevelID(cfgreader.Settings.DisplayThreshold), "Lowest severity level to display "+levelOptions)
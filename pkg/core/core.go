@@ -19,6 +19,8 @@ package core
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"log"
@@ -26,17 +28,19 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/americanexpress/earlybird/v4/pkg/broadcast"
 	"github.com/americanexpress/earlybird/v4/pkg/buildflags"
 
 	"github.com/americanexpress/earlybird/v4/pkg/api"
+	"github.com/americanexpress/earlybird/v4/pkg/auth"
 	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
 	"github.com/americanexpress/earlybird/v4/pkg/file"
 	"github.com/americanexpress/earlybird/v4/pkg/git"
+	"github.com/americanexpress/earlybird/v4/pkg/metrics"
 	"github.com/americanexpress/earlybird/v4/pkg/scan"
 	configupdate "github.com/americanexpress/earlybird/v4/pkg/update"
 	"github.com/americanexpress/earlybird/v4/pkg/utils"
@@ -75,10 +79,17 @@ func (eb *EarlybirdCfg) GitClone(ptr PTRGitConfig) {
 			gitPassword = utils.GetGitURL(ptr.Repo, ptr.RepoUser)
 		}
 		var err error
+		// Keep clone progress off stdout when it carries a machine readable report
+		quiet := eb.Config.OutputFormat == "json" || eb.Config.OutputFormat == "sarif"
+		// History scans need every commit, not just the shallow clone of the latest one
+		cloneGitRepos := git.CloneGitRepos
+		if eb.Config.GitHistory {
+			cloneGitRepos = git.CloneGitReposWithHistory
+		}
 		if *ptr.RepoUser != "" { // use auth
-			eb.Config.SearchDir, err = git.CloneGitRepos(scanRepos, *ptr.RepoUser, gitPassword, *ptr.RepoBranch, (eb.Config.OutputFormat == "json"))
+			eb.Config.SearchDir, err = cloneGitRepos(scanRepos, *ptr.RepoUser, gitPassword, *ptr.RepoBranch, quiet)
 		} else {
-			eb.Config.SearchDir, err = git.CloneGitRepos(scanRepos, "", "", "", (eb.Config.OutputFormat == "json")) //Blank no auth
+			eb.Config.SearchDir, err = cloneGitRepos(scanRepos, "", "", "", quiet) //Blank no auth
 		}
 		if err != nil {
 			log.Println("Failed to clone repository:", err)
@@ -93,22 +104,15 @@ func (eb *EarlybirdCfg) GitClone(ptr PTRGitConfig) {
 
 // StartHTTP spins up the Earlybird REST API server
 func (eb *EarlybirdCfg) StartHTTP(ptr PTRHTTPConfig) {
-	// Set up http server
-	r := mux.NewRouter()
-	r.HandleFunc("/scan/git", api.GITScan(eb.Config)).Methods("GET")
-	r.HandleFunc("/scan", api.Scan(eb.Config)).Methods("POST")
-	r.HandleFunc("/labels", api.Labels(eb.Config.Version, scan.Labels)).Methods("GET")
-	r.HandleFunc("/categorylabels", api.LabelsPerCategory(eb.Config.Version, scan.Labels)).Methods("GET")
-	r.HandleFunc("/categories", api.Categories(eb.Config.Version, scan.CombinedRules)).Methods("GET")
-	// Catch-all: Serve our JavaScript application's entry-point (index.html) and static assets directly.
-	r.PathPrefix("/").Handler(http.FileServer(http.Dir(userHomeDir + string(os.PathSeparator) + ".eb-wa-build" + string(os.PathSeparator))))
-
 	var serverconfig cfgreader.ServerConfig
-	//Default time out settings
+	//Default time out and scan job settings
 	serverconfig = cfgreader.ServerConfig{
-		WriteTimeout: 60,
-		ReadTimeout:  60,
-		IdleTimeout:  120,
+		WriteTimeout:  60,
+		ReadTimeout:   60,
+		IdleTimeout:   120,
+		ScanQueueSize: 100,
+		ScanWorkers:   2,
+		JobRetention:  3600,
 	}
 
 	if *ptr.HTTPConfig != "" {
@@ -118,6 +122,55 @@ func (eb *EarlybirdCfg) StartHTTP(ptr PTRHTTPConfig) {
 		}
 	}
 
+	// The API only clones the repositories the git URL policy allows
+	eb.Config.GitURLPolicy = serverconfig.GitURLPolicy
+	git.InstallURLPolicy(serverconfig.GitURLPolicy)
+
+	// Only the clients in the auth file may use the API when one is given
+	var guard *auth.Guard
+	if *ptr.HTTPAuthFile != "" {
+		var err error
+		guard, err = auth.LoadGuard(*ptr.HTTPAuthFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// The per rule timings cost time on every line, so they're only taken when /metrics is served
+	if *ptr.HTTPMetrics {
+		metrics.Enable()
+	}
+
+	// Set up http server
+	jobs := api.NewJobQueue(eb.Config, serverconfig.ScanQueueSize, serverconfig.ScanWorkers, time.Second*time.Duration(serverconfig.JobRetention))
+	r := mux.NewRouter()
+	r.Handle("/scan/git", guard.Require(auth.ScopeScanGit, api.GITScan(eb.Config))).Methods("GET")
+	r.Handle("/scan", guard.Require(auth.ScopeScan, api.Scan(eb.Config))).Methods("POST")
+	r.Handle("/scan/git/stream", guard.Require(auth.ScopeScanGit, api.GITScanStream(eb.Config))).Methods("GET")
+	r.Handle("/scan/stream", guard.Require(auth.ScopeScan, api.ScanStream(eb.Config))).Methods("POST")
+	r.Handle("/scans", guard.RequireFunc(api.ScanJobScope, jobs.Create())).Methods("POST")
+	r.Handle("/scans/{id}", guard.Require("", jobs.Status())).Methods("GET")
+	r.Handle("/scans/{id}/report", guard.Require("", jobs.Report())).Methods("GET")
+	r.Handle("/scans/{id}", guard.Require("", jobs.Cancel())).Methods("DELETE")
+	if *ptr.HTTPMetrics {
+		r.Handle("/metrics", guard.Require(auth.ScopeRead, metrics.Handler())).Methods("GET")
+	}
+	// Reloading changes the rules every client scans with, so it's only served to admin clients of an auth file
+	if guard != nil {
+		r.Handle("/admin/reload", guard.Require(auth.ScopeAdmin, api.Reload(eb.Config))).Methods("POST")
+	}
+	r.Handle("/labels", guard.Require(auth.ScopeRead, currentRules(func(e *scan.Engine) http.HandlerFunc {
+		return api.Labels(eb.Config.Version, e.Labels)
+	}))).Methods("GET")
+	r.Handle("/categorylabels", guard.Require(auth.ScopeRead, currentRules(func(e *scan.Engine) http.HandlerFunc {
+		return api.LabelsPerCategory(eb.Config.Version, e.Labels)
+	}))).Methods("GET")
+	r.Handle("/categories", guard.Require(auth.ScopeRead, currentRules(func(e *scan.Engine) http.HandlerFunc {
+		return api.Categories(eb.Config.Version, e.Rules)
+	}))).Methods("GET")
+	// Catch-all: Serve our JavaScript application's entry-point (index.html) and static assets directly.
+	r.PathPrefix("/").Handler(http.FileServer(http.Dir(userHomeDir + string(os.PathSeparator) + ".eb-wa-build" + string(os.PathSeparator))))
+
 	srv := &http.Server{
 		Addr: *ptr.HTTP,
 		// Good practice to set timeouts to avoid Slowloris attacks.
@@ -132,6 +185,9 @@ func (eb *EarlybirdCfg) StartHTTP(ptr PTRHTTPConfig) {
 
 	if *ptr.HTTPS != "" {
 		srv.Addr = *ptr.HTTPS
+		if *ptr.HTTPSClientCA != "" {
+			srv.TLSConfig = clientCATLSConfig(*ptr.HTTPSClientCA, guard != nil)
+		}
 		err := http2.ConfigureServer(srv, &http2.Server{})
 		if err != nil {
 			log.Fatal("Failed to configure HTTP server", err)
@@ -144,26 +200,41 @@ func (eb *EarlybirdCfg) StartHTTP(ptr PTRHTTPConfig) {
 	}
 }
 
+// clientCATLSConfig verifies client certificates against the CA certificates in the file. Without an auth file
+// every connection must present a valid certificate, with one the certificate is optional since clients can also use
+// API keys or signed requests.
+func clientCATLSConfig(caFile string, withAuthFile bool) *tls.Config {
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		log.Fatal("Failed to read client CA file ", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		log.Fatal("No certificates found in client CA file ", caFile)
+	}
+	tlsConfig := &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		MinVersion: tls.VersionTLS12,
+	}
+	if withAuthFile {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig
+}
+
+// currentRules builds the handler from the rules in use on each request, so the rules and labels served follow a reload
+func currentRules(handler func(e *scan.Engine) http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(scan.CurrentEngine())(w, r)
+	}
+}
+
 // GetRuleModulesMap walks the `rules` directory and creates a hash map of module name to the filename
 // for example { content: 'content.json', ccnumber: 'ccnumber.json' },
-// and generates a list of the available modules in the `rules` directory
+// and generates a list of the available modules in the `rules` directory and the ones of the rule sources
 func (eb *EarlybirdCfg) GetRuleModulesMap() (err error) {
-	rulesPath := filepath.Join(eb.Config.ConfigDir, "rules")
-
-	eb.Config.RuleModulesFilenameMap = make(map[string]string)
-
-	err = filepath.Walk(rulesPath, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
-		}
-
-		moduleName := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
-
-		eb.Config.RuleModulesFilenameMap[moduleName] = info.Name()
-		eb.Config.AvailableModules = append(eb.Config.AvailableModules, moduleName)
-
-		return nil
-	})
+	eb.Config.RuleModulesFilenameMap, eb.Config.AvailableModules, err = cfgreader.GetRuleModules(eb.Config.ConfigDir, eb.Config.RuleSources)
 	return err
 }
 
@@ -189,6 +260,10 @@ func (eb *EarlybirdCfg) ConfigInit() {
 		log.Fatal("failed to load Earlybird config", err)
 	}
 
+	eb.Config.RuleSources, err = cfgreader.ResolveRuleSources(eb.Config.ConfigDir, cfgreader.Settings.RuleSources)
+	if err != nil {
+		log.Fatal("error in rule_sources: ", err)
+	}
 	err = eb.GetRuleModulesMap()
 	if err != nil {
 		log.Fatal("error getting rule modules", err)
@@ -197,6 +272,9 @@ func (eb *EarlybirdCfg) ConfigInit() {
 	//Assign CLI arguments to our global configuration
 	eb.Config.LevelMap = cfgreader.Settings.GetLevelMap()
 	eb.Config.WorkerCount = *ptrWorkerCount
+	eb.Config.ReaderCount = *ptrReaderCount
+	eb.Config.ShardFiles = *ptrShardFiles
+	eb.Config.Timeout = *ptrTimeout
 	eb.Config.WorkLength = *ptrWorkLength
 	eb.Config.ShowFullLine = *ptrShowFullLine
 	eb.Config.MaxFileSize = *ptrMaxFileSize
@@ -208,9 +286,28 @@ func (eb *EarlybirdCfg) ConfigInit() {
 	eb.Config.OutputFile = *ptrOutputFile
 	eb.Config.SearchDir = *ptrPath
 	eb.Config.IgnoreFile = *ptrIgnoreFile
+	eb.Config.UseGitignore = *ptrUseGitignore
 	eb.Config.IgnoreFailure = *ptrIgnoreFailure
+	eb.Config.BaselineFile = *ptrBaselineFile
+	eb.Config.WriteBaselineFile = *ptrWriteBaselineFile
+	eb.Config.SuppressionsFile = *ptrSuppressionsFile
+	eb.Config.MetricsFile = *ptrMetricsFile
+	if eb.Config.MetricsFile != "" {
+		metrics.Enable()
+	}
+	eb.Config.ProfileRules = *ptrProfileRules
+	eb.Config.Verify = *ptrVerify
+	eb.Config.VerifyEndpointOverride = *ptrVerifyEndpointOverride
+	eb.Config.VerifyRate = *ptrVerifyRate
+	eb.Config.VerifyTrustedHosts = cfgreader.Settings.VerifyTrustedHosts
 	eb.Config.GitStream = *ptrGitStreamInput
+	eb.Config.GitHistory = *ptrGitHistory
+	eb.Config.GitSince = *ptrGitSince
+	eb.Config.GitRevRange = *ptrGitRevRange
+	eb.Config.GitScanRemoved = *ptrGitScanRemoved
 	eb.Config.RulesOnly = *ptrRulesOnly
+	eb.Config.TestRules = *ptrTestRules
+	eb.Config.LintConfig = *ptrLintConfig
 	eb.Config.SkipComments = *ptrSkipComments
 	eb.Config.IgnoreFPRules = *ptrIgnoreFPRules
 	eb.Config.ShowSolutions = *ptrShowSolutions
@@ -226,12 +323,20 @@ func (eb *EarlybirdCfg) ConfigInit() {
 	}
 	// Check to see if the user opted to update config.  If they choose this option
 	// the configuration files will be updated and load config again.
-	if *ptrUpdateFlag {
-		doUpdate(eb.Config.ConfigDir, eb.Config.RulesConfigDir, earlybirdConfigPath, cfgreader.Settings.ConfigFileURL, eb.Config.RuleModulesFilenameMap)
+	if *ptrUpdateFlag || *ptrUpdateRollback {
+		if *ptrUpdateRollback {
+			doRollback(eb.Config.ConfigDir)
+		} else {
+			doUpdate(eb.Config.ConfigDir, eb.Config.RuleSources)
+		}
 		err = cfgreader.LoadConfig(&cfgreader.Settings, earlybirdConfigPath)
 		if err != nil {
 			log.Fatal("failed to load Earlybird config", err)
 		}
+		// The update may have fetched new modules
+		if err = eb.GetRuleModulesMap(); err != nil {
+			log.Fatal("error getting rule modules", err)
+		}
 	}
 
 	// Set the skip options (what not to scan) from configs
@@ -259,6 +364,15 @@ func (eb *EarlybirdCfg) ConfigInit() {
 		eb.LoadModuleConfig(*ptrModuleConfigFile)
 		eb.getDefaultModuleSettings()
 	}
+
+	// Rule tests cover every rule of the enabled modules, whatever level it would be displayed at
+	if eb.Config.TestRules {
+		eb.Config.ModuleConfigs.Modules = nil
+		for _, level := range eb.Config.LevelMap {
+			eb.Config.SeverityDisplayLevel = max(eb.Config.SeverityDisplayLevel, level)
+			eb.Config.ConfidenceDisplayLevel = max(eb.Config.ConfidenceDisplayLevel, level)
+		}
+	}
 }
 
 // Load module config if user has passed a config file for individual modules with -module-config-file flag
@@ -299,13 +413,65 @@ func (eb *EarlybirdCfg) Scan() {
 	if err != nil {
 		log.Fatal("Failed to get FileContext: ", err)
 	}
+	ctx := context.Background()
+	if eb.Config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, eb.Config.Timeout)
+		defer cancel()
+	}
+	var profile *scan.RuleProfile
+	if eb.Config.ProfileRules {
+		profile = scan.NewRuleProfile()
+		ctx = scan.WithRuleProfile(ctx, profile)
+	}
+	fileContext.Suppressions = &scan.SuppressionLog{}
+	fileContext.Unread = &scan.SkipLog{}
+	ctx = scan.WithSkipLog(scan.WithSuppressions(ctx, fileContext.Suppressions), fileContext.Unread)
+	// The baseline records every hit that can fail the scan, not only the displayed ones
+	var baselineLog *scan.BaselineLog
+	if eb.Config.WriteBaselineFile != "" {
+		baselineLog = &scan.BaselineLog{}
+		ctx = scan.WithBaselineLog(ctx, baselineLog)
+	}
 	HitChannel := make(chan scan.Hit)
-	go scan.SearchFiles(&eb.Config, fileContext.Files, fileContext.CompressPaths, fileContext.ConvertPaths, HitChannel)
+	historyErr := make(chan error, 1)
+	if eb.Config.GitHistory {
+		go func() {
+			historyErr <- eb.searchHistory(ctx, fileContext.StreamedFiles, HitChannel)
+		}()
+	} else {
+		historyErr <- nil
+		go scan.SearchFiles(ctx, &eb.Config, fileContext.Files, fileContext.CompressPaths, fileContext.ConvertPaths, HitChannel)
+	}
 
 	// Send output to a writer
 	eb.WriteResults(start, HitChannel, fileContext)
+	if baselineLog != nil {
+		eb.writeBaseline(baselineLog.Hits())
+	}
 
 	utils.DeleteGit(eb.Config.Gitrepo, eb.Config.SearchDir)
+	// A failed history walk only ends the process once the clone is removed
+	if err = <-historyErr; err != nil {
+		log.Fatal("Failed to scan the git history: ", err)
+	}
+	if profile != nil {
+		// The profile goes to stderr so it never ends up in the report
+		if err := writers.WriteRuleProfile(profile.Stats(), eb.Config.OutputFormat, os.Stderr); err != nil {
+			log.Println("Failed to write rule profile", err)
+		}
+	}
+	if eb.Config.MetricsFile != "" {
+		if err := metrics.WriteFile(eb.Config.MetricsFile); err != nil {
+			log.Println("Failed to write metrics file", err)
+		}
+	}
+	if suppressed := len(fileContext.Suppressions.Entries()); suppressed > 0 && eb.Config.OutputFormat == "console" {
+		fmt.Fprintln(os.Stderr, suppressed, "hits suppressed by ignore annotations, see the suppressed section of the JSON report.")
+	}
+	if eb.Config.ScanTruncated && eb.Config.OutputFormat == "console" {
+		fmt.Fprintln(os.Stderr, "Scan did not finish within", eb.Config.Timeout, "-- results are truncated.")
+	}
 	if eb.Config.FailScan {
 		if eb.Config.OutputFormat == "console" {
 			fmt.Fprintln(os.Stderr, "Scan detected findings above the accepted threshold -- Failing.")
@@ -316,9 +482,45 @@ func (eb *EarlybirdCfg) Scan() {
 	}
 }
 
+// searchHistory scans the files of each commit in the search directory as the history walk reads it, counting them in streamed.
+// It returns the error the walk stopped with, the walk being cut short by the end of ctx isn't one.
+func (eb *EarlybirdCfg) searchHistory(ctx context.Context, streamed *atomic.Int64, hits chan<- scan.Hit) error {
+	searchDir := eb.Config.SearchDir
+	opts := git.HistoryOptions{
+		Since:       eb.Config.GitSince,
+		RevRange:    eb.Config.GitRevRange,
+		ScanRemoved: eb.Config.GitScanRemoved,
+	}
+	batches := make(chan []scan.File)
+	var walkErr error
+	go func() {
+		defer close(batches)
+		walkErr = git.WalkHistory(searchDir, opts, func(files []scan.File) error {
+			select {
+			case batches <- files:
+				streamed.Add(int64(len(files)))
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	// The batches are drained before the scan returns, so the walk is done by then
+	scan.SearchFileBatches(ctx, &eb.Config, batches, hits)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return walkErr
+}
+
 // FileContext provides an inclusive file system context of our scan
 func (eb *EarlybirdCfg) FileContext() (fileContext file.Context, err error) {
 	cfg := eb.Config
+	if cfg.GitHistory {
+		// The commits are streamed to the scan by searchHistory, listing them here would hold the whole history in memory
+		fileContext.StreamedFiles = new(atomic.Int64)
+		return fileContext, nil
+	}
 	if cfg.SearchDir != "" {
 		// We're going to load a 'files' slice based on the CLI args
 		switch cfg.TargetType {
@@ -327,15 +529,12 @@ func (eb *EarlybirdCfg) FileContext() (fileContext file.Context, err error) {
 		case utils.Staged:
 			return file.GetGitFiles(utils.Staged, &cfg)
 		default:
-			return file.GetFiles(cfg.SearchDir, cfg.IgnoreFile, cfg.VerboseEnabled, cfg.MaxFileSize)
+			return file.GetFiles(cfg.SearchDir, cfg.IgnoreFile, cfg.UseGitignore, cfg.VerboseEnabled, cfg.MaxFileSize)
 		}
 	}
 	if cfg.GitStream {
-		var err error
-		fileContext.Files, err = git.ParseGitLog(bufio.NewReader(os.Stdin))
-		if err != nil {
-			return fileContext, err
-		}
+		fileContext.Files, err = git.ParseGitLog(bufio.NewReader(os.Stdin), cfg.GitScanRemoved)
+		return fileContext, err
 	}
 	fileContext.Files = file.GetFileFromStream(&cfg)
 	return fileContext, nil
@@ -357,23 +556,26 @@ func (eb *EarlybirdCfg) WriteResults(start time.Time, HitChannel chan scan.Hit,
 		go func() {
 			defer wg.Done()
 			err = writers.WriteConsole(listener1, "", eb.Config.ShowFullLine)
-			log.Printf("\n%d files scanned in %s", len(fileContext.Files), time.Since(start))
+			log.Printf("\n%d files scanned in %s", fileContext.FilesScanned(), time.Since(start))
 			log.Printf("\n%d rules observed\n", len(scan.CombinedRules))
 		}()
 		go func() {
 			defer wg.Done()
-			err = writers.WriteJSON(listener2, eb.Config, fileContext, eb.Config.OutputFile)
+			err = writers.WriteJSON(listener2, &eb.Config, fileContext, eb.Config.OutputFile)
 		}()
 		wg.Wait()
 	} else {
 		switch {
 		case eb.Config.OutputFormat == "json":
-			err = writers.WriteJSON(HitChannel, eb.Config, fileContext, eb.Config.OutputFile)
+			err = writers.WriteJSON(HitChannel, &eb.Config, fileContext, eb.Config.OutputFile)
 		case eb.Config.OutputFormat == "csv":
 			err = writers.WriteCSV(HitChannel, eb.Config.OutputFile)
+		case eb.Config.OutputFormat == "sarif":
+			engine := scan.CurrentEngine()
+			err = writers.WriteSARIF(HitChannel, eb.Config, engine.Rules, engine.SolutionConfigs, eb.Config.OutputFile)
 		default:
 			err = writers.WriteConsole(HitChannel, eb.Config.OutputFile, eb.Config.ShowFullLine)
-			log.Printf("\n%d files scanned in %s", len(fileContext.Files), time.Since(start))
+			log.Printf("\n%d files scanned in %s", fileContext.FilesScanned(), time.Since(start))
 			log.Printf("\n%d rules observed\n", len(scan.CombinedRules))
 		}
 	}
@@ -382,11 +584,82 @@ func (eb *EarlybirdCfg) WriteResults(start time.Time, HitChannel chan scan.Hit,
 	}
 }
 
-// Update configs from the latest in the repo
-func doUpdate(configDir, rulesConfigDir, configPath, appConfigURL string, ruleModulesFilenameMap map[string]string) {
-	err := configupdate.UpdateConfigFiles(configDir, rulesConfigDir, configPath, appConfigURL, ruleModulesFilenameMap)
+// writeBaseline saves the fingerprints of the hits in the baseline file once the scan is done
+func (eb *EarlybirdCfg) writeBaseline(hits []scan.Hit) {
+	if err := scan.WriteBaseline(eb.Config.WriteBaselineFile, eb.Config.Version, hits); err != nil {
+		log.Println("Writing baseline failed:", err)
+		return
+	}
+	if eb.Config.OutputFormat == "console" {
+		log.Println(len(hits), "findings recorded in baseline", eb.Config.WriteBaselineFile)
+	}
+}
+
+// Update configs from the latest signed rule pack and the rule sources
+func doUpdate(configDir string, ruleSources []cfgreader.RuleSource) {
+	opts := configupdate.Options{
+		ConfigDir:   configDir,
+		PackURL:     cfgreader.Settings.RulePackURL,
+		Sources:     ruleSources,
+		GitUser:     os.Getenv("gituser"),
+		GitPassword: os.Getenv("gitpassword"),
+		Validate:    validateStagedConfig(configDir),
+	}
+	if cfgreader.Settings.RulePackPublicKey != "" {
+		keyPath := cfgreader.Settings.RulePackPublicKey
+		if !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(configDir, keyPath)
+		}
+		var err error
+		if opts.PublicKey, err = configupdate.LoadPublicKey(keyPath); err != nil {
+			log.Fatal("Failed to load the rule pack public key: ", err)
+		}
+	}
+	version, err := configupdate.Update(opts)
 	if err != nil {
 		log.Fatal("Failed to update config:", err)
 	}
-	log.Println("Configurations updated.  Exiting")
+	log.Println("Configurations updated to rule pack version", version)
+}
+
+// Restore the configs the last update replaced
+func doRollback(configDir string) {
+	version, err := configupdate.Rollback(configDir)
+	if err != nil {
+		log.Fatal("Failed to roll back config:", err)
+	}
+	log.Println("Configurations rolled back to rule pack version", version)
+}
+
+// validateStagedConfig checks every module of an update staged in a copy of the config directory loads, with the
+// rule sources fetched into it
+func validateStagedConfig(configDir string) func(stageDir string) error {
+	return func(stageDir string) error {
+		var staged EarlybirdCfg
+		staged.Config.ConfigDir = stageDir
+		var err error
+		if staged.Config.RuleSources, err = cfgreader.ResolveRuleSources(configDir, cfgreader.Settings.RuleSources); err != nil {
+			return err
+		}
+		for i, source := range staged.Config.RuleSources {
+			if source.Type != cfgreader.SourceDir {
+				staged.Config.RuleSources[i].Dir = filepath.Join(stageDir, cfgreader.SourcesDir, source.Name)
+			}
+		}
+		if err = staged.GetRuleModulesMap(); err != nil {
+			return err
+		}
+		staged.Config.EnabledModulesMap = staged.Config.RuleModulesFilenameMap
+		staged.Config.RulesConfigDir = path.Join(stageDir, rulesDir)
+		staged.Config.FalsePositivesConfigDir = path.Join(stageDir, falsePositivesDir)
+		staged.Config.LabelsConfigDir = path.Join(stageDir, labelsDir)
+		staged.Config.SolutionsConfigDir = path.Join(stageDir, solutionsDir)
+		staged.Config.ShowSolutions = true
+		for _, level := range cfgreader.Settings.GetLevelMap() {
+			staged.Config.SeverityDisplayLevel = max(staged.Config.SeverityDisplayLevel, level)
+			staged.Config.ConfidenceDisplayLevel = max(staged.Config.ConfidenceDisplayLevel, level)
+		}
+		_, err = scan.NewEngine(staged.Config)
+		return err
+	}
 }
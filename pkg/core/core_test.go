@@ -17,8 +17,10 @@
 package core
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 
 	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
@@ -95,3 +97,19 @@ func TestEarlybirdCfg_getDefaultModuleSettings(t *testing.T) {
 
 	cleanup()
 }
+
+func TestEarlybirdCfg_searchHistory(t *testing.T) {
+	// The directory is no git repository, so the walk fails and the error goes back to the caller
+	history := EarlybirdCfg{Config: eb.Config}
+	history.Config.SearchDir = t.TempDir()
+	hits := make(chan scan.Hit)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- history.searchHistory(context.Background(), new(atomic.Int64), hits)
+	}()
+	for range hits {
+	}
+	if err := <-errs; err == nil {
+		t.Error("searchHistory() of a directory without a repository returned no error")
+	}
+}
@@ -20,13 +20,15 @@ import (
 	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
 )
 
-//EarlybirdCore is the main interface for interacting with Earlybird as a package
+//EarlybirdCore is the interface of the Earlybird CLI, embed the scanner in other programs with the earlybird package instead
 type EarlybirdCore interface {
-	ConfigInit() cfgReader.EarlybirdConfig
-	StartHTTP()
+	ConfigInit()
+	StartHTTP(ptr PTRHTTPConfig)
 	Scan()
 }
 
+var _ EarlybirdCore = &EarlybirdCfg{}
+
 //EarlybirdCfg is the global Earlybird configuration
 type EarlybirdCfg struct {
 	Config cfgReader.EarlybirdConfig
@@ -39,6 +41,12 @@ type PTRHTTPConfig struct {
 	HTTPS      *string
 	HTTPSCert  *string
 	HTTPSKey   *string
+	// HTTPSClientCA verifies client certificates on the HTTPS listener
+	HTTPSClientCA *string
+	// HTTPAuthFile lists the clients allowed to use the API, it is open to everyone without one
+	HTTPAuthFile *string
+	// HTTPMetrics serves /metrics and takes the per rule timings it reports
+	HTTPMetrics *bool
 }
 
 //PTRGitConfig is the configuration definition for Earlybird git scans
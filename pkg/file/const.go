@@ -19,4 +19,6 @@ package file
 const (
 	notTrackedDir string = "This does not seem to be a git tracked directory. Exiting"
 	gitErr        string = "Failed to find any git files. Exiting"
+	geIgnoreFile  string = ".ge_ignore"
+	gitIgnoreFile string = ".gitignore"
 )
@@ -28,24 +28,36 @@ import (
 	"mime/multipart"
 	"os"
 	"os/exec"
-	"path"
 	"path/filepath"
 	"strings"
 
 	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
 	"github.com/americanexpress/earlybird/v4/pkg/scan"
 	"github.com/americanexpress/earlybird/v4/pkg/utils"
-	"github.com/americanexpress/earlybird/v4/pkg/wildcard"
 )
 
-var (
-	ignoreFiles    = [...]string{".ge_ignore"}
-	ignorePatterns []string
-)
+//defaultIgnorePatterns are ignored in every scan, on top of the ignore files
+var defaultIgnorePatterns = []string{".git/"}
+
+// FilesScanned counts the files of the scan, the ones listed up front and the ones streamed to it
+func (c Context) FilesScanned() int {
+	count := len(c.Files)
+	if c.StreamedFiles != nil {
+		count += int(c.StreamedFiles.Load())
+	}
+	return count
+}
+
+// Skipped lists the files left out of the scan, the ones skipped up front and the ones the scan couldn't read
+func (c Context) Skipped() []string {
+	return append(append([]string(nil), c.SkippedFiles...), c.Unread.Paths()...)
+}
 
 // MultipartToScanFiles converts the multipart file upload into Earlybird files
 func MultipartToScanFiles(files []*multipart.FileHeader, cfg cfgreader.EarlybirdConfig) (fileList []scan.File, err error) {
-	ignorePatterns = getIgnorePatterns(cfg.SearchDir, cfg.IgnoreFile, cfg.VerboseEnabled)
+	// Uploaded files aren't on disk, so only the ignore files at the root of the search directory apply
+	ignores := newIgnoreMatcher(cfg.SearchDir, cfg.IgnoreFile, cfg.UseGitignore, cfg.VerboseEnabled)
+	ignores.nested = false
 
     var buffer bytes.Buffer
 	for _, fheader := range files {
@@ -72,7 +84,7 @@ func MultipartToScanFiles(files []*multipart.FileHeader, cfg cfgreader.Earlybird
 			fileNameWithPathPrefix = pathSeparator + fileNameWithPathPrefix
 		}
 		//Skip file with extensions Earlybird ignores
-		if isIgnoredFile(fileNameWithPathPrefix, cfg.SearchDir) {
+		if ignores.ignored(fileNameWithPathPrefix, false) {
 			continue
 		}
 
@@ -137,7 +149,7 @@ func MultipartToScanFiles(files []*multipart.FileHeader, cfg cfgreader.Earlybird
 
 // GetGitFiles Builds the list of staged or tracked files
 func GetGitFiles(fileType string, cfg *cfgreader.EarlybirdConfig) (fileContext Context, err error) {
-	ignorePatterns = getIgnorePatterns(cfg.SearchDir, cfg.IgnoreFile, cfg.VerboseEnabled)
+	ignores := newIgnoreMatcher(cfg.SearchDir, cfg.IgnoreFile, cfg.UseGitignore, cfg.VerboseEnabled)
 
 	var (
 		output       []byte
@@ -158,21 +170,21 @@ func GetGitFiles(fileType string, cfg *cfgreader.EarlybirdConfig) (fileContext C
 		return fileContext, err
 	}
 
-	fileList, skipList = parseGitFiles(output, cfg.VerboseEnabled, cfg.MaxFileSize, cfg.SearchDir)
+	fileList, skipList = parseGitFiles(output, cfg.VerboseEnabled, cfg.MaxFileSize, ignores)
 	compressList, fileList = separateCompressedAndUncompressed(fileList)
-	compressList, fileContext.CompressPaths, err = GetCompressedFiles(compressList, cfg.SearchDir) //Get the files within our compressed list
+	compressList, fileContext.CompressPaths, err = getCompressedFiles(compressList, ignores) //Get the files within our compressed list
 	if err != nil {
 		return fileContext, err
 	}
 	fileContext.Files = append(fileList, compressList...)
 	convertList, fileContext.ConvertPaths = GetConvertedFiles(fileContext.Files) //Get the files that need to be converted and convert them to plaintext
 	fileContext.Files = append(fileContext.Files, convertList...)
-	fileContext.IgnorePatterns = ignorePatterns
+	fileContext.IgnorePatterns = ignores.patterns()
 	fileContext.SkippedFiles = skipList
 	return fileContext, nil
 }
 
-func parseGitFiles(out []byte, verbose bool, maxFileSize int64, searchDir string) (fileList []scan.File, skipList []string) {
+func parseGitFiles(out []byte, verbose bool, maxFileSize int64, ignores *ignoreMatcher) (fileList []scan.File, skipList []string) {
 	var curFile scan.File
 	// Convert byteArray to string
 	gitFiles := string(out)
@@ -192,7 +204,7 @@ func parseGitFiles(out []byte, verbose bool, maxFileSize int64, searchDir string
 
 			curFile.Name = filepath.Base(scanner.Text())
 			//Skip file with extensions Earlybird ignores
-			if !isIgnoredFile(absolutePath, searchDir) {
+			if !ignores.ignored(absolutePath, false) {
 				if fileExists := Exists(curFile.Path); fileExists {
 					pathIsDirectory, dirErr := isDirectory(curFile.Path)
 					if dirErr != nil {
@@ -218,16 +230,27 @@ func parseGitFiles(out []byte, verbose bool, maxFileSize int64, searchDir string
 	return fileList, skipList
 }
 
-// GetFiles Build the list of files
-func GetFiles(searchDir, ignoreFile string, verbose bool, maxFileSize int64) (fileContext Context, err error) {
-	ignorePatterns = getIgnorePatterns(searchDir, ignoreFile, verbose)
+// GetFiles Build the list of files, leaving out the ones matching the patterns of the ignoreFile, the .ge_ignore files
+// of the search directory and its subdirectories and, when useGitignore is set, their .gitignore files
+func GetFiles(searchDir, ignoreFile string, useGitignore, verbose bool, maxFileSize int64) (fileContext Context, err error) {
+	ignores := newIgnoreMatcher(searchDir, ignoreFile, useGitignore, verbose)
 	fileList := make([]scan.File, 0)
 	var curFile scan.File
 	err = filepath.Walk(searchDir, func(path string, f os.FileInfo, err error) error {
 		if err != nil {
 			log.Println("Error reading directory: ", err)
 		}
-		if !isIgnoredFile(path, searchDir) {
+		isDir := f != nil && f.IsDir()
+		if ignores.ignored(path, isDir) {
+			fileContext.SkippedFiles = append(fileContext.SkippedFiles, path)
+			if verbose {
+				log.Println("Ignoring", path, ". File blacklisted.")
+			}
+			// Nothing inside an ignored directory can be scanned, so don't walk it
+			if isDir && err == nil {
+				return filepath.SkipDir
+			}
+		} else {
 			// Ignore the path if it's a directory
 			pathIsDirectory, isDirErr := isDirectory(path)
 			if !pathIsDirectory {
@@ -248,11 +271,6 @@ func GetFiles(searchDir, ignoreFile string, verbose bool, maxFileSize int64) (fi
 					}
 				}
 			}
-		} else {
-			fileContext.SkippedFiles = append(fileContext.SkippedFiles, path)
-			if verbose {
-				log.Println("Ignoring", path, ". File blacklisted.")
-			}
 		}
 		return err
 	})
@@ -262,62 +280,65 @@ func GetFiles(searchDir, ignoreFile string, verbose bool, maxFileSize int64) (fi
 
 	var compressList, convertList []scan.File
 	compressList, fileList = separateCompressedAndUncompressed(fileList)
-	compressList, fileContext.CompressPaths, err = GetCompressedFiles(compressList, searchDir) //Get the files within our compressed list
+	compressList, fileContext.CompressPaths, err = getCompressedFiles(compressList, ignores) //Get the files within our compressed list
 	if err != nil {
 		return fileContext, err
 	}
 	fileContext.Files = append(fileList, compressList...)
 	convertList, fileContext.ConvertPaths = GetConvertedFiles(fileContext.Files) //Get the files that need to be converted and convert them to plaintext
 	fileContext.Files = append(fileContext.Files, convertList...)
-	fileContext.IgnorePatterns = ignorePatterns
+	fileContext.IgnorePatterns = ignores.patterns()
 	return fileContext, nil
 }
 
 // GetFileFromStream Builds a file as a collection of lines from the input stream.
 // This will be fed to the scan modules.
 func GetFileFromStream(cfg *cfgreader.EarlybirdConfig) []scan.File {
-	// Read Stdin
-	scanner := bufio.NewScanner(os.Stdin)
 	// The scan modules will expect a list of Files, so create that list with just one
-	fileList := make([]scan.File, 0)
-	curFile := scan.File{
-		Name: "buffer",
+	curFile, err := ReaderToScanFile("buffer", os.Stdin, cfg)
+	if err != nil {
+		log.Fatal("Reading standard input:", err)
+	}
+	return []scan.File{curFile}
+}
+
+// ReaderToScanFile builds an in memory file named name from the lines of the reader. Lines holding an
+// EARLYBIRD-IGNORE annotation are kept as they are, the scan applies the annotation and records what it suppressed.
+func ReaderToScanFile(name string, r io.Reader, cfg *cfgreader.EarlybirdConfig) (curFile scan.File, err error) {
+	curFile = scan.File{
+		Name: name,
 		Path: "buffer",
 	}
 
+	// Key stores are scanned from the raw content rather than line by line
+	if filepath.Ext(name) == ".jks" {
+		curFile.Raw, err = io.ReadAll(r)
+		return curFile, err
+	}
+
 	var line scan.Line
-	// Set the stage for escaping lines with the EARLYBIRD-IGNORE annotation
-	ignoreAnnotationInLine := false
-	nextLineIgnored := false
-
-	// Read the initial line
-	for scanner.Scan() {
-		lineText := scanner.Text()
-		// If we find the EARLYBIRD-IGNORE annotation...
-		if scan.IsIgnoreAnnotation(cfg, lineText) {
-			lineText = ""
-			ignoreAnnotationInLine = true
-			nextLineIgnored = true
+	reader := bufio.NewReader(r)
+	for {
+		lineText, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return curFile, readErr
 		}
-		// If we had previously found the EARLYBIRD-IGNORE annotation...
-		if nextLineIgnored {
-			lineText = ""
+		if readErr == io.EOF && lineText == "" {
+			break
 		}
+		lineText = strings.TrimSuffix(strings.TrimSuffix(lineText, "\n"), "\r")
+
 		line.LineNum = line.LineNum + 1
 		line.LineValue = lineText
 		line.FilePath = curFile.Path
+		line.FileName = name
 		curFile.Lines = append(curFile.Lines, line)
 
-		// Cancel any flags to ignore the next line for the next iteration
-		if nextLineIgnored && !ignoreAnnotationInLine {
-			nextLineIgnored = false
+		if readErr == io.EOF {
+			break
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		log.Fatal("Reading standard input:", err)
-	}
-	fileList = append(fileList, curFile)
-	return fileList
+	return curFile, nil
 }
 
 // GetFileSize returns the file size of target file
@@ -344,73 +365,6 @@ func hasCompressionExtension(path string) bool {
 	}
 }
 
-// Read in .ge_ignore file and ignore files matching the patterns
-func getIgnorePatterns(filePath, ignoreFile string, verbose bool) (ignorePatterns []string) {
-	ignorePatterns = append(ignorePatterns, "*.git/*")
-
-	// Loop through the files defined to contain ignore patterns (.ge_ignore, .gitignore, etc.)
-	for _, ignoreFile := range ignoreFiles {
-		actualFilePath := path.Join(filePath, ignoreFile)
-		if Exists(actualFilePath) {
-			file, err := os.Open(actualFilePath)
-			if err != nil {
-				log.Fatal(err)
-			}
-			defer file.Close()
-			scanner := bufio.NewScanner(file)
-			var line, firstChar string
-			for scanner.Scan() {
-				line = scanner.Text()
-
-				// Ignore comment lines (starting with #)
-				runes := []rune(line)
-				firstChar = string(runes[0:1])
-				if !(firstChar == "#") && strings.Trim(line, " ") != "" {
-					ignorePatterns = append(ignorePatterns, line)
-				}
-			}
-		}
-	}
-
-	if ignoreFile != "" {
-		file, err := os.Open(ignoreFile)
-		if err != nil {
-			log.Println("Failed to open ignore file", err)
-		} else {
-			defer file.Close()
-			scanner := bufio.NewScanner(file)
-			var line, firstChar string
-			for scanner.Scan() {
-				line = scanner.Text()
-
-				// Ignore comment lines (starting with #)
-				runes := []rune(line)
-				firstChar = string(runes[0:1])
-				if !(firstChar == "#") && strings.Trim(line, " ") != "" {
-					ignorePatterns = append(ignorePatterns, line)
-				}
-			}
-		}
-	}
-
-	if verbose {
-		log.Println("Ignore pattern: ", strings.Join(ignorePatterns, ", "))
-	}
-	return ignorePatterns
-}
-
-// If the file matches a pattern in one of the ignore files, return true
-func isIgnoredFile(fileName string, fileRoot string) bool {
-	// ignore root directory when checking ignore matching
-	trimmedName := strings.Replace(fileName, fileRoot, "", 1)
-	for _, pattern := range ignorePatterns {
-		if wildcard.PatternMatch(trimmedName, pattern) {
-			return true
-		}
-	}
-	return false
-}
-
 // Check a path to see if it's a directory
 func isDirectory(path string) (bool, error) {
 	fileInfo, err := os.Stat(path)
@@ -458,8 +412,14 @@ func separateCompressedAndUncompressed(files []scan.File) (compressed, uncompres
 	return compressed, uncompressed
 }
 
-// GetCompressedFiles provides all the files contained within compressed files
+// GetCompressedFiles provides all the files contained within compressed files, leaving out the ones the ignore files of rootPath match
 func GetCompressedFiles(files []scan.File, rootPath string) (newfiles []scan.File, compresspaths []string, err error) {
+	return getCompressedFiles(files, newIgnoreMatcher(rootPath, "", false, false))
+}
+
+// getCompressedFiles unpacks the compressed files, their contents are matched against the ignore patterns as if the
+// archive was a directory
+func getCompressedFiles(files []scan.File, ignores *ignoreMatcher) (newfiles []scan.File, compresspaths []string, err error) {
 	//check if file list contains compressed files, if so, scan their contents
 	for _, file := range files {
 		//Unpack and append to file list
@@ -476,7 +436,9 @@ func GetCompressedFiles(files []scan.File, rootPath string) (newfiles []scan.Fil
 			continue
 		}
 		for _, subfile := range filenames {
-			if !isIgnoredFile(subfile, rootPath) && !scan.CompressPattern.MatchString(subfile) {
+			inside, _ := filepath.Rel(tmppath, subfile)
+			isDir, _ := isDirectory(subfile)
+			if !ignores.ignored(filepath.Join(file.Path, inside), isDir) && !scan.CompressPattern.MatchString(subfile) {
 				var curFile scan.File
 				curFile.Path = file.Path + "/" + filepath.Base(subfile)
 				curFile.Name = subfile //Build view file name in format: file.zip/contents/file
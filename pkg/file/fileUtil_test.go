@@ -17,6 +17,7 @@
 package file
 
 import (
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
 	"github.com/americanexpress/earlybird/v4/pkg/scan"
 	"os/exec"
 	"reflect"
@@ -38,7 +39,6 @@ func init() {
 
 	workDir = workingDir
 	projectRoot = path.Join(workingDir, "../../")
-	ignorePatterns = getIgnorePatterns(projectRoot, path.Join(projectRoot, ".ge_ignore"), false)
 }
 
 func TestGetFiles(t *testing.T) {
@@ -47,7 +47,7 @@ func TestGetFiles(t *testing.T) {
 	verbose := false
 	maxFileSize := int64(1000000)
 
-	fileContext, err := GetFiles(searchDir, ignoreFile, verbose, maxFileSize)
+	fileContext, err := GetFiles(searchDir, ignoreFile, false, verbose, maxFileSize)
 	if err != nil {
 		t.Errorf("GetFiles() err = %v", err)
 	}
@@ -62,6 +62,60 @@ func TestGetFiles(t *testing.T) {
 	}
 }
 
+func TestReaderToScanFile(t *testing.T) {
+	cfg := &cfgreader.EarlybirdConfig{AnnotationsToSkipLine: []string{"EARLYBIRD-IGNORE"}}
+	tests := []struct {
+		name      string
+		fileName  string
+		content   string
+		wantLines []string
+		wantRaw   bool
+	}{
+		{
+			name:      "Split lines and strip line endings",
+			fileName:  "config.py",
+			content:   "host = 'localhost'\r\npassword = 'secret'",
+			wantLines: []string{"host = 'localhost'", "password = 'secret'"},
+		},
+		{
+			name:      "Keep annotated lines for the scan to apply",
+			fileName:  "config.py",
+			content:   "# EARLYBIRD-IGNORE-NEXT-LINE\npassword = 'secret'\npassword = 'other'\n",
+			wantLines: []string{"# EARLYBIRD-IGNORE-NEXT-LINE", "password = 'secret'", "password = 'other'"},
+		},
+		{
+			name:     "Keep key stores raw",
+			fileName: "keystore.jks",
+			content:  "binary",
+			wantRaw:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReaderToScanFile(tt.fileName, strings.NewReader(tt.content), cfg)
+			if err != nil {
+				t.Fatalf("ReaderToScanFile() error = %v", err)
+			}
+			if got.Name != tt.fileName || got.Path != "buffer" {
+				t.Errorf("ReaderToScanFile() file = %s at %s, want %s at buffer", got.Name, got.Path, tt.fileName)
+			}
+			if tt.wantRaw != (string(got.Raw) == tt.content) {
+				t.Errorf("ReaderToScanFile() raw = %q", got.Raw)
+			}
+			var gotLines []string
+			for i, line := range got.Lines {
+				if line.LineNum != i+1 {
+					t.Errorf("ReaderToScanFile() line %d numbered %d", i+1, line.LineNum)
+				}
+				gotLines = append(gotLines, line.LineValue)
+			}
+			if !reflect.DeepEqual(gotLines, tt.wantLines) {
+				t.Errorf("ReaderToScanFile() lines = %q, want %q", gotLines, tt.wantLines)
+			}
+		})
+	}
+}
+
 func TestGetFileSize(t *testing.T) {
 	type args struct {
 		path string
@@ -128,50 +182,6 @@ func Test_getFileSizeOK(t *testing.T) {
 	}
 }
 
-func Test_getIgnorePatterns(t *testing.T) {
-	if gotIgnorePatterns := getIgnorePatterns(projectRoot, ".ge_ignore", false); len(ignorePatterns) == 0 {
-		t.Errorf("getIgnorePatterns() = %v, want multiple patterns", gotIgnorePatterns)
-	}
-}
-
-func Test_isIgnoredFile(t *testing.T) {
-	if os.Getenv("local") == "" {
-		t.Skip("If test cases not running locally, skip cloning external repositories for CI/CD purposes.")
-	}
-	type args struct {
-		fileName string
-		rootPath string
-	}
-	tests := []struct {
-		name string
-		args args
-		want bool
-	}{
-		{
-			name: "Check if file is ignored",
-			args: args{
-				fileName: "/npm-shrinkwrap.json",
-			},
-			want: true,
-		},
-		{
-			name: "Check if file is ignored",
-			args: args{
-				fileName: "/test.js",
-				rootPath: "src/node_modules",
-			},
-			want: false,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := isIgnoredFile(tt.args.fileName, tt.args.rootPath); got != tt.want {
-				t.Errorf("isIgnoredFile() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func Test_isDirectory(t *testing.T) {
 	type args struct {
 		path string
@@ -414,7 +424,7 @@ func Test_parseGitFiles(t *testing.T) {
 	if len(output) == 0 {
 		t.Errorf("parseGitFiles() output = %v, want multiple files", output)
 	}
-	_, skipFiles := parseGitFiles(output, true, int64(1000000), projectRoot)
+	_, skipFiles := parseGitFiles(output, true, int64(1000000), newIgnoreMatcher(projectRoot, "", false, false))
 
 	if len(skipFiles) == 0 {
 		t.Errorf("parseGitFiles() skipFiles = %v, want multiple files", skipFiles)
@@ -0,0 +1,232 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package file
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/americanexpress/earlybird/v4/pkg/wildcard"
+)
+
+// newIgnoreMatcher loads the built in patterns, the patterns of the ignore file passed with -ignorefile and the ignore files
+// at the root of the scan. The ignore files of the directories below the root are read as the scan gets to them.
+func newIgnoreMatcher(root, ignoreFile string, useGitignore, verbose bool) *ignoreMatcher {
+	m := &ignoreMatcher{
+		root:      root,
+		fileNames: []string{geIgnoreFile},
+		dirs:      make(map[string][]ignoreRule),
+		combined:  make(map[string][]ignoreRule),
+		nested:    true,
+		verbose:   verbose,
+	}
+	// Git applies a .gitignore before the more specific .ge_ignore of the same directory could re-include a file
+	if useGitignore {
+		m.fileNames = []string{gitIgnoreFile, geIgnoreFile}
+	}
+	for _, pattern := range defaultIgnorePatterns {
+		if rule, ok := parseIgnoreRule(pattern, ""); ok {
+			m.global = append(m.global, rule)
+		}
+	}
+	if ignoreFile != "" {
+		rules, err := readIgnoreFile(ignoreFile, "")
+		if err != nil {
+			log.Println("Failed to open ignore file", err)
+		}
+		m.global = append(m.global, rules...)
+	}
+
+	if verbose {
+		log.Println("Ignore pattern: ", strings.Join(m.patterns(), ", "))
+	}
+	return m
+}
+
+// parseIgnoreRule reads a line of an ignore file in directory dir, ok is false for blank lines and comments
+func parseIgnoreRule(line, dir string) (rule ignoreRule, ok bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are dropped unless they are escaped with a backslash
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	rule.pattern = line
+	rule.dir = dir
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	// A pattern with a slash before its end is relative to the directory of the ignore file, otherwise it matches the name at any depth
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	rule.glob = strings.ReplaceAll(line, "[!", "[^")
+	return rule, true
+}
+
+// readIgnoreFile reads the rules of an ignore file found in directory dir, relative to the scan root
+func readIgnoreFile(name, dir string) (rules []ignoreRule, err error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text(), dir); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// matches reports whether the rule applies to the path, relative to the scan root
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.dir != "" {
+		if !strings.HasPrefix(rel, r.dir+"/") {
+			return false
+		}
+		rel = rel[len(r.dir)+1:]
+	}
+	if !r.anchored {
+		ok, _ := path.Match(r.glob, path.Base(rel))
+		return ok
+	}
+	return wildcard.PathMatch(r.glob, rel)
+}
+
+// ignored reports whether the file or directory at the path is ignored. Like git, nothing inside an ignored directory
+// can be re-included by a negated pattern.
+func (m *ignoreMatcher) ignored(name string, isDir bool) bool {
+	rel := m.relative(name)
+	if rel == "" {
+		return false
+	}
+	segments := strings.Split(rel, "/")
+	for i := 1; i < len(segments); i++ {
+		if m.matches(strings.Join(segments[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matches(rel, isDir)
+}
+
+// matches applies the rules of every ignore file above the path, the last one matching decides
+func (m *ignoreMatcher) matches(rel string, isDir bool) (ignored bool) {
+	for _, rule := range m.rulesFor(path.Dir(rel)) {
+		if rule.matches(rel, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// relative turns a path on disk, or a path like /src/main.go of an uploaded or git listed file, into a slash separated
+// path relative to the scan root
+func (m *ignoreMatcher) relative(name string) string {
+	if m.root != "" {
+		if rel, err := filepath.Rel(m.root, name); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+	}
+	return strings.Trim(path.Clean("/"+filepath.ToSlash(name)), "/")
+}
+
+// rulesFor returns the rules for the entries of a directory, from the least to the most specific
+func (m *ignoreMatcher) rulesFor(dir string) []ignoreRule {
+	if dir == "." {
+		dir = ""
+	}
+	if rules, ok := m.combined[dir]; ok {
+		return rules
+	}
+	var rules []ignoreRule
+	if dir == "" {
+		rules = append(append(rules, m.global...), m.dirRules("")...)
+	} else if m.nested {
+		rules = append(append(rules, m.rulesFor(path.Dir(dir))...), m.dirRules(dir)...)
+	} else {
+		rules = m.rulesFor("")
+	}
+	m.combined[dir] = rules
+	return rules
+}
+
+// dirRules reads the ignore files of a directory, once
+func (m *ignoreMatcher) dirRules(dir string) []ignoreRule {
+	if rules, ok := m.dirs[dir]; ok {
+		return rules
+	}
+	var rules []ignoreRule
+	for _, fileName := range m.fileNames {
+		ignoreFile := filepath.Join(m.root, filepath.FromSlash(dir), fileName)
+		// Paths inside archives have no ignore files of their own
+		if info, err := os.Stat(ignoreFile); err != nil || info.IsDir() {
+			continue
+		}
+		fileRules, err := readIgnoreFile(ignoreFile, dir)
+		if err != nil {
+			log.Println("Failed to read ignore file", err)
+		}
+		if m.verbose && dir != "" {
+			log.Println("Ignore patterns of", ignoreFile, ":", len(fileRules))
+		}
+		rules = append(rules, fileRules...)
+	}
+	m.dirs[dir] = rules
+	return rules
+}
+
+// patterns lists the patterns applied so far for the report, the ones of nested ignore files prefixed with their directory
+func (m *ignoreMatcher) patterns() (patterns []string) {
+	for _, rule := range m.rulesFor("") {
+		patterns = append(patterns, rule.pattern)
+	}
+	var nested []string
+	for dir, rules := range m.dirs {
+		if dir == "" {
+			continue
+		}
+		for _, rule := range rules {
+			nested = append(nested, dir+"/: "+rule.pattern)
+		}
+	}
+	sort.Strings(nested)
+	return append(patterns, nested...)
+}
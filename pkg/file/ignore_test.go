@@ -0,0 +1,174 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package file
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func Test_parseIgnoreRule(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   ignoreRule
+		wantOk bool
+	}{
+		{line: "# comment"},
+		{line: "   "},
+		{line: "*.jpg", want: ignoreRule{pattern: "*.jpg", glob: "*.jpg"}, wantOk: true},
+		{line: "build/", want: ignoreRule{pattern: "build/", glob: "build", dirOnly: true}, wantOk: true},
+		{line: "/config.env  ", want: ignoreRule{pattern: "/config.env", glob: "config.env", anchored: true}, wantOk: true},
+		{line: "!keep.txt", want: ignoreRule{pattern: "!keep.txt", glob: "keep.txt", negate: true}, wantOk: true},
+		{line: `\!important`, want: ignoreRule{pattern: `\!important`, glob: "!important"}, wantOk: true},
+		{line: "docs/**/*.md", want: ignoreRule{pattern: "docs/**/*.md", glob: "docs/**/*.md", anchored: true}, wantOk: true},
+		{line: "[!a]*.txt", want: ignoreRule{pattern: "[!a]*.txt", glob: "[^a]*.txt"}, wantOk: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, gotOk := parseIgnoreRule(tt.line, "")
+			if gotOk != tt.wantOk || (gotOk && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("parseIgnoreRule() = %+v, %v, want %+v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+// writeTree creates the files, with their content, below a temporary directory
+func writeTree(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		fullPath := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func Test_ignoreMatcher_ignored(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		".ge_ignore":            "*.log\nbuild/\n/config.env\n!keep.log\nlogs/\n!logs/keep.txt\ndocs/**/*.md\n",
+		".gitignore":            "*.tmp\n",
+		"src/.ge_ignore":        "fixtures/\n!debug.log\n/local.py\n",
+		"src/app/.ge_ignore":    "*.py\n",
+		"src/app/main.go":       "",
+		"src/app/settings.py":   "",
+		"src/local.py":          "",
+		"src/app/local.py":      "",
+		"src/fixtures/keys.pem": "",
+		"src/debug.log":         "",
+		"build/out":             "",
+		"pkg/build":             "",
+		"config.env":            "",
+		"src/config.env":        "",
+		"keep.log":              "",
+		"app.log":               "",
+		"logs/keep.txt":         "",
+		"docs/a/b/readme.md":    "",
+		"docs/readme.md":        "",
+		"notes.tmp":             "",
+	})
+	ignoreFile := filepath.Join(root, "global_ignore")
+	if err := os.WriteFile(ignoreFile, []byte("**/npm-shrinkwrap.json\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		gitignore bool
+		want      bool
+	}{
+		{name: "Name pattern at any depth", path: "src/app/server.log", want: true},
+		{name: "Negated name", path: "keep.log"},
+		{name: "Directory only pattern", path: "build/out", want: true},
+		{name: "Directory only pattern skips files", path: "pkg/build"},
+		{name: "Anchored pattern", path: "config.env", want: true},
+		{name: "Anchored pattern only at its root", path: "src/config.env"},
+		{name: "No re-including inside an ignored directory", path: "logs/keep.txt", want: true},
+		{name: "Double star", path: "docs/a/b/readme.md", want: true},
+		{name: "Double star matches no directory", path: "docs/readme.md", want: true},
+		{name: "Nested ignore file", path: "src/fixtures/keys.pem", want: true},
+		{name: "Nested negation overrides the root", path: "src/debug.log"},
+		{name: "Nested anchored pattern", path: "src/local.py", want: true},
+		{name: "Nested anchored pattern only at its directory", path: "src/app/local.py", want: true},
+		{name: "Deepest ignore file", path: "src/app/settings.py", want: true},
+		{name: "Not ignored", path: "src/app/main.go"},
+		{name: "Ignore file pattern", path: "web/npm-shrinkwrap.json", want: true},
+		{name: "Git directory", path: ".git/config", want: true},
+		{name: "Gitignore only when asked for", path: "notes.tmp"},
+		{name: "Gitignore", path: "notes.tmp", gitignore: true, want: true},
+		{name: "Upload path", path: "/src/fixtures/keys.pem", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ignores := newIgnoreMatcher(root, ignoreFile, tt.gitignore, false)
+			name := tt.path
+			if !path.IsAbs(name) {
+				name = filepath.Join(root, filepath.FromSlash(name))
+			}
+			if got := ignores.ignored(name, false); got != tt.want {
+				t.Errorf("ignored(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFiles_ignoreFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		".ge_ignore":         "vendor/\n",
+		"main.go":            "x",
+		"vendor/lib/lib.go":  "x",
+		"web/.ge_ignore":     "*.min.js\n",
+		"web/app.js":         "x",
+		"web/app.min.js":     "x",
+		"web/.gitignore":     "dist/\n",
+		"web/dist/bundle.js": "x",
+	})
+	tests := []struct {
+		name      string
+		gitignore bool
+		want      []string
+	}{
+		{name: "Nested ge_ignore", want: []string{".ge_ignore", "main.go", "web/.ge_ignore", "web/.gitignore", "web/app.js", "web/dist/bundle.js"}},
+		{name: "With gitignore", gitignore: true, want: []string{".ge_ignore", "main.go", "web/.ge_ignore", "web/.gitignore", "web/app.js"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileContext, err := GetFiles(root, "", tt.gitignore, false, 1000000)
+			if err != nil {
+				t.Fatalf("GetFiles() error = %v", err)
+			}
+			var got []string
+			for _, f := range fileContext.Files {
+				rel, _ := filepath.Rel(root, f.Path)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,10 +16,44 @@
 
 package file
 
-import "github.com/americanexpress/earlybird/v4/pkg/scan"
+import (
+	"sync/atomic"
+
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+)
 
 //Context is the file system context used for the scan process
 type Context struct {
 	Files                                                     []scan.File
 	CompressPaths, ConvertPaths, IgnorePatterns, SkippedFiles []string
+	//Suppressions collects the hits left out by ignore directives, when the scan was given it with scan.WithSuppressions
+	Suppressions *scan.SuppressionLog
+	//Unread collects the files the scan couldn't read, when the scan was given it with scan.WithSkipLog
+	Unread *scan.SkipLog
+	//StreamedFiles counts the files handed to the scan while it runs, when they aren't listed in Files up front
+	StreamedFiles *atomic.Int64
+}
+
+// ignoreRule is a pattern of a .ge_ignore or .gitignore file
+type ignoreRule struct {
+	pattern string //As written in the ignore file
+	glob    string
+	dir     string //Directory of the ignore file relative to the scan root, empty at the root
+	negate  bool
+	dirOnly bool
+	//anchored patterns match the path from the directory of the ignore file, the others match the name at any depth
+	anchored bool
+}
+
+// ignoreMatcher decides which paths below the scan root are ignored, with the semantics of .gitignore files
+type ignoreMatcher struct {
+	root      string
+	fileNames []string //The ignore files read in every directory
+	//global are the built in patterns and the ones of the -ignorefile, relative to the root
+	global   []ignoreRule
+	dirs     map[string][]ignoreRule //Rules of the ignore files in each directory
+	combined map[string][]ignoreRule //Rules applying to the entries of each directory
+	//nested is false when the paths aren't on disk, only the ignore files of the root apply then
+	nested  bool
+	verbose bool
 }
@@ -17,15 +17,19 @@
 package git
 
 import (
-	"fmt"
 	"io"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/americanexpress/earlybird/v4/pkg/scan"
 )
 
-//ParseGitLog parses the git log into the earlybird file format
-func ParseGitLog(r io.Reader) (fileList []scan.File, err error) {
+// hunkHeaderPattern captures the old and new start lines of a unified diff hunk, e.g. `@@ -1,3 +1,4 @@`
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)?\s*\+(\d+)(?:,\d+)?\s*@@`)
+
+// ParseGitLog parses the git log into the earlybird file format, keeping only the lines each diff adds unless scanRemoved is set
+func ParseGitLog(r io.Reader, scanRemoved bool) (fileList []scan.File, err error) {
 	diff := Diff{}
 	err = splitDiffs(r, &diff)
 	if err != nil {
@@ -33,24 +37,60 @@ func ParseGitLog(r io.Reader) (fileList []scan.File, err error) {
 	}
 
 	for _, d := range diff.Items {
-		//Build file here
+		//Build file here, the commit is kept on the file and lines rather than in the path
 		curFile := scan.File{
-			Name: "buffer",
-			Path: fmt.Sprintf("%s:%s", d.commit, d.fPath),
+			Name:   d.fPath,
+			Path:   "buffer",
+			Commit: d.commit,
 		}
 		//Append lines
-		var line scan.Line
-		lineNum := 1
-		for _, lineText := range strings.Split(strings.TrimSuffix(d.raw, "\n"), "\n") {
-			line.LineNum = lineNum
-			line.LineValue = lineText
-			line.FilePath = curFile.Path
-			lineNum++
-			curFile.Lines = append(curFile.Lines, line)
+		curFile.Lines = diffLines(d.raw, scanRemoved)
+		for i := range curFile.Lines {
+			curFile.Lines[i].FilePath = curFile.Path
+			curFile.Lines[i].FileName = curFile.Name
+			curFile.Lines[i].Commit = curFile.Commit
 		}
 		//Append commit file
-		fileList = append(fileList, curFile)
+		if len(curFile.Lines) > 0 {
+			fileList = append(fileList, curFile)
+		}
 	}
 
 	return fileList, nil
 }
+
+// diffLines walks the hunks of a unified diff and returns the added lines numbered as in the new file.
+// Removed lines, numbered as in the old file, are only returned when scanRemoved is set.
+func diffLines(raw string, scanRemoved bool) (lines []scan.Line) {
+	var oldLine, newLine int
+	inHunk := false
+	for _, lineText := range strings.Split(strings.TrimSuffix(raw, "\n"), "\n") {
+		if header := hunkHeaderPattern.FindStringSubmatch(lineText); header != nil {
+			oldLine, _ = strconv.Atoi(header[1])
+			newLine, _ = strconv.Atoi(header[2])
+			inHunk = true
+			continue
+		}
+		// Commit message, `diff --git`, `index` and `---`/`+++` lines come before the first hunk
+		if !inHunk {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(lineText, "+"):
+			lines = append(lines, scan.Line{LineNum: newLine, LineValue: lineText[1:]})
+			newLine++
+		case strings.HasPrefix(lineText, "-"):
+			if scanRemoved {
+				lines = append(lines, scan.Line{LineNum: oldLine, LineValue: lineText[1:], Removed: true})
+			}
+			oldLine++
+		case strings.HasPrefix(lineText, "\\"):
+			// "\ No newline at end of file" isn't part of either file
+		default:
+			oldLine++
+			newLine++
+		}
+	}
+	return lines
+}
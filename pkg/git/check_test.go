@@ -20,6 +20,8 @@ import (
 	"io"
 	"strings"
 	"testing"
+
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
 )
 
 func TestParseGitLog(t *testing.T) {
@@ -59,7 +61,7 @@ func TestParseGitLog(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := ParseGitLog(tt.args.r)
+			_, err := ParseGitLog(tt.args.r, false)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ParseGitLog() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -67,3 +69,94 @@ func TestParseGitLog(t *testing.T) {
 		})
 	}
 }
+
+func TestParseGitLog_commit(t *testing.T) {
+	gitReader := strings.NewReader(`commit 719709695ab1041c8cde51b721cdc4e63cbac389 (HEAD -> example, origin/example)
+Author: Foo Bar <foo@bar.com>
+Date:   Fri Jan 17 11:34:35 2020 -0700
+
+    feature(test): Important GIT diff test
+
+diff --git a/sample.go b/sample.go
+index 3c3108d..f53837c 100644
+--- a/sample.go
++++ b/sample.go
+@@ -1,3 +1,3 @@ FIRST LINE
+-       DELETED THIS LINE
++       ADDED THIS LINE
+`)
+	files, err := ParseGitLog(gitReader, false)
+	if err != nil {
+		t.Fatalf("ParseGitLog() error = %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("ParseGitLog() returned %d files, want 1", len(files))
+	}
+
+	got := files[0]
+	want := scan.Commit{
+		Hash:   "719709695ab1041c8cde51b721cdc4e63cbac389",
+		Author: "Foo Bar",
+		Email:  "foo@bar.com",
+		Date:   "2020-01-17T18:34:35Z",
+	}
+	if got.Name != "sample.go" || got.Commit == nil || *got.Commit != want {
+		t.Errorf("ParseGitLog() = %s %+v, want sample.go %+v", got.Name, got.Commit, want)
+	}
+	for _, line := range got.Lines {
+		if line.Commit != got.Commit || line.FileName != "sample.go" {
+			t.Errorf("ParseGitLog() line %d = %+v, want commit and file name of sample.go", line.LineNum, line)
+		}
+	}
+}
+
+func Test_diffLines(t *testing.T) {
+	raw := `diff --git a/sample.go b/sample.go
+--- a/sample.go
++++ b/sample.go
+@@ -10,4 +12,4 @@ func main() {
+ 	host := "localhost"
+-	password := "OldSecret1673"
++	password := os.Getenv("PASSWORD")
+ 	port := 8080
+@@ -40 +42,2 @@
+ }
++// done
+\ No newline at end of file
+`
+	tests := []struct {
+		name        string
+		scanRemoved bool
+		want        []scan.Line
+	}{
+		{
+			name: "Only added lines, numbered as in the new file",
+			want: []scan.Line{
+				{LineNum: 13, LineValue: `	password := os.Getenv("PASSWORD")`},
+				{LineNum: 43, LineValue: "// done"},
+			},
+		},
+		{
+			name:        "Removed lines, numbered as in the old file",
+			scanRemoved: true,
+			want: []scan.Line{
+				{LineNum: 11, LineValue: `	password := "OldSecret1673"`, Removed: true},
+				{LineNum: 13, LineValue: `	password := os.Getenv("PASSWORD")`},
+				{LineNum: 43, LineValue: "// done"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffLines(raw, tt.scanRemoved)
+			if len(got) != len(tt.want) {
+				t.Fatalf("diffLines() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("diffLines() line %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
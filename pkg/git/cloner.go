@@ -23,7 +23,9 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/americanexpress/earlybird/v4/pkg/metrics"
 	"github.com/americanexpress/earlybird/v4/pkg/utils"
 
 	"github.com/google/go-github/github"
@@ -78,6 +80,15 @@ func ReposPerProject(projectURL, username, password string) (scanRepos []string)
 
 //CloneGitRepos Clones a Git repo into a random temporary folder
 func CloneGitRepos(repoURLs []string, username, password string, branch string, json bool) (tmpDir string, err error) {
+	return cloneGitRepos(repoURLs, username, password, branch, json, 1)
+}
+
+//CloneGitReposWithHistory Clones a Git repo with all of its commits into a random temporary folder
+func CloneGitReposWithHistory(repoURLs []string, username, password string, branch string, json bool) (tmpDir string, err error) {
+	return cloneGitRepos(repoURLs, username, password, branch, json, 0)
+}
+
+func cloneGitRepos(repoURLs []string, username, password string, branch string, json bool, depth int) (tmpDir string, err error) {
 	tmpDir, err = os.MkdirTemp("", "ebgit")
 	if err != nil {
 		return "", err
@@ -90,7 +101,7 @@ func CloneGitRepos(repoURLs []string, username, password string, branch string,
 	for _, repo := range repoURLs {
 		options := git.CloneOptions{
 			URL:   repo,
-			Depth: 1,
+			Depth: depth,
 		}
 
 		if username != "" {
@@ -116,7 +127,9 @@ func CloneGitRepos(repoURLs []string, username, password string, branch string,
 
 		//Clone repo into random temporary path
 		log.Println("Cloned into:", scanDir)
+		start := time.Now()
 		_, err = git.PlainClone(scanDir, false, &options)
+		metrics.CloneDuration.Observe(time.Since(start).Seconds())
 		if err != nil {
 			return tmpDir, err
 		}
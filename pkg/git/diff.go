@@ -19,16 +19,22 @@ package git
 import (
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
 )
 
 const (
-	pathSep = " b/"
+	pathSep       = " b/"
+	authorPrefix  = "Author:"
+	datePrefix    = "Date:"
+	gitDateLayout = "Mon Jan 2 15:04:05 2006 -0700"
 )
 
 // GetHashKey returns the hash key identifier for the diff
 func (d *DiffItem) GetHashKey() string {
-	if d.commit != "" {
-		return fmt.Sprintf("%s:%s", d.commit, d.fPath)
+	if d.commit != nil && d.commit.Hash != "" {
+		return fmt.Sprintf("%s:%s", d.commit.Hash, d.fPath)
 	}
 	return d.fPath
 }
@@ -37,22 +43,24 @@ func (d *DiffItem) GetHashKey() string {
 type Diff struct {
 	Items     []DiffItem
 	Error     error
-	commitTmp string
+	commitTmp *scan.Commit
 }
 
 // Push a diff on to the list
 func (d *Diff) Push(s string) {
 
-	var commitHeader, commit string
+	var commitHeader string
+	var commit *scan.Commit
 
 	if beginsWithHash(s) {
 		commitHeader, s = split(s, "\n")
-		commit = extractHash(commitHeader)
+		commit = &scan.Commit{Hash: extractHash(commitHeader)}
+		commit.Author, commit.Email, commit.Date = extractAuthor(s)
 		d.commitTmp = commit
 	}
 	// add commit to diffs within each diff which do not
 	// have the commit on the line directly above them
-	if d.commitTmp != "" && commit == "" {
+	if d.commitTmp != nil && commit == nil {
 		commit = d.commitTmp
 	}
 
@@ -96,6 +104,29 @@ func extractFilePath(in string) (string, error) {
 	return "", fmt.Errorf("Not valid diff content:\n%s", in)
 }
 
+// extractAuthor reads the author name, email and date from the commit header lines preceding the diff
+func extractAuthor(in string) (author, email, date string) {
+	for _, line := range strings.Split(in, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, diffSep):
+			return author, email, date
+		case strings.HasPrefix(line, authorPrefix):
+			author = strings.TrimSpace(strings.TrimPrefix(line, authorPrefix))
+			if start, end := strings.LastIndex(author, "<"), strings.LastIndex(author, ">"); start >= 0 && end > start {
+				email = author[start+1 : end]
+				author = strings.TrimSpace(author[:start])
+			}
+		case strings.HasPrefix(line, datePrefix):
+			date = strings.TrimSpace(strings.TrimPrefix(line, datePrefix))
+			if t, err := time.Parse(gitDateLayout, date); err == nil {
+				date = t.UTC().Format(time.RFC3339)
+			}
+		}
+	}
+	return author, email, date
+}
+
 func extractHash(in string) string {
 	if len(strings.Fields(in)) >= 2 {
 		return strings.Fields(in)[1]
@@ -0,0 +1,273 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	fdiff "gopkg.in/src-d/go-git.v4/plumbing/format/diff"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const (
+	revRangeSep = ".."
+)
+
+// WalkHistory walks the commits of the repository at repoPath and hands the lines added by each commit to send as
+// Earlybird files, one call per commit. When repoPath holds several repositories, like the clone of a git project,
+// each of them is walked and their file names start with the directory of the repository.
+// Lines removed by each commit are included as well when opts.ScanRemoved is set.
+// Merge commits are skipped since their changes are already reported on the commits being merged.
+// The walk stops at the first error send returns.
+func WalkHistory(repoPath string, opts HistoryOptions, send func(files []scan.File) error) error {
+	since, err := parseSince(opts.Since)
+	if err != nil {
+		return err
+	}
+	repos, err := historyRepos(repoPath)
+	if err != nil {
+		return err
+	}
+	for _, repo := range repos {
+		prefix := ""
+		if repo != repoPath {
+			prefix = filepath.Base(repo) + "/"
+		}
+		if err = walkRepoHistory(repo, prefix, since, opts, send); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// historyRepos returns the repositories in dir, the directory itself unless it only holds repositories in its
+// subdirectories
+func historyRepos(dir string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return []string{dir}, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var repos []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, entry.Name(), ".git")); err == nil {
+			repos = append(repos, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if len(repos) == 0 {
+		// A directory inside a repository walks the repository around it
+		return []string{dir}, nil
+	}
+	return repos, nil
+}
+
+// walkRepoHistory walks the commits of a single repository, see WalkHistory
+func walkRepoHistory(repoPath, prefix string, since time.Time, opts HistoryOptions, send func(files []scan.File) error) error {
+	repo, err := git.PlainOpenWithOptions(repoPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return fmt.Errorf("opening git repository %s: %v", repoPath, err)
+	}
+
+	from, to := splitRevRange(opts.RevRange)
+	toHash, err := repo.ResolveRevision(plumbing.Revision(to))
+	if err != nil {
+		return fmt.Errorf("resolving revision %s in %s: %v", to, repoPath, err)
+	}
+
+	// Commits reachable from the start of the range were already scanned, the same way `git log from..to` leaves them out
+	excluded := make(map[plumbing.Hash]bool)
+	if from != "" {
+		fromHash, err := repo.ResolveRevision(plumbing.Revision(from))
+		if err != nil {
+			return fmt.Errorf("resolving revision %s in %s: %v", from, repoPath, err)
+		}
+		fromIter, err := repo.Log(&git.LogOptions{From: *fromHash})
+		if err != nil {
+			return err
+		}
+		err = fromIter.ForEach(func(c *object.Commit) error {
+			excluded[c.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	commitIter, err := repo.Log(&git.LogOptions{From: *toHash, Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return err
+	}
+
+	return commitIter.ForEach(func(c *object.Commit) error {
+		if excluded[c.Hash] || c.NumParents() > 1 || (!since.IsZero() && c.Committer.When.Before(since)) {
+			return nil
+		}
+
+		patch, err := commitPatch(c)
+		if err != nil {
+			return fmt.Errorf("building patch for commit %s: %v", c.Hash, err)
+		}
+
+		commit := &scan.Commit{
+			Hash:   c.Hash.String(),
+			Author: c.Author.Name,
+			Email:  c.Author.Email,
+			Date:   c.Author.When.UTC().Format(time.RFC3339),
+		}
+		var files []scan.File
+		for _, filePatch := range patch.FilePatches() {
+			if curFile, ok := patchLines(filePatch, commit, prefix, opts.ScanRemoved); ok {
+				files = append(files, curFile)
+			}
+		}
+		if len(files) == 0 {
+			return nil
+		}
+		return send(files)
+	})
+}
+
+// commitPatch diffs the commit against its parent, or against an empty tree for the root commit
+func commitPatch(c *object.Commit) (*object.Patch, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var parentTree *object.Tree
+	if c.NumParents() == 1 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		parentTree, err = parent.Tree()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, err
+	}
+	return changes.Patch()
+}
+
+// patchLines builds a file from the lines a patch adds, numbered as they appear in the new version of the file.
+// Removed lines, numbered as in the old version of the file, are only included when scanRemoved is set.
+// The file is named by its path in the repository, after the prefix.
+func patchLines(filePatch fdiff.FilePatch, commit *scan.Commit, prefix string, scanRemoved bool) (curFile scan.File, ok bool) {
+	from, to := filePatch.Files()
+	// Binary files don't have any lines to scan, deleted files only have removed lines
+	if filePatch.IsBinary() || (to == nil && !scanRemoved) {
+		return curFile, false
+	}
+
+	curFile = scan.File{
+		Path:   "buffer",
+		Commit: commit,
+	}
+	if to != nil {
+		curFile.Name = prefix + to.Path()
+	} else {
+		curFile.Name = prefix + from.Path()
+	}
+
+	oldLine, newLine := 0, 0
+	for _, chunk := range filePatch.Chunks() {
+		switch chunk.Type() {
+		case fdiff.Equal:
+			oldLine += countLines(chunk.Content())
+			newLine += countLines(chunk.Content())
+		case fdiff.Delete:
+			for _, lineText := range strings.Split(strings.TrimSuffix(chunk.Content(), "\n"), "\n") {
+				oldLine++
+				if scanRemoved {
+					curFile.Lines = append(curFile.Lines, scan.Line{
+						LineNum:   oldLine,
+						LineValue: lineText,
+						FilePath:  curFile.Path,
+						FileName:  curFile.Name,
+						Commit:    commit,
+						Removed:   true,
+					})
+				}
+			}
+		case fdiff.Add:
+			for _, lineText := range strings.Split(strings.TrimSuffix(chunk.Content(), "\n"), "\n") {
+				newLine++
+				curFile.Lines = append(curFile.Lines, scan.Line{
+					LineNum:   newLine,
+					LineValue: lineText,
+					FilePath:  curFile.Path,
+					FileName:  curFile.Name,
+					Commit:    commit,
+				})
+			}
+		}
+	}
+	return curFile, len(curFile.Lines) > 0
+}
+
+// countLines counts the lines in a chunk, including a final line without a trailing newline
+func countLines(content string) int {
+	if content == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+}
+
+// splitRevRange splits `from..to` into its two revisions, `to` defaults to HEAD
+func splitRevRange(revRange string) (from, to string) {
+	from, to = "", revRange
+	if i := strings.Index(revRange, revRangeSep); i >= 0 {
+		from, to = revRange[:i], revRange[i+len(revRangeSep):]
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+	return from, to
+}
+
+// parseSince accepts either a date (2006-01-02) or an RFC3339 timestamp
+func parseSince(since string) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", since); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return t, fmt.Errorf("invalid since date %s, use YYYY-MM-DD or RFC3339", since)
+	}
+	return t, nil
+}
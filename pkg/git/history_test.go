@@ -0,0 +1,194 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// commitFile writes the content to the file in the repository and commits it
+func commitFile(t *testing.T, repo *git.Repository, dir, name, content string, when time.Time) plumbing.Hash {
+	t.Helper()
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wt.Add(name); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "Foo Bar", Email: "foo@bar.com", When: when}
+	hash, err := wt.Commit("update "+name, &git.CommitOptions{Author: signature, Committer: signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestWalkHistory(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2020, 1, 17, 11, 34, 35, 0, time.UTC)
+	first := commitFile(t, repo, dir, "config.py", "host = 'localhost'\npassword = 'SecretValue1673'\n", start)
+	second := commitFile(t, repo, dir, "config.py", "host = 'localhost'\n", start.AddDate(0, 0, 1))
+	third := commitFile(t, repo, dir, "config.py", "# header\nhost = 'localhost'\nuser = 'admin'\n", start.AddDate(0, 0, 2))
+
+	tests := []struct {
+		name      string
+		opts      HistoryOptions
+		wantFiles map[string][]int
+	}{
+		{
+			name: "Scan every commit, deletions add no lines",
+			opts: HistoryOptions{},
+			wantFiles: map[string][]int{
+				first.String(): {1, 2},
+				third.String(): {1, 3},
+			},
+		},
+		{
+			name: "Scan removed lines too",
+			opts: HistoryOptions{ScanRemoved: true},
+			wantFiles: map[string][]int{
+				first.String():  {1, 2},
+				second.String(): {2},
+				third.String():  {1, 3},
+			},
+		},
+		{
+			name: "Scan commits since a date",
+			opts: HistoryOptions{Since: "2020-01-19"},
+			wantFiles: map[string][]int{
+				third.String(): {1, 3},
+			},
+		},
+		{
+			name:      "Scan a revision range",
+			opts:      HistoryOptions{RevRange: first.String() + ".." + second.String()},
+			wantFiles: map[string][]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var batches [][]scan.File
+			err := WalkHistory(dir, tt.opts, func(files []scan.File) error {
+				batches = append(batches, files)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("WalkHistory() error = %v", err)
+			}
+			if len(batches) != len(tt.wantFiles) {
+				t.Fatalf("WalkHistory() sent %d batches, want one per commit with changes, %d", len(batches), len(tt.wantFiles))
+			}
+			for _, files := range batches {
+				if len(files) != 1 {
+					t.Errorf("WalkHistory() sent a batch of %d files, want the one file of the commit", len(files))
+					continue
+				}
+				f := files[0]
+				wantLines, ok := tt.wantFiles[f.Commit.Hash]
+				if !ok {
+					t.Errorf("WalkHistory() sent unexpected commit %s", f.Commit.Hash)
+					continue
+				}
+				if f.Name != "config.py" || f.Commit.Author != "Foo Bar" {
+					t.Errorf("WalkHistory() file = %s by %s, want config.py by Foo Bar", f.Name, f.Commit.Author)
+				}
+				var gotLines []int
+				for _, line := range f.Lines {
+					gotLines = append(gotLines, line.LineNum)
+				}
+				if len(gotLines) != len(wantLines) || gotLines[0] != wantLines[0] || gotLines[len(gotLines)-1] != wantLines[len(wantLines)-1] {
+					t.Errorf("WalkHistory() commit %s added lines %v, want %v", f.Commit.Hash, gotLines, wantLines)
+				}
+			}
+		})
+	}
+}
+
+func Test_splitRevRange(t *testing.T) {
+	tests := []struct {
+		revRange string
+		wantFrom string
+		wantTo   string
+	}{
+		{revRange: "", wantFrom: "", wantTo: "HEAD"},
+		{revRange: "main", wantFrom: "", wantTo: "main"},
+		{revRange: "v1.0..main", wantFrom: "v1.0", wantTo: "main"},
+		{revRange: "v1.0..", wantFrom: "v1.0", wantTo: "HEAD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.revRange, func(t *testing.T) {
+			gotFrom, gotTo := splitRevRange(tt.revRange)
+			if gotFrom != tt.wantFrom || gotTo != tt.wantTo {
+				t.Errorf("splitRevRange() = %v, %v, want %v, %v", gotFrom, gotTo, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestWalkHistory_project(t *testing.T) {
+	// A project clone holds one repository per directory
+	dir := t.TempDir()
+	start := time.Date(2020, 1, 17, 11, 34, 35, 0, time.UTC)
+	for _, name := range []string{"api", "web"} {
+		repoDir := filepath.Join(dir, name)
+		repo, err := git.PlainInit(repoDir, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commitFile(t, repo, repoDir, "config.py", "password = 'SecretValue1673'\n", start)
+		commitFile(t, repo, repoDir, "app.py", "user = 'admin'\n", start.AddDate(0, 0, 1))
+	}
+
+	var commits int
+	names := make(map[string]bool)
+	err := WalkHistory(dir, HistoryOptions{}, func(files []scan.File) error {
+		commits++
+		for _, f := range files {
+			names[f.Name] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkHistory() error = %v", err)
+	}
+	if commits != 4 {
+		t.Errorf("WalkHistory() sent %d commits, want 4", commits)
+	}
+	for _, want := range []string{"api/config.py", "api/app.py", "web/config.py", "web/app.py"} {
+		if !names[want] {
+			t.Errorf("WalkHistory() sent files %v, want %s", names, want)
+		}
+	}
+}
@@ -16,7 +16,11 @@
 
 package git
 
-import "github.com/dghubble/sling"
+import (
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+
+	"github.com/dghubble/sling"
+)
 
 type errorResponse struct {
 	Errors []apiError
@@ -90,9 +94,19 @@ type List interface {
 	Push(string)
 }
 
+// HistoryOptions limits the commits walked by WalkHistory
+type HistoryOptions struct {
+	// Since skips commits committed before this date (YYYY-MM-DD or RFC3339)
+	Since string
+	// RevRange is a single revision or a `from..to` range, defaults to HEAD
+	RevRange string
+	// ScanRemoved also returns the lines each commit deleted
+	ScanRemoved bool
+}
+
 // DiffItem is a diff struct for an inidividual file
 type DiffItem struct {
 	raw    string
 	fPath  string
-	commit string
+	commit *scan.Commit
 }
@@ -0,0 +1,210 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/utils"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/client"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+var (
+	// Address ranges which aren't private by net/netip but still don't belong to public hosts
+	nonPublicPrefixes = []netip.Prefix{
+		netip.MustParsePrefix("0.0.0.0/8"),
+		netip.MustParsePrefix("100.64.0.0/10"),
+		netip.MustParsePrefix("192.0.0.0/24"),
+		netip.MustParsePrefix("198.18.0.0/15"),
+	}
+	errPrivateAddress = errors.New("private and link-local addresses are not allowed")
+)
+
+const maxRedirects = 10
+
+// CheckCloneURL validates a repository URL from an API request against the policy, including the addresses its host
+// resolves to, and returns the URL to clone. URLs without a scheme are cloned over https.
+func CheckCloneURL(ctx context.Context, rawURL string, policy cfgreader.GitURLPolicy) (string, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid repository URL: %v", err)
+	}
+	// Credentials belong in the server environment, and a user name would make the server prompt for a password
+	if u.User != nil {
+		return "", errors.New("repository URL must not contain credentials")
+	}
+	if err = checkURL(u, policy); err != nil {
+		return "", err
+	}
+	if policy.AllowPrivateNetworks {
+		return u.String(), nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %v", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !isPublicAddr(addr) {
+			return "", fmt.Errorf("%s resolves to %s: %w", u.Hostname(), addr, errPrivateAddress)
+		}
+	}
+	return u.String(), nil
+}
+
+// InstallURLPolicy makes every http and https clone follow only the redirects the policy allows and, unless private
+// networks are allowed, connect only to public addresses. The addresses are checked again when connecting because the
+// host may resolve differently than it did for CheckCloneURL. Connections to the proxy from the environment are exempt.
+func InstallURLPolicy(policy cfgreader.GitURLPolicy) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	publicDialer := &net.Dialer{Timeout: dialer.Timeout, KeepAlive: dialer.KeepAlive}
+	publicDialer.Control = func(network, address string, _ syscall.RawConn) error {
+		addrPort, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return err
+		}
+		if !isPublicAddr(addrPort.Addr()) {
+			return fmt.Errorf("%s: %w", address, errPrivateAddress)
+		}
+		return nil
+	}
+	proxies := proxyAddresses()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if policy.AllowPrivateNetworks || proxies[address] {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return publicDialer.DialContext(ctx, network, address)
+	}
+	httpClient := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.User != nil {
+				return errors.New("redirect URL must not contain credentials")
+			}
+			return checkURL(req.URL, policy)
+		},
+	}
+	client.InstallProtocol("https", githttp.NewClient(httpClient))
+	client.InstallProtocol("http", githttp.NewClient(httpClient))
+}
+
+// proxyAddresses returns the host:port of the http and https proxies set in the environment
+func proxyAddresses() map[string]bool {
+	proxies := make(map[string]bool)
+	for scheme, port := range map[string]string{"http": "80", "https": "443"} {
+		proxyURL, err := http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: scheme, Host: "example.com"}})
+		if err != nil || proxyURL == nil {
+			continue
+		}
+		if proxyURL.Port() != "" {
+			port = proxyURL.Port()
+		}
+		proxies[net.JoinHostPort(proxyURL.Hostname(), port)] = true
+	}
+	return proxies
+}
+
+// checkURL checks the scheme, host and organization of a repository URL against the policy
+func checkURL(u *url.URL, policy cfgreader.GitURLPolicy) error {
+	allowedSchemes := policy.AllowedSchemes
+	if len(allowedSchemes) == 0 {
+		allowedSchemes = []string{"https"}
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if !utils.Contains(allowedSchemes, scheme) {
+		return fmt.Errorf("scheme %s is not allowed, use one of %s", scheme, utils.GetDisplayList(allowedSchemes))
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return errors.New("repository URL has no host")
+	}
+	if matchesHost(policy.DeniedHosts, host) {
+		return fmt.Errorf("host %s is denied", host)
+	}
+	if len(policy.AllowedHosts) > 0 && !matchesHost(policy.AllowedHosts, host) {
+		return fmt.Errorf("host %s is not allowed", host)
+	}
+
+	organization := strings.ToLower(strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)[0])
+	if matchesOrganization(policy.DeniedOrganizations, host, organization) {
+		return fmt.Errorf("organization %s is denied", organization)
+	}
+	if len(policy.AllowedOrganizations) > 0 && !matchesOrganization(policy.AllowedOrganizations, host, organization) {
+		return fmt.Errorf("organization %s is not allowed", organization)
+	}
+	return nil
+}
+
+// matchesHost reports whether the host is in the list, `*.example.com` entries match every subdomain of example.com
+func matchesHost(hosts []string, host string) bool {
+	for _, entry := range hosts {
+		entry = strings.ToLower(entry)
+		if entry == host || (strings.HasPrefix(entry, "*.") && strings.HasSuffix(host, entry[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesOrganization reports whether the organization is in the list, either on its own or as host/organization
+func matchesOrganization(organizations []string, host, organization string) bool {
+	if organization == "" {
+		return false
+	}
+	for _, entry := range organizations {
+		entry = strings.ToLower(entry)
+		if entry == organization || entry == host+"/"+organization {
+			return true
+		}
+	}
+	return false
+}
+
+// isPublicAddr reports whether the address can belong to a public host
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
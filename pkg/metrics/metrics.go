@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package metrics
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	//Registry holds the Earlybird metrics below
+	Registry = prometheus.NewRegistry()
+
+	//ScansStarted counts the scans handed to the engine
+	ScansStarted = promauto.With(Registry).NewCounter(prometheus.CounterOpts{Name: "earlybird_scans_started_total", Help: "Scans started."})
+	//ScansFinished counts the finished scans, result is complete or truncated when the scan was cancelled or timed out
+	ScansFinished = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{Name: "earlybird_scans_finished_total", Help: "Scans finished by result."}, []string{"result"})
+	//ScanDuration observes how long the scans took
+	ScanDuration = promauto.With(Registry).NewHistogram(prometheus.HistogramOpts{Name: "earlybird_scan_duration_seconds", Help: "Duration of the scans in seconds.", Buckets: durationBuckets})
+	//FilesScanned counts the files whose content was searched
+	FilesScanned = promauto.With(Registry).NewCounter(prometheus.CounterOpts{Name: "earlybird_files_scanned_total", Help: "Files scanned."})
+	//BytesRead counts the bytes of file content searched
+	BytesRead = promauto.With(Registry).NewCounter(prometheus.CounterOpts{Name: "earlybird_bytes_read_total", Help: "Bytes of file content read."})
+	//Hits counts the reported hits
+	Hits = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{Name: "earlybird_hits_total", Help: "Hits reported by rule code, category and severity."}, []string{"code", "category", "severity"})
+	//RuleRegexSeconds adds up the time spent matching the pattern of each rule, only measured while Enabled
+	RuleRegexSeconds = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{Name: "earlybird_rule_regex_seconds_total", Help: "Time spent matching the pattern of each rule in seconds."}, []string{"code"})
+	//RuleEvaluations counts the lines each rule pattern ran on, only measured while Enabled
+	RuleEvaluations = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{Name: "earlybird_rule_evaluations_total", Help: "Lines the pattern of each rule was matched against."}, []string{"code"})
+	//CloneDuration observes how long git clones took
+	CloneDuration = promauto.With(Registry).NewHistogram(prometheus.HistogramOpts{Name: "earlybird_git_clone_duration_seconds", Help: "Duration of git clones in seconds.", Buckets: durationBuckets})
+	//JobsQueued is the number of scan jobs waiting for a worker
+	JobsQueued = promauto.With(Registry).NewGauge(prometheus.GaugeOpts{Name: "earlybird_scan_jobs_queued", Help: "Scan jobs waiting for a worker."})
+	//JobsRunning is the number of scan jobs being run by a worker
+	JobsRunning = promauto.With(Registry).NewGauge(prometheus.GaugeOpts{Name: "earlybird_scan_jobs_running", Help: "Scan jobs being run by a worker."})
+	//ConfigReloads counts the reloads of the rules and their config files by result, "success" or "failure"
+	ConfigReloads = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{Name: "earlybird_config_reloads_total", Help: "Reloads of the rules, false positives, labels and solutions by result."}, []string{"result"})
+
+	//enabled turns on the measurements that cost time on every line, like the per rule regex timing
+	enabled atomic.Bool
+)
+
+// Enable turns on the measurements which are too expensive to take when nobody reads them
+func Enable() {
+	enabled.Store(true)
+}
+
+// Enabled reports whether the expensive measurements are taken
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Handler serves the metrics of the Registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// WriteFile writes the metrics of the Registry to the file at path in the Prometheus text exposition format
+func WriteFile(path string) error {
+	return prometheus.WriteToTextfile(path, Registry)
+}
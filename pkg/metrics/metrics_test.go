@@ -0,0 +1,65 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	ScansStarted.Inc()
+	Hits.WithLabelValues("3013", "password", "high").Inc()
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Handler() = %d, want 200", w.Code)
+	}
+	for _, want := range []string{
+		"# TYPE earlybird_scans_started_total counter",
+		`earlybird_hits_total{category="password",code="3013",severity="high"} 1`,
+		"# TYPE earlybird_scan_jobs_queued gauge",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Handler() body is missing %q:\n%s", want, w.Body.String())
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	CloneDuration.Observe(3)
+	path := filepath.Join(t.TempDir(), "metrics.prom")
+	if err := WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# TYPE earlybird_git_clone_duration_seconds histogram",
+		`earlybird_git_clone_duration_seconds_bucket{le="5"} 1`,
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("WriteFile() content is missing %q:\n%s", want, content)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
+)
+
+// Fingerprint returns an identifier for the hit which stays the same when the secret moves to a different line.
+// It is built from the rule code, the file path relative to the scanned directory and a digest of the match value.
+func Fingerprint(hit Hit, searchDir string) string {
+	valueDigest := sha1.Sum([]byte(hit.MatchValue))
+
+	digest := sha1.New()
+	digest.Write([]byte(strconv.Itoa(hit.Code) + ":" + fingerprintPath(hit.Filename, searchDir) + ":" + hex.EncodeToString(valueDigest[:])))
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// fingerprintPath strips the scanned directory so the fingerprint does not depend on where the repository is checked out
+func fingerprintPath(filename, searchDir string) string {
+	if searchDir != "" {
+		if rel, err := filepath.Rel(searchDir, filename); err == nil && !strings.HasPrefix(rel, "..") {
+			filename = rel
+		}
+	}
+	return filepath.ToSlash(filename)
+}
+
+// isBaselined checks if the hit was already recorded in the baseline file
+func (e *Engine) isBaselined(hit Hit) bool {
+	return e.BaselineFingerprints[hit.Fingerprint]
+}
+
+// WithBaselineLog returns a context which makes SearchFiles record in log every hit that is reported or fails the scan,
+// including the ones below the display levels
+func WithBaselineLog(ctx context.Context, log *BaselineLog) context.Context {
+	return context.WithValue(ctx, baselineKey{}, log)
+}
+
+// recordBaselineHit adds the hit to the baseline log of the scan, if the caller asked for one
+func recordBaselineHit(ctx context.Context, hit Hit) {
+	log, ok := ctx.Value(baselineKey{}).(*BaselineLog)
+	if !ok || log == nil {
+		return
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.hits = append(log.hits, hit)
+}
+
+// Hits returns the hits recorded so far
+func (l *BaselineLog) Hits() []Hit {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Hit(nil), l.hits...)
+}
+
+// loadBaseline reads the fingerprints from a baseline file written with WriteBaseline
+func loadBaseline(path string) (fingerprints map[string]bool, err error) {
+	var baseline Baseline
+	err = cfgReader.LoadConfig(&baseline, path)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprints = make(map[string]bool, len(baseline.Fingerprints))
+	for _, fingerprint := range baseline.Fingerprints {
+		fingerprints[fingerprint] = true
+	}
+	return fingerprints, nil
+}
+
+// WriteBaseline records the fingerprints of the hits in a baseline file for future scans to suppress
+func WriteBaseline(path, version string, hits []Hit) error {
+	unique := make(map[string]bool)
+	baseline := Baseline{
+		Version:      version,
+		CreatedAt:    time.Now().UTC().Format(time.RFC3339),
+		Fingerprints: make([]string, 0, len(hits)),
+	}
+	for _, hit := range hits {
+		if hit.Fingerprint == "" || unique[hit.Fingerprint] {
+			continue
+		}
+		unique[hit.Fingerprint] = true
+		baseline.Fingerprints = append(baseline.Fingerprints, hit.Fingerprint)
+	}
+	// Keep the file stable between runs so it diffs cleanly in source control
+	sort.Strings(baseline.Fingerprints)
+
+	b, err := json.MarshalIndent(baseline, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0666)
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	hit := Hit{
+		Code:       3001,
+		Line:       1,
+		Filename:   "/home/jdoe/repo/config/app.properties",
+		MatchValue: "SecretValue1673",
+	}
+	moved := hit
+	moved.Line = 42
+	moved.Filename = "/tmp/ebgit123/config/app.properties"
+	changed := hit
+	changed.MatchValue = "OtherSecret2468"
+
+	tests := []struct {
+		name      string
+		a, b      Hit
+		dirA      string
+		dirB      string
+		wantEqual bool
+	}{
+		{
+			name:      "Same finding on a different line in a different checkout",
+			a:         hit,
+			b:         moved,
+			dirA:      "/home/jdoe/repo",
+			dirB:      "/tmp/ebgit123",
+			wantEqual: true,
+		},
+		{
+			name:      "Different secret in the same file",
+			a:         hit,
+			b:         changed,
+			dirA:      "/home/jdoe/repo",
+			dirB:      "/home/jdoe/repo",
+			wantEqual: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fingerprint(tt.a, tt.dirA) == Fingerprint(tt.b, tt.dirB); got != tt.wantEqual {
+				t.Errorf("Fingerprint() equal = %v, want %v", got, tt.wantEqual)
+			}
+		})
+	}
+}
+
+func TestWriteBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	hits := []Hit{
+		{Fingerprint: "b"},
+		{Fingerprint: "a"},
+		{Fingerprint: "b"},
+	}
+	if err := WriteBaseline(path, "Test 1.0", hits); err != nil {
+		t.Fatalf("WriteBaseline() error = %v", err)
+	}
+
+	fingerprints, err := loadBaseline(path)
+	if err != nil {
+		t.Fatalf("loadBaseline() error = %v", err)
+	}
+	if len(fingerprints) != 2 || !fingerprints["a"] || !fingerprints["b"] {
+		t.Errorf("loadBaseline() = %v, want fingerprints a and b", fingerprints)
+	}
+}
+
+func TestSearchFiles_baseline(t *testing.T) {
+	files := []File{
+		{
+			Name: "file.py",
+			Path: "buffer",
+			Lines: []Line{
+				{
+					LineValue: `password = "SecretValue1673"`,
+					LineNum:   1,
+					FileName:  "file.py",
+					FilePath:  "buffer",
+				},
+			},
+		},
+	}
+	scanCfg := cfg
+
+	// Find the hit once to learn its fingerprint
+	hits := make(chan Hit)
+	go SearchFiles(context.Background(), &scanCfg, files, nil, nil, hits)
+	var found []Hit
+	for hit := range hits {
+		found = append(found, hit)
+	}
+	if len(found) == 0 {
+		t.Fatal("SearchFiles() found no hits to baseline")
+	}
+
+	BaselineFingerprints = map[string]bool{found[0].Fingerprint: true}
+	defer func() { BaselineFingerprints = nil }()
+
+	scanCfg = cfg
+	hits = make(chan Hit)
+	go SearchFiles(context.Background(), &scanCfg, files, nil, nil, hits)
+	for hit := range hits {
+		if hit.Fingerprint == found[0].Fingerprint {
+			t.Errorf("SearchFiles() reported baselined hit %v", hit)
+		}
+	}
+	if scanCfg.FailScan && len(found) == 1 {
+		t.Errorf("SearchFiles() failed the scan on a baselined hit")
+	}
+}
+
+func TestSearchFiles_baselineLog(t *testing.T) {
+	files := []File{
+		{
+			Name: "file.py",
+			Path: "buffer",
+			Lines: []Line{
+				{
+					LineValue: `password = "SecretValue1673"`,
+					LineNum:   1,
+					FileName:  "file.py",
+					FilePath:  "buffer",
+				},
+			},
+		},
+	}
+	// The hits fail the scan without being shown
+	scanCfg := cfg
+	scanCfg.ConfidenceDisplayLevel = 0
+	baselineLog := &BaselineLog{}
+	hits := make(chan Hit)
+	go SearchFiles(WithBaselineLog(context.Background(), baselineLog), &scanCfg, files, nil, nil, hits)
+	for hit := range hits {
+		t.Errorf("SearchFiles() reported %v below the display level", hit)
+	}
+	if !scanCfg.FailScan {
+		t.Fatal("SearchFiles() didn't fail the scan")
+	}
+	if len(baselineLog.Hits()) == 0 {
+		t.Errorf("SearchFiles() recorded no baseline hits, want the ones failing the scan")
+	}
+}
@@ -25,4 +25,14 @@ const (
     maskCharacter     string  = "*"
     overlapLength     int     = 25
     infoLevelSeverity string  = "info"
+    removedLineLabel  string  = "removed line"
+    expiredLabel      string  = "expired suppression"
+    expiryLayout      string  = "2006-01-02"
+    ignoreNextLine    string  = "-NEXT-LINE" //Suffixes of the ignore annotations
+    ignoreStart       string  = "-START"
+    ignoreEnd         string  = "-END"
+    ignoreFile        string  = "-FILE"
 )
+
+//SuppressionsFileName is the suppressions file picked up from the root of the scanned directory
+const SuppressionsFileName = ".earlybird-suppressions.yaml"
@@ -684,7 +684,7 @@ func Test_findFalsePositive(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if gotIsFP := findFalsePositive(tt.hit); gotIsFP != tt.wantIsFP {
+			if gotIsFP := findFalsePositive(tt.hit, FalsePositiveRules); gotIsFP != tt.wantIsFP {
 				t.Errorf("findFalsePositive() = %v, want %v", gotIsFP, tt.wantIsFP)
 			}
 		})
@@ -718,7 +718,7 @@ func Test_falsePositiveInclusivity(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if gotIsFP := findFalsePositive(tt.hit); gotIsFP != tt.wantIsFP {
+			if gotIsFP := findFalsePositive(tt.hit, FalsePositiveRules); gotIsFP != tt.wantIsFP {
 				t.Errorf("findFalsePositive() = %v, want %v", gotIsFP, tt.wantIsFP)
 			}
 		})
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"log"
+	"regexp"
+)
+
+// multilineKeys compiles the keys of the multiline labels, which are looked up once per file rather than for every hit
+func (e *Engine) multilineKeys() (keys []labelKey) {
+	seen := make(map[string]bool)
+	for _, configs := range e.Labels {
+		for _, label := range configs.Labels {
+			if !label.Multiline {
+				continue
+			}
+			for _, key := range label.Keys {
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				pattern, err := regexp.Compile("(?i)" + key)
+				if err != nil {
+					log.Printf("Skipping multiline key %q of label %q: %v", key, label.Label, err)
+					continue
+				}
+				keys = append(keys, labelKey{key: key, pattern: pattern})
+			}
+		}
+	}
+	return keys
+}
+
+// newFileIndexer starts the index of a file, which is fed its lines in order
+func newFileIndexer(keys []labelKey, annotations []string) *fileIndexer {
+	return &fileIndexer{keys: keys, found: make(map[string]bool), ignores: ignoreIndexBuilder{annotations: annotations}}
+}
+
+// add indexes the next line of the file
+func (x *fileIndexer) add(line Line) {
+	x.ignores.add(line)
+	for _, key := range x.keys {
+		if !x.found[key.key] && key.pattern.MatchString(line.LineValue) {
+			x.found[key.key] = true
+		}
+	}
+}
+
+// finish returns the index once every line of the file has been added
+func (x *fileIndexer) finish() *fileIndex {
+	return &fileIndex{keys: x.found, ignores: x.ignores.finish(), dupes: make(map[string]bool)}
+}
+
+// newStreamedIndex starts the index of a file which is scanned while its lines are read and added to the indexer.
+// The index is only filled in by read, so the hits wait in it until then, see hold.
+func newStreamedIndex() *fileIndex {
+	return &fileIndex{dupes: make(map[string]bool), streamed: true, pending: 1}
+}
+
+// sent counts a job of the streamed file handed to the workers
+func (f *fileIndex) sent() {
+	f.holdLock.Lock()
+	defer f.holdLock.Unlock()
+	f.pending++
+}
+
+// read fills in the index of the streamed file once the indexer has been given every line, a directive or multiline
+// label key may come after the lines it applies to
+func (f *fileIndex) read(indexer *fileIndexer) {
+	f.holdLock.Lock()
+	defer f.holdLock.Unlock()
+	f.keys = indexer.found
+	f.ignores = indexer.ignores.finish()
+}
+
+// streaming reports whether the hits of the file are held until it's read
+func (f *fileIndex) streaming() bool {
+	return f != nil && f.streamed
+}
+
+// hold takes the hits of a scanned job. The hits of a streamed file are held until its last job, the end of file,
+// is scanned, which gets all of them. The hits of any other file are handed back straight away.
+func (f *fileIndex) hold(hits []Hit) []Hit {
+	if !f.streaming() {
+		return hits
+	}
+	f.holdLock.Lock()
+	defer f.holdLock.Unlock()
+	f.held = append(f.held, hits...)
+	f.pending--
+	if f.pending > 0 {
+		return nil
+	}
+	held := f.held
+	f.held = nil
+	return held
+}
+
+// indexLines builds the index of a file which is already in memory
+func indexLines(keys []labelKey, annotations []string, lines []Line) *fileIndex {
+	indexer := newFileIndexer(keys, annotations)
+	for _, line := range lines {
+		indexer.add(line)
+	}
+	return indexer.finish()
+}
+
+// hasKey reports whether a multiline label key occurs anywhere in the file
+func (f *fileIndex) hasKey(key string) bool {
+	return f != nil && f.keys[key]
+}
+
+// suppressedBy returns the ignore directive of the file that suppresses the hit, if any
+func (f *fileIndex) suppressedBy(hit Hit) (ignoreDirective, bool) {
+	if f == nil {
+		return ignoreDirective{}, false
+	}
+	return f.ignores.suppressedBy(hit)
+}
+
+// unique reports whether the hit is the first of its kind in the file, the file may be scanned by several workers
+func (f *fileIndex) unique(hit Hit) bool {
+	f.dupeLock.Lock()
+	defer f.dupeLock.Unlock()
+	return hitUnique(f.dupes, hit)
+}
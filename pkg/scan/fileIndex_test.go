@@ -0,0 +1,140 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_fileIndexer(t *testing.T) {
+	keys := []string{"hideme", "racf", "[A-Za-z]{2}[0-9]{4}[a-zA-X]{1}"}
+	labels := map[int]LabelConfigs{1: {Labels: []LabelConfig{{Label: "fixture", Keys: keys, Multiline: true}}}}
+	engine := &Engine{Labels: labels}
+	lines := []Line{
+		{LineNum: 1, LineValue: "Nothing to see here"},
+		{LineNum: 2, LineValue: "Not sure what you're looking HIDEME for"},
+		{LineNum: 3, LineValue: "# EARLYBIRD-IGNORE-FILE test fixture"},
+		{LineNum: 4, LineValue: "user = ab1234c"},
+	}
+	file := indexLines(engine.multilineKeys(), annotations, lines)
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "hideme", want: true},
+		{key: "racf", want: false},
+		{key: "[A-Za-z]{2}[0-9]{4}[a-zA-X]{1}", want: true},
+		{key: "not a key of any label", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := file.hasKey(tt.key); got != tt.want {
+				t.Errorf("hasKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+	if _, ok := file.suppressedBy(Hit{Code: 1002, Line: 1}); !ok {
+		t.Error("suppressedBy() did not apply the file directive")
+	}
+	var none *fileIndex
+	if none.hasKey("hideme") {
+		t.Error("hasKey() found a key without an index")
+	}
+	if _, ok := none.suppressedBy(Hit{Code: 1002, Line: 1}); ok {
+		t.Error("suppressedBy() suppressed a hit without an index")
+	}
+}
+
+func TestSearchFiles_multilineLabels(t *testing.T) {
+	secret := `password = "SecretValue1673"`
+	dir := t.TempDir()
+	files := map[string]string{
+		"labelled.py":   strings.Join([]string{secret, "x = 1", "# marker42 further down the file"}, "\n"),
+		"unlabelled.py": strings.Join([]string{secret, "x = 1"}, "\n"),
+	}
+	var scanFiles []File
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		scanFiles = append(scanFiles, File{Name: path, Path: path})
+	}
+
+	engine := globalEngine()
+	engine.Labels = make(map[int]LabelConfigs)
+	for _, rule := range engine.Rules {
+		engine.Labels[rule.Code] = LabelConfigs{Labels: []LabelConfig{{Label: "fixture", Keys: []string{"marker[0-9]+"}, Multiline: true}}}
+	}
+	scanCfg := cfg
+	hits := make(chan Hit)
+	go engine.SearchFiles(context.Background(), &scanCfg, scanFiles, nil, nil, hits)
+	labelled := map[string]bool{}
+	for hit := range hits {
+		if hit.Line != 1 {
+			continue
+		}
+		for _, label := range hit.Labels {
+			if label == "fixture" {
+				labelled[filepath.Base(hit.Filename)] = true
+			}
+		}
+	}
+	if !labelled["labelled.py"] || labelled["unlabelled.py"] {
+		t.Errorf("SearchFiles() labelled hits in %v, want only labelled.py", labelled)
+	}
+}
+
+func TestSearchFiles_streamedFiles(t *testing.T) {
+	secret := `password = "SecretValue1673"`
+	dir := t.TempDir()
+	// The directive for the whole file only comes after the hits it suppresses
+	ignored := filepath.Join(dir, "ignored.py")
+	if err := os.WriteFile(ignored, []byte(strings.Join([]string{secret, secret, "# EARLYBIRD-IGNORE-FILE fixtures"}, "\n")), 0666); err != nil {
+		t.Fatal(err)
+	}
+	reported := filepath.Join(dir, "reported.py")
+	if err := os.WriteFile(reported, []byte(secret), 0666); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.py")
+
+	for _, shard := range []bool{false, true} {
+		scanCfg := cfg
+		scanCfg.AnnotationsToSkipLine = annotations
+		scanCfg.ShardFiles = shard
+		unread := &SkipLog{}
+		hits := make(chan Hit)
+		files := []File{{Name: ignored, Path: ignored}, {Name: missing, Path: missing}, {Name: reported, Path: reported}}
+		go SearchFiles(WithSkipLog(context.Background(), unread), &scanCfg, files, nil, nil, hits)
+		found := map[string]bool{}
+		for hit := range hits {
+			found[filepath.Base(hit.Filename)] = true
+		}
+		if found["ignored.py"] || !found["reported.py"] {
+			t.Errorf("SearchFiles() sharded %v reported hits in %v, want only reported.py", shard, found)
+		}
+		if paths := unread.Paths(); len(paths) != 1 || paths[0] != missing {
+			t.Errorf("SearchFiles() sharded %v skipped %v, want %s", shard, paths, missing)
+		}
+	}
+}
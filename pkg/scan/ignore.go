@@ -0,0 +1,229 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseIgnoreDirective finds an ignore annotation in the line, along with the rule codes it is limited to and the reason written after it
+func parseIgnoreDirective(annotations []string, line string) (directive ignoreDirective, ok bool) {
+	for _, annotation := range annotations {
+		start := strings.Index(line, annotation)
+		if start < 0 {
+			continue
+		}
+		rest := line[start+len(annotation):]
+		for _, kind := range []string{ignoreNextLine, ignoreStart, ignoreEnd, ignoreFile} {
+			if strings.HasPrefix(rest, kind) {
+				directive.kind = kind
+				rest = rest[len(kind):]
+				break
+			}
+		}
+		if strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return ignoreDirective{}, false
+			}
+			for _, field := range strings.Split(rest[1:end], ",") {
+				code, err := strconv.Atoi(strings.TrimSpace(field))
+				if err != nil {
+					// A misspelt rule list must not turn into ignoring every rule
+					return ignoreDirective{}, false
+				}
+				directive.codes = append(directive.codes, code)
+			}
+			rest = rest[end+1:]
+		}
+		directive.text = line[start : len(line)-len(rest)]
+		directive.reason = ignoreReason(rest)
+		return directive, true
+	}
+	return ignoreDirective{}, false
+}
+
+// ignoreReason cleans up the text after an ignore annotation, dropping separators and comment closers
+func ignoreReason(text string) string {
+	text = strings.TrimSpace(text)
+	for _, closer := range []string{"*/", "-->", "--%>", "%>", "#}", "*)"} {
+		text = strings.TrimSuffix(text, closer)
+	}
+	return strings.TrimSpace(strings.TrimLeft(text, " \t:-"))
+}
+
+// newIgnoreIndex collects the ignore directives of a file, nil if it has none
+func newIgnoreIndex(annotations []string, lines []Line) *ignoreIndex {
+	builder := ignoreIndexBuilder{annotations: annotations}
+	for _, line := range lines {
+		builder.add(line)
+	}
+	return builder.finish()
+}
+
+// add collects the ignore directive on the next line of the file, if there is one
+func (b *ignoreIndexBuilder) add(line Line) {
+	if line.LineNum > b.last {
+		b.last = line.LineNum
+	}
+	directive, ok := parseIgnoreDirective(b.annotations, line.LineValue)
+	if !ok {
+		return
+	}
+	if b.index == nil {
+		b.index = &ignoreIndex{lines: make(map[int][]ignoreDirective)}
+	}
+	index := b.index
+	directive.line = line.LineNum
+	switch directive.kind {
+	case ignoreNextLine:
+		index.lines[line.LineNum+1] = append(index.lines[line.LineNum+1], directive)
+	case ignoreFile:
+		index.file = append(index.file, directive)
+	case ignoreStart:
+		b.open = append(b.open, directive)
+	case ignoreEnd:
+		//An -END closes the blocks with the same rules, or every open block when it names none
+		remaining := b.open[:0]
+		for _, block := range b.open {
+			if len(directive.codes) == 0 || sameCodes(block.codes, directive.codes) {
+				index.blocks = append(index.blocks, ignoreBlock{directive: block, from: block.line, to: line.LineNum})
+			} else {
+				remaining = append(remaining, block)
+			}
+		}
+		b.open = remaining
+	default:
+		index.lines[line.LineNum] = append(index.lines[line.LineNum], directive)
+	}
+}
+
+// finish returns the index once the whole file is read, nil if it has no directives
+func (b *ignoreIndexBuilder) finish() *ignoreIndex {
+	//Blocks which are never closed run to the end of the file
+	for _, block := range b.open {
+		b.index.blocks = append(b.index.blocks, ignoreBlock{directive: block, from: block.line, to: b.last})
+	}
+	b.open = nil
+	return b.index
+}
+
+// suppressedBy returns the directive that suppresses the hit, if any
+func (x *ignoreIndex) suppressedBy(hit Hit) (ignoreDirective, bool) {
+	if x == nil {
+		return ignoreDirective{}, false
+	}
+	covering := append([]ignoreDirective{}, x.lines[hit.Line]...)
+	for _, block := range x.blocks {
+		if hit.Line >= block.from && hit.Line <= block.to {
+			covering = append(covering, block.directive)
+		}
+	}
+	covering = append(covering, x.file...)
+	for _, directive := range covering {
+		if directive.covers(hit.Code) {
+			return directive, true
+		}
+	}
+	return ignoreDirective{}, false
+}
+
+// covers reports whether the directive applies to the rule
+func (d ignoreDirective) covers(code int) bool {
+	if len(d.codes) == 0 {
+		return true
+	}
+	for _, c := range d.codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func sameCodes(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, code := range b {
+		if !(ignoreDirective{codes: a}).covers(code) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithSuppressions returns a context which makes SearchFiles record the hits left out by ignore directives in log
+func WithSuppressions(ctx context.Context, log *SuppressionLog) context.Context {
+	return context.WithValue(ctx, suppressionsKey{}, log)
+}
+
+// suppression describes the hit suppressed by the directive
+func (d ignoreDirective) suppression(hit Hit) Suppression {
+	suppression := newSuppression(hit)
+	suppression.Directive = d.text
+	suppression.DirectiveLine = d.line
+	suppression.Reason = d.reason
+	return suppression
+}
+
+// newSuppression describes the suppressed hit, leaving out its secret values
+func newSuppression(hit Hit) Suppression {
+	return Suppression{
+		Code:        hit.Code,
+		Caption:     hit.Caption,
+		Category:    hit.Category,
+		Severity:    hit.Severity,
+		Filename:    hit.Filename,
+		Line:        hit.Line,
+		Fingerprint: hit.Fingerprint,
+	}
+}
+
+// recordSuppression adds the suppression to the log of the scan, if the caller asked for one
+func recordSuppression(ctx context.Context, suppression Suppression) {
+	log, ok := ctx.Value(suppressionsKey{}).(*SuppressionLog)
+	if !ok || log == nil {
+		return
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.entries = append(log.entries, suppression)
+}
+
+// Entries returns the suppressions recorded so far, ordered by file, line and rule
+func (l *SuppressionLog) Entries() []Suppression {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	entries := append([]Suppression(nil), l.entries...)
+	l.mu.Unlock()
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Filename != entries[j].Filename {
+			return entries[i].Filename < entries[j].Filename
+		}
+		if entries[i].Line != entries[j].Line {
+			return entries[i].Line < entries[j].Line
+		}
+		return entries[i].Code < entries[j].Code
+	})
+	return entries
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var annotations = []string{"EARLYBIRD-IGNORE", "EB-IGNORE"}
+
+func Test_parseIgnoreDirective(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   ignoreDirective
+		wantOk bool
+	}{
+		{
+			name: "No annotation",
+			line: `password = "SecretValue1673"`,
+		},
+		{
+			name:   "Same line",
+			line:   `password = "unit_test" //EARLYBIRD-IGNORE`,
+			want:   ignoreDirective{text: "EARLYBIRD-IGNORE"},
+			wantOk: true,
+		},
+		{
+			name:   "Next line with a reason",
+			line:   `# EARLYBIRD-IGNORE-NEXT-LINE: test fixture`,
+			want:   ignoreDirective{text: "EARLYBIRD-IGNORE-NEXT-LINE", kind: ignoreNextLine, reason: "test fixture"},
+			wantOk: true,
+		},
+		{
+			name:   "Rule scoped block in an HTML comment",
+			line:   `<!-- EB-IGNORE-START[3013, 1002] sample keys -->`,
+			want:   ignoreDirective{text: "EB-IGNORE-START[3013, 1002]", kind: ignoreStart, codes: []int{3013, 1002}, reason: "sample keys"},
+			wantOk: true,
+		},
+		{
+			name:   "Block end in a C comment",
+			line:   `/* EARLYBIRD-IGNORE-END */`,
+			want:   ignoreDirective{text: "EARLYBIRD-IGNORE-END", kind: ignoreEnd},
+			wantOk: true,
+		},
+		{
+			name:   "Whole file",
+			line:   `# EARLYBIRD-IGNORE-FILE - generated fixtures`,
+			want:   ignoreDirective{text: "EARLYBIRD-IGNORE-FILE", kind: ignoreFile, reason: "generated fixtures"},
+			wantOk: true,
+		},
+		{
+			name: "Unreadable rule list",
+			line: `# EARLYBIRD-IGNORE[password]`,
+		},
+		{
+			name: "Unclosed rule list",
+			line: `# EARLYBIRD-IGNORE[3013`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := parseIgnoreDirective(annotations, tt.line)
+			if gotOk != tt.wantOk || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIgnoreDirective() = %+v, %v, want %+v, %v", got, gotOk, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_ignoreIndex_suppressedBy(t *testing.T) {
+	var lines []Line
+	for i, value := range []string{
+		"a //EARLYBIRD-IGNORE",
+		"# EARLYBIRD-IGNORE-NEXT-LINE[3013]",
+		"b",
+		"# EARLYBIRD-IGNORE-START[1002]",
+		"c",
+		"# EARLYBIRD-IGNORE-START",
+		"d",
+		"# EARLYBIRD-IGNORE-END[1002]",
+		"e",
+		"f",
+	} {
+		lines = append(lines, Line{LineNum: i + 1, LineValue: value})
+	}
+	index := newIgnoreIndex(annotations, lines)
+
+	tests := []struct {
+		line, code    int
+		wantDirective int
+	}{
+		{line: 1, code: 3013, wantDirective: 1},
+		{line: 2, code: 3013},
+		{line: 3, code: 3013, wantDirective: 2},
+		{line: 3, code: 1002},
+		{line: 5, code: 1002, wantDirective: 4},
+		{line: 5, code: 3013},
+		{line: 7, code: 3013, wantDirective: 6},
+		// The -END[1002] only closed the 1002 block, the unscoped one runs to the end of the file
+		{line: 9, code: 1002, wantDirective: 6},
+		{line: 10, code: 3013, wantDirective: 6},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("line %d rule %d", tt.line, tt.code), func(t *testing.T) {
+			directive, ok := index.suppressedBy(Hit{Line: tt.line, Code: tt.code})
+			if ok != (tt.wantDirective != 0) || directive.line != tt.wantDirective {
+				t.Errorf("suppressedBy() = line %d, %v, want line %d", directive.line, ok, tt.wantDirective)
+			}
+		})
+	}
+
+	if newIgnoreIndex(annotations, []Line{{LineNum: 1, LineValue: "a"}}) != nil {
+		t.Errorf("newIgnoreIndex() built an index for a file without directives")
+	}
+	fileIndex := newIgnoreIndex(annotations, []Line{{LineNum: 1, LineValue: "a"}, {LineNum: 2, LineValue: "# EB-IGNORE-FILE[3013]"}})
+	if _, ok := fileIndex.suppressedBy(Hit{Line: 1, Code: 3013}); !ok {
+		t.Errorf("suppressedBy() ignored the -FILE directive")
+	}
+}
+
+func TestSearchFiles_ignoreDirectives(t *testing.T) {
+	secret := `password = "SecretValue1673"`
+	content := strings.Join([]string{
+		secret,
+		"# EARLYBIRD-IGNORE-NEXT-LINE test fixture",
+		secret,
+		"# EARLYBIRD-IGNORE-START[1] some other rule",
+		secret,
+		"# EARLYBIRD-IGNORE-END",
+	}, "\n")
+	scanFile := filepath.Join(t.TempDir(), "settings.py")
+	if err := os.WriteFile(scanFile, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	scanCfg := cfg
+	scanCfg.AnnotationsToSkipLine = annotations
+	suppressions := &SuppressionLog{}
+
+	hits := make(chan Hit)
+	go SearchFiles(WithSuppressions(context.Background(), suppressions), &scanCfg, []File{{Name: scanFile, Path: scanFile}}, nil, nil, hits)
+	found := map[int]bool{}
+	for hit := range hits {
+		found[hit.Line] = true
+	}
+	if !found[1] || found[3] || !found[5] {
+		t.Errorf("SearchFiles() reported hits on lines %v, want 1 and 5", found)
+	}
+
+	entries := suppressions.Entries()
+	if len(entries) == 0 {
+		t.Fatal("SearchFiles() recorded no suppressions")
+	}
+	for _, entry := range entries {
+		if entry.Line != 3 || entry.DirectiveLine != 2 || entry.Reason != "test fixture" || entry.Directive != "EARLYBIRD-IGNORE-NEXT-LINE" {
+			t.Errorf("SearchFiles() recorded suppression %+v", entry)
+		}
+	}
+}
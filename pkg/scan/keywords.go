@@ -0,0 +1,101 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+// keywordMatcher is an Aho-Corasick automaton over the keywords of all the rules.
+// It finds in a single pass over a line which rules have one of their keywords in it, ignoring ASCII case.
+type keywordMatcher struct {
+	// next is the complete transition table, so matching never has to follow failure links
+	next [][256]int32
+	// rules lists the indexes of the rules with a keyword ending at each state
+	rules [][]int
+	count int
+}
+
+// newKeywordMatcher builds the automaton for the keywords of the rules, indexed by their position in the slice
+func newKeywordMatcher(rules []Rule) *keywordMatcher {
+	m := &keywordMatcher{
+		next:  make([][256]int32, 1),
+		rules: make([][]int, 1),
+		count: len(rules),
+	}
+
+	// Build the trie of lowercased keywords, a next value of 0 means no transition yet
+	for i, rule := range rules {
+		for _, keyword := range rule.Keywords {
+			if keyword == "" {
+				continue
+			}
+			state := int32(0)
+			for j := 0; j < len(keyword); j++ {
+				c := lowerASCII(keyword[j])
+				if m.next[state][c] == 0 {
+					m.next = append(m.next, [256]int32{})
+					m.rules = append(m.rules, nil)
+					m.next[state][c] = int32(len(m.next) - 1)
+				}
+				state = m.next[state][c]
+			}
+			m.rules[state] = append(m.rules[state], i)
+		}
+	}
+
+	// Breadth first, point missing transitions at the transition of the failure state and inherit its rules
+	fail := make([]int32, len(m.next))
+	var queue []int32
+	for c := 0; c < 256; c++ {
+		if child := m.next[0][c]; child != 0 {
+			queue = append(queue, child)
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		m.rules[state] = append(m.rules[state], m.rules[fail[state]]...)
+		for c := 0; c < 256; c++ {
+			child := m.next[state][c]
+			if child == 0 {
+				m.next[state][c] = m.next[fail[state]][c]
+				continue
+			}
+			fail[child] = m.next[fail[state]][c]
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// match returns which rules have a keyword in the line, indexed like the rules the matcher was built from
+func (m *keywordMatcher) match(line string) []bool {
+	found := make([]bool, m.count)
+	state := int32(0)
+	for i := 0; i < len(line); i++ {
+		state = m.next[state][lowerASCII(line[i])]
+		for _, rule := range m.rules[state] {
+			found[rule] = true
+		}
+	}
+	return found
+}
+
+// lowerASCII lowercases a single ASCII letter, other bytes are returned as is
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
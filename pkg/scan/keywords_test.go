@@ -0,0 +1,121 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_keywordMatcher(t *testing.T) {
+	rules := []Rule{
+		{Code: 1, Keywords: []string{"password"}},
+		{Code: 2, Keywords: []string{"pass", "pwd"}},
+		{Code: 3},
+		{Code: 4, Keywords: []string{"word"}},
+	}
+	matcher := newKeywordMatcher(rules)
+
+	tests := []struct {
+		name string
+		line string
+		want []bool
+	}{
+		{name: "Overlapping keywords", line: `db_password = "abc"`, want: []bool{true, true, false, true}},
+		{name: "Case insensitive", line: `DB_PWD=abc`, want: []bool{false, true, false, false}},
+		{name: "No keyword", line: `host = "localhost"`, want: []bool{false, false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matcher.match(tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleKeywords(t *testing.T) {
+	// A keyword that isn't in the example the rule matches would hide real findings
+	for _, rule := range CombinedRules {
+		if len(rule.Keywords) == 0 || rule.Example == "" || !rule.CompiledPattern.MatchString(rule.Example) {
+			continue
+		}
+		if !newKeywordMatcher([]Rule{rule}).match(rule.Example)[0] {
+			t.Errorf("Rule %d matches its example %q but none of its keywords %v", rule.Code, rule.Example, rule.Keywords)
+		}
+	}
+}
+
+// syntheticCorpus mixes ordinary source lines with a few secrets
+func syntheticCorpus(lineCount int) []Line {
+	templates := []string{
+		`func handler%d(w http.ResponseWriter, r *http.Request) {`,
+		`	result := compute(input[%d], options)`,
+		`	log.Printf("processing item %%d", %d)`,
+		`// keep the cache warm for request %d`,
+		`	if err != nil { return fmt.Errorf("step %d: %%v", err) }`,
+		`	values = append(values, item%d.Name)`,
+		`	db_password = "SecretValue%d"`,
+		`}`,
+	}
+	lines := make([]Line, lineCount)
+	for i := range lines {
+		lines[i] = Line{
+			LineNum:   i + 1,
+			LineValue: fmt.Sprintf(templates[i%len(templates)], i),
+			FileName:  "corpus.go",
+			FilePath:  "buffer",
+		}
+	}
+	return lines
+}
+
+func BenchmarkScanLine(b *testing.B) {
+	lines := syntheticCorpus(800)
+	var size int64
+	for _, line := range lines {
+		size += int64(len(line.LineValue))
+	}
+	scanCfg := cfg
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	matcher := engine.keywords
+
+	for _, bench := range []struct {
+		name    string
+		matcher *keywordMatcher
+	}{
+		{name: "regex only", matcher: nil},
+		{name: "keyword prefilter", matcher: matcher},
+	} {
+		b.Run(strings.ReplaceAll(bench.name, " ", "_"), func(b *testing.B) {
+			engine.keywords = bench.matcher
+			b.SetBytes(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Without a file index no multiline label applies, so the benchmark measures the rules
+				for j := range lines {
+					engine.scanLine(lines[j], nil, &scanCfg)
+				}
+			}
+		})
+	}
+}
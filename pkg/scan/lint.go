@@ -0,0 +1,194 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
+)
+
+// knownPostprocess are the Postprocess names of the rules which the scan implements, an empty name means none
+var knownPostprocess = map[string]bool{
+	"":          true,
+	"password":  true,
+	"ssn":       true,
+	"jwt":       true,
+	"basicAuth": true,
+	"mod10":     true,
+	"entropy":   true,
+	"key":       true,
+	"jks":       true,
+	"pem":       true,
+}
+
+// LintConfig checks every rules file in the rules directory and the rule sources together with the false positives,
+// labels and solutions files, and returns all the problems it finds. Besides what keeps the config from loading, it finds misspelled fields,
+// codes used by more than one module, solutions and levels that don't exist, and false positive rules and labels for
+// codes no rule has.
+func LintConfig(cfg cfgReader.EarlybirdConfig) (problems []ConfigProblem) {
+	layers := configLayers(cfg)
+	levels := make(map[int]bool)
+	for _, level := range cfg.LevelMap {
+		levels[level] = true
+	}
+	solutionDirs := layerDirs(layers, func(l configLayer) string { return l.solutionsDir })
+	solutions, solutionProblems := loadSolutions(solutionDirs...)
+	problems = append(problems, solutionProblems...)
+	for _, dir := range solutionDirs {
+		problems = append(problems, lintStrict(dir, &Solutions{})...)
+	}
+
+	//codeFiles tracks the rules file each code was first seen in, a rule source may override the codes of the layers below it
+	codeFiles := make(map[int]string)
+	for _, rulesDir := range layerDirs(layers, func(l configLayer) string { return l.rulesDir }) {
+		ruleFiles, err := os.ReadDir(rulesDir)
+		if err != nil {
+			problems = append(problems, ConfigProblem{File: rulesDir, Message: "failed to read rules directory: " + err.Error(), Fatal: true})
+			continue
+		}
+		for _, entry := range ruleFiles {
+			if entry.IsDir() {
+				continue
+			}
+			rulePath := path.Join(rulesDir, entry.Name())
+			rules, ruleProblems := readRules(rulePath)
+			problems = append(problems, ruleProblems...)
+			problems = append(problems, lintStrict(rulePath, &Rules{})...)
+			for i, rule := range rules.Rules {
+				problem := ConfigProblem{File: rulePath, Position: fmt.Sprintf("rules[%d]", i), Code: rule.Code}
+				if rule.Code == 0 {
+					problem.Message = "missing Code"
+					problems = append(problems, problem)
+				} else if file, ok := codeFiles[rule.Code]; ok && file != rulePath && path.Dir(file) == rulesDir {
+					problem.Message = fmt.Sprintf("Code %d is also used in %s", rule.Code, file)
+					problems = append(problems, problem)
+				} else if !ok || path.Dir(file) != rulesDir {
+					codeFiles[rule.Code] = rulePath
+				}
+				if _, ok := solutions[rule.SolutionID]; rule.SolutionID != 0 && !ok {
+					problem.Message = fmt.Sprintf("SolutionID %d has no solution", rule.SolutionID)
+					problems = append(problems, problem)
+				}
+				if len(levels) > 0 && !levels[rule.Severity] {
+					problem.Message = fmt.Sprintf("Severity %d is not a level", rule.Severity)
+					problems = append(problems, problem)
+				}
+				if len(levels) > 0 && !levels[rule.Confidence] {
+					problem.Message = fmt.Sprintf("Confidence %d is not a level", rule.Confidence)
+					problems = append(problems, problem)
+				}
+			}
+		}
+	}
+
+	problems = append(problems, lintCodes(layerDirs(layers, func(l configLayer) string { return l.falsePositivesDir }), codeFiles, &FalsePositives{}, func(path string) ([]codeEntry, []ConfigProblem) {
+		falsePositives, readProblems := readFalsePositives(path)
+		var entries []codeEntry
+		for i, fp := range falsePositives.FalsePositives {
+			entries = append(entries, codeEntry{position: fmt.Sprintf("rules[%d]", i), codes: fp.Codes})
+		}
+		return entries, readProblems
+	})...)
+	problems = append(problems, lintCodes(layerDirs(layers, func(l configLayer) string { return l.labelsDir }), codeFiles, &LabelConfigs{}, func(path string) ([]codeEntry, []ConfigProblem) {
+		labels, readProblems := readLabels(path)
+		var entries []codeEntry
+		for i, label := range labels.Labels {
+			entries = append(entries, codeEntry{position: fmt.Sprintf("Labels[%d]", i), codes: label.Codes})
+		}
+		return entries, readProblems
+	})...)
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].File < problems[j].File })
+	return problems
+}
+
+// codeEntry is a false positive rule or label with the rule codes it applies to
+type codeEntry struct {
+	position string
+	codes    []int
+}
+
+// lintCodes checks the files of the false positives or labels directories, and reports the codes no rule has
+func lintCodes(dirPaths []string, codeFiles map[int]string, schema interface{}, read func(path string) ([]codeEntry, []ConfigProblem)) (problems []ConfigProblem) {
+	files, problems := configFiles(dirPaths...)
+	for _, path := range files {
+		entries, readProblems := read(path)
+		problems = append(problems, readProblems...)
+		problems = append(problems, lintStrict(path, schema)...)
+		for _, entry := range entries {
+			var missing []string
+			seen := make(map[int]bool)
+			for _, code := range entry.codes {
+				if _, ok := codeFiles[code]; !ok && !seen[code] {
+					seen[code] = true
+					missing = append(missing, strconv.Itoa(code))
+				}
+			}
+			if len(missing) > 0 {
+				problems = append(problems, ConfigProblem{File: path, Position: entry.position, Message: "no rule has Codes " + strings.Join(missing, ", ")})
+			}
+		}
+	}
+	return problems
+}
+
+// lintStrict reports the fields of the file, or of every file in the directory, which the schema doesn't have
+func lintStrict(filePath string, schema interface{}) (problems []ConfigProblem) {
+	files, _ := configFiles(filePath)
+	for _, file := range files {
+		// Files that don't load at all are already reported
+		if err := cfgReader.LoadConfig(schema, file); err != nil {
+			continue
+		}
+		if err := cfgReader.LoadConfigStrict(schema, file); err != nil {
+			problems = append(problems, ConfigProblem{File: file, Message: "unexpected content: " + err.Error()})
+		}
+	}
+	return problems
+}
+
+// String formats the problem as file: position (code): severity: message
+func (p ConfigProblem) String() string {
+	location := p.File
+	if p.Position != "" {
+		location += ": " + p.Position
+	}
+	if p.Code != 0 {
+		location += fmt.Sprintf(" (code %d)", p.Code)
+	}
+	severity := "warning"
+	if p.Fatal {
+		severity = "error"
+	}
+	return fmt.Sprintf("%s: %s: %s", location, severity, p.Message)
+}
+
+// Error lists every problem on its own line
+func (e *ConfigError) Error() string {
+	lines := make([]string, 0, len(e.Problems)+1)
+	lines = append(lines, "Earlybird config problems:")
+	for _, problem := range e.Problems {
+		lines = append(lines, problem.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
+)
+
+// writeConfig writes the files, keyed by their path in the config directory, and returns a config reading them
+func writeConfig(t *testing.T, files map[string]string) cfgReader.EarlybirdConfig {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, sub := range []string{"rules", "falsepositives", "labels", "solutions"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfgReader.EarlybirdConfig{
+		RulesConfigDir:          filepath.Join(dir, "rules"),
+		FalsePositivesConfigDir: filepath.Join(dir, "falsepositives"),
+		LabelsConfigDir:         filepath.Join(dir, "labels"),
+		SolutionsConfigDir:      filepath.Join(dir, "solutions"),
+		LevelMap:                map[string]int{"critical": 1, "high": 2, "medium": 3, "low": 4},
+		SeverityDisplayLevel:    4,
+		ConfidenceDisplayLevel:  4,
+		EnabledModulesMap:       map[string]string{"custom": "custom.yaml", "other": "other.yaml"},
+	}
+}
+
+var brokenConfig = map[string]string{
+	"rules/custom.yaml": `Searcharea: body
+rules:
+  - Code: 9001
+    Pattern: "token=(["
+    Caption: Broken pattern
+    SolutionID: 1
+    Severity: 2
+    Confidence: 2
+  - Code: 9002
+    Pattern: "secret=\\w+"
+    Caption: Misspelled post-processing
+    Postprocess: pasword
+    Verifier: whoami
+    SolutionID: 7
+    Severity: 9
+    Confidence: 2
+    Exmaple: secret=abc
+`,
+	"rules/other.yaml": `Searcharea: body
+rules:
+  - Code: 9002
+    Pattern: "key=\\w+"
+    Caption: Duplicate code
+    SolutionID: 1
+    Severity: 2
+    Confidence: 2
+`,
+	"falsepositives/fp.yaml": `rules:
+  - Codes: [9002, 9999]
+    Pattern: "secret=test"
+  - Codes: [9001]
+    Pattern: "(unclosed"
+`,
+	"labels/labels.yaml": `Labels:
+  - label: test
+    keys: [test]
+    codes: [9998]
+`,
+	"solutions/solutions.yaml": `solutions:
+  - id: 1
+    text: Remove the secret
+`,
+}
+
+func TestLintConfig(t *testing.T) {
+	problems := LintConfig(writeConfig(t, brokenConfig))
+	want := []struct {
+		file    string
+		message string
+		fatal   bool
+	}{
+		{file: "custom.yaml", message: "invalid Pattern", fatal: true},
+		{file: "custom.yaml", message: `unknown Postprocess "pasword"`},
+		{file: "custom.yaml", message: `unknown Verifier "whoami"`},
+		{file: "custom.yaml", message: "SolutionID 7 has no solution"},
+		{file: "custom.yaml", message: "Severity 9 is not a level"},
+		{file: "custom.yaml", message: `unknown field "Exmaple"`},
+		{file: "other.yaml", message: "Code 9002 is also used in"},
+		{file: "fp.yaml", message: "no rule has Codes 9999"},
+		{file: "fp.yaml", message: "invalid Pattern", fatal: true},
+		{file: "labels.yaml", message: "no rule has Codes 9998"},
+	}
+	for _, w := range want {
+		found := false
+		for _, problem := range problems {
+			if strings.HasSuffix(problem.File, w.file) && strings.Contains(problem.Message, w.message) && problem.Fatal == w.fatal {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("LintConfig() is missing %s: %s (fatal %v) in %v", w.file, w.message, w.fatal, problems)
+		}
+	}
+	if len(problems) != len(want) {
+		t.Errorf("LintConfig() found %d problems, want %d: %v", len(problems), len(want), problems)
+	}
+}
+
+func TestNewEngine_configProblems(t *testing.T) {
+	_, err := NewEngine(writeConfig(t, brokenConfig))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("NewEngine() error = %v, want a *ConfigError", err)
+	}
+	// Both broken patterns are reported at once, the other problems don't keep the config from loading
+	if len(configErr.Problems) != 2 {
+		t.Errorf("NewEngine() reported %v, want the two invalid patterns", configErr.Problems)
+	}
+	if !strings.Contains(err.Error(), "rules[0] (code 9001): error: invalid Pattern") {
+		t.Errorf("NewEngine() error = %v, want the file and position of the invalid pattern", err)
+	}
+}
+
+func TestConfigProblem_String(t *testing.T) {
+	tests := []struct {
+		problem ConfigProblem
+		want    string
+	}{
+		{problem: ConfigProblem{File: "rules/custom.yaml", Position: "rules[3]", Code: 9001, Message: "missing Pattern", Fatal: true}, want: "rules/custom.yaml: rules[3] (code 9001): error: missing Pattern"},
+		{problem: ConfigProblem{File: "labels/labels.yaml", Message: "unexpected content"}, want: "labels/labels.yaml: warning: unexpected content"},
+	}
+	for _, tt := range tests {
+		if got := tt.problem.String(); got != tt.want {
+			t.Errorf("ConfigProblem.String() = %v, want %v", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/americanexpress/earlybird/v4/pkg/metrics"
+)
+
+// NewRuleProfile creates an empty profile, pass it to the scan with WithRuleProfile
+func NewRuleProfile() *RuleProfile {
+	return &RuleProfile{rules: make(map[int]*ruleCounters)}
+}
+
+// WithRuleProfile returns a context which makes SearchFiles record the cost and outcome of every rule in p
+func WithRuleProfile(ctx context.Context, p *RuleProfile) context.Context {
+	return context.WithValue(ctx, ruleProfileKey{}, p)
+}
+
+// Stats returns the recorded numbers of every rule, the most expensive rule first
+func (p *RuleProfile) Stats() []RuleStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]RuleStats, 0, len(p.rules))
+	for _, c := range p.rules {
+		stats = append(stats, RuleStats{
+			Code:           c.code,
+			Caption:        c.caption,
+			Category:       c.category,
+			Evaluations:    c.evaluations.Load(),
+			MatchTime:      time.Duration(c.matchTime.Load()),
+			Matches:        c.matches.Load(),
+			Hits:           c.hits.Load(),
+			FalsePositives: c.falsePositives.Load(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].MatchTime != stats[j].MatchTime {
+			return stats[i].MatchTime > stats[j].MatchTime
+		}
+		return stats[i].Code < stats[j].Code
+	})
+	return stats
+}
+
+// FalsePositiveRate is the share of the pattern matches the false positive rules removed
+func (s RuleStats) FalsePositiveRate() float64 {
+	if s.Matches == 0 {
+		return 0
+	}
+	return float64(s.FalsePositives) / float64(s.Matches)
+}
+
+// counters returns the counters of the rules in the order of the rules, rules with the same code share them
+func (p *RuleProfile) counters(rules []Rule) []*ruleCounters {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	counters := make([]*ruleCounters, len(rules))
+	for i, rule := range rules {
+		c, ok := p.rules[rule.Code]
+		if !ok {
+			c = &ruleCounters{code: rule.Code, caption: rule.Caption, category: rule.Category}
+			p.rules[rule.Code] = c
+		}
+		counters[i] = c
+	}
+	return counters
+}
+
+// withRuleProfile returns a copy of the engine which records into the rule profile of the context, if the caller asked for one
+func (e *Engine) withRuleProfile(ctx context.Context) *Engine {
+	p, ok := ctx.Value(ruleProfileKey{}).(*RuleProfile)
+	if !ok {
+		return e
+	}
+	profiled := *e
+	profiled.ruleCounters = p.counters(e.Rules)
+	return &profiled
+}
+
+// timed reports whether rule evaluations are measured, for the metrics or the rule profile
+func (e *Engine) timed() bool {
+	return e.ruleCounters != nil || metrics.Enabled()
+}
+
+// recordEvaluation records that the rule at index i of the engine ran its pattern for elapsed
+func (e *Engine) recordEvaluation(i int, elapsed time.Duration, matched bool) {
+	if metrics.Enabled() {
+		code := strconv.Itoa(e.Rules[i].Code)
+		metrics.RuleRegexSeconds.WithLabelValues(code).Add(elapsed.Seconds())
+		metrics.RuleEvaluations.WithLabelValues(code).Inc()
+	}
+	if e.ruleCounters != nil {
+		c := e.ruleCounters[i]
+		c.evaluations.Add(1)
+		c.matchTime.Add(int64(elapsed))
+		if matched {
+			c.matches.Add(1)
+		}
+	}
+}
+
+// recordOutcome records whether the match of the rule at index i of the engine survived the post-processing
+func (e *Engine) recordOutcome(i int, isHit, isFalsePositive bool) {
+	if e.ruleCounters == nil {
+		return
+	}
+	switch {
+	case isHit:
+		e.ruleCounters[i].hits.Add(1)
+	case isFalsePositive:
+		e.ruleCounters[i].falsePositives.Add(1)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/metrics"
+)
+
+// Reload loads the rules, false positives, labels and solutions again from the config directories and swaps them in for the next scans.
+// Scans already running finish with the rules they started with.  When the new config doesn't load, the error is returned and the current rules stay active.
+func Reload(cfg cfgreader.EarlybirdConfig) (*Engine, error) {
+	// The baseline and the verifier are kept, they are not part of the rule config
+	cfg.BaselineFile, cfg.Verify = "", false
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		metrics.ConfigReloads.WithLabelValues("failure").Inc()
+		return nil, err
+	}
+	swapRules(engine)
+	metrics.ConfigReloads.WithLabelValues("success").Inc()
+	return CurrentEngine(), nil
+}
+
+// CurrentEngine returns the rules loaded by Init or the last Reload, with the rest of the package level rule state
+func CurrentEngine() *Engine {
+	return globalEngine()
+}
+
+// swapRules makes the rules of the engine, with their false positives, labels, solutions and adjusted severities, the package level rule state
+func swapRules(engine *Engine) {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+	CombinedRules = engine.Rules
+	ruleKeywords = engine.keywords
+	severityAdjustments = engine.severityAdjustments
+	SolutionConfigs = engine.SolutionConfigs
+	Labels = engine.Labels
+	FalsePositiveRules = engine.FalsePositiveRules
+}
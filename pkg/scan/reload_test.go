@@ -0,0 +1,67 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	before := CurrentEngine()
+	t.Cleanup(func() { swapRules(before) })
+
+	// A config that doesn't load leaves the current rules active
+	_, err := Reload(writeConfig(t, brokenConfig))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Reload() error = %v, want a *ConfigError", err)
+	}
+	if got := CurrentEngine(); len(got.Rules) != len(before.Rules) || len(got.FalsePositiveRules) != len(before.FalsePositiveRules) {
+		t.Errorf("Reload() of a broken config changed the rules to %d rules", len(got.Rules))
+	}
+
+	// A valid config is swapped in for the next scans, a scan holding the old engine keeps its rules
+	reloadCfg := writeConfig(t, map[string]string{
+		"rules/custom.yaml": `Searcharea: body
+rules:
+  - Code: 9001
+    Pattern: "token=\\w+"
+    Caption: Token
+    Severity: 2
+    Confidence: 2
+`,
+		"falsepositives/fp.yaml": `rules:
+  - Codes: [9001]
+    Pattern: "token=test"
+`,
+	})
+	reloadCfg.EnabledModulesMap = map[string]string{"custom": "custom.yaml"}
+	reloaded, err := Reload(reloadCfg)
+	if err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if len(reloaded.Rules) != 1 || reloaded.Rules[0].Code != 9001 || len(reloaded.FalsePositiveRules) != 1 {
+		t.Errorf("Reload() loaded %d rules and %d false positive codes, want the custom rule", len(reloaded.Rules), len(reloaded.FalsePositiveRules))
+	}
+	if got := CurrentEngine(); len(got.Rules) != 1 || got.keywords != reloaded.keywords {
+		t.Errorf("Reload() didn't swap in the new rules, the current engine has %d rules", len(got.Rules))
+	}
+	if len(before.Rules) == 1 {
+		t.Errorf("Reload() changed the rules of an engine taken before it")
+	}
+}
@@ -17,6 +17,7 @@
 package scan
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -25,11 +26,12 @@ import (
 	"regexp"
 
 	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/verify"
 )
 
-// Init loads in all the Earlybird rules into the CombinedRules global variable
+// Init loads in all the Earlybird rules into the CombinedRules global variable and the rest of the package level rule state
 func Init(cfg cfgreader.EarlybirdConfig) {
-	if cfg.OutputFormat != "json" && !cfg.HideMeta {
+	if cfg.OutputFormat != "json" && cfg.OutputFormat != "sarif" && !cfg.HideMeta {
 		log.Println("Go-EarlyBird version: ", cfg.Version)
 		// Display options
 		fmt.Println("Severity Fail threshold (at or above): ", cfgreader.Settings.TranslateLevelID(cfg.SeverityFailLevel))
@@ -39,35 +41,33 @@ func Init(cfg cfgreader.EarlybirdConfig) {
 		fmt.Println("Max file size to scan: ", cfg.MaxFileSize, " bytes")
 	}
 
-	// Init rule set for modules
-	for moduleName, fileName := range cfg.EnabledModulesMap {
-		log.Println("loading module: ", moduleName)
-		CombinedRules = append(CombinedRules, loadRuleConfigs(cfg, moduleName, fileName)...)
-	}
-
-	var err error
-	//Load solutions for the rules
-	if cfg.ShowSolutions {
-		SolutionConfigs, err = loadSolutions(cfg.SolutionsConfigDir)
-
-		if err != nil {
-			log.Fatal("error loading solutions", err)
+	// When linting, report every problem in the config files and exit, failing on the ones that keep the config from loading
+	if cfg.LintConfig {
+		var errorCount int
+		problems := LintConfig(cfg)
+		for _, problem := range problems {
+			fmt.Println(problem)
+			if problem.Fatal {
+				errorCount++
+			}
+		}
+		fmt.Println(errorCount, "errors,", len(problems)-errorCount, "warnings")
+		if errorCount > 0 {
+			os.Exit(1)
 		}
+		os.Exit(0)
 	}
 
-	// Init label configs
-	Labels, err = loadLabelConfigs(cfg.LabelsConfigDir)
-
-	if err != nil {
-		log.Fatal("error loading labels file")
+	for moduleName := range cfg.EnabledModulesMap {
+		log.Println("loading module: ", moduleName)
 	}
-
-	//Load false positive rules
-	FalsePositiveRules, err = loadFalsePositives(cfg.FalsePositivesConfigDir)
-
+	engine, err := NewEngine(cfg)
 	if err != nil {
-		log.Fatal("error loading false positive rules", err)
+		log.Fatal(err)
 	}
+	swapRules(engine)
+	BaselineFingerprints = engine.BaselineFingerprints
+	Verifier = engine.Verifier
 
 	// If we're only displaying the rules to be run, filter out anything that we wouldn't fail on and exit to skip the scan.
 	// Only one output option since we exit before any interaction with Writers
@@ -89,68 +89,181 @@ func Init(cfg cfgreader.EarlybirdConfig) {
 		os.Exit(0)
 	}
 
-	// Compile adjusted severity regex patterns
-	if cfg.AdjustedSeverityCategories != nil {
-		for i := range cfg.AdjustedSeverityCategories {
-			if cfg.AdjustedSeverityCategories[i].Category == "" {
-				log.Fatal("Missing required field category")
+	// When testing the rules, check every example and exit, failing when any example doesn't behave
+	if cfg.TestRules {
+		failures := engine.CheckExamples(cfg)
+		fmt.Println("\nTesting the examples of", len(CombinedRules), "rules")
+		for _, failure := range failures {
+			if failure.Negative {
+				fmt.Printf("FAIL %d %s: negative example %q was reported\n", failure.Code, failure.Caption, failure.Example)
+			} else {
+				fmt.Printf("FAIL %d %s: example %q was not reported\n", failure.Code, failure.Caption, failure.Example)
 			}
+		}
+		if len(failures) > 0 {
+			fmt.Println(len(failures), "examples failed")
+			os.Exit(1)
+		}
+		fmt.Println("All examples passed")
+		os.Exit(0)
+	}
+}
 
-			if cfg.AdjustedSeverityCategories[i].Patterns == nil {
-				log.Fatal("Missing required field patterns")
-			}
+// NewEngine loads the rules of the enabled modules with their labels, false positive rules and solutions from the config directories.
+// Every problem which keeps the config from loading is returned at once in a *ConfigError, the others are logged.
+func NewEngine(cfg cfgreader.EarlybirdConfig) (engine *Engine, err error) {
+	engine = &Engine{}
+	var problems, loadProblems []ConfigProblem
+	// The rule sources are layered over the config directory, their rules override the ones with the same code
+	layers := configLayers(cfg)
+	layerRules := make([][]Rule, len(layers))
+	for moduleName, fileName := range cfg.EnabledModulesMap {
+		var moduleRules [][]Rule
+		moduleRules, loadProblems = loadModuleRules(cfg, layers, moduleName, fileName)
+		for i := range layers {
+			layerRules[i] = append(layerRules[i], moduleRules[i]...)
+		}
+		problems = append(problems, loadProblems...)
+	}
+	engine.Rules = overrideRules(layers, layerRules, cfg.VerboseEnabled)
+	engine.keywords = newKeywordMatcher(engine.Rules)
 
-			if cfg.AdjustedSeverityCategories[i].AdjustedDisplaySeverity == "" {
-				log.Fatal("Missing required field adjusted_display_severity")
-			}
+	//Load solutions for the rules, SARIF output always carries them in the rule table
+	if cfg.ShowSolutions || cfg.OutputFormat == "sarif" {
+		engine.SolutionConfigs, loadProblems = loadSolutions(layerDirs(layers, func(l configLayer) string { return l.solutionsDir })...)
+		problems = append(problems, loadProblems...)
+	}
+
+	// Init label configs
+	engine.Labels, loadProblems = loadLabelConfigs(layerDirs(layers, func(l configLayer) string { return l.labelsDir })...)
+	problems = append(problems, loadProblems...)
 
-			for _, regEx := range cfg.AdjustedSeverityCategories[i].Patterns {
-				compiled := regexp.MustCompile(regEx)
+	//Load false positive rules
+	engine.FalsePositiveRules, loadProblems = loadFalsePositives(layerDirs(layers, func(l configLayer) string { return l.falsePositivesDir })...)
+	problems = append(problems, loadProblems...)
+
+	if err = checkProblems(problems); err != nil {
+		return nil, err
+	}
 
-				cfg.AdjustedSeverityCategories[i].CompiledPatterns = append(cfg.AdjustedSeverityCategories[i].CompiledPatterns, compiled)
+	// Load the fingerprints of already known findings, unless we're recording a new baseline
+	if cfg.BaselineFile != "" && cfg.WriteBaselineFile == "" {
+		engine.BaselineFingerprints, err = loadBaseline(cfg.BaselineFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading baseline file: %v", err)
+		}
+	}
+
+	// Verifiers are only set up when asked for since they send the found credentials over the network
+	if cfg.Verify {
+		engine.Verifier = verify.NewChecker(cfg.VerifyEndpointOverride, cfg.VerifyTrustedHosts, cfg.VerifyRate, cfg.VerboseEnabled)
+	}
+
+	// Compile adjusted severity regex patterns, into a copy so the config is left as it is
+	engine.severityAdjustments = make([]cfgreader.AdjustedSeverityCategory, len(cfg.AdjustedSeverityCategories))
+	for i, adjustment := range cfg.AdjustedSeverityCategories {
+		if adjustment.Category == "" {
+			return nil, errors.New("Missing required field category")
+		}
+
+		if adjustment.Patterns == nil {
+			return nil, errors.New("Missing required field patterns")
+		}
+
+		if adjustment.AdjustedDisplaySeverity == "" {
+			return nil, errors.New("Missing required field adjusted_display_severity")
+		}
+
+		adjustment.CompiledPatterns = nil
+		for _, regEx := range adjustment.Patterns {
+			compiled, err := regexp.Compile(regEx)
+			if err != nil {
+				return nil, fmt.Errorf("invalid adjusted severity pattern %s: %v", regEx, err)
 			}
+
+			adjustment.CompiledPatterns = append(adjustment.CompiledPatterns, compiled)
 		}
+		engine.severityAdjustments[i] = adjustment
 	}
+	return engine, nil
 }
 
-// loadRuleConfigs loads the rules from the JSON config file, compiles the rules and defines the search area
-func loadRuleConfigs(cfg cfgreader.EarlybirdConfig, moduleName, fileName string) []Rule {
-	var rules, tmpRules Rules
-	rulePath := path.Join(cfg.RulesConfigDir, fileName)
+// checkProblems logs the problems the config still loads with, and returns the ones it doesn't as a *ConfigError
+func checkProblems(problems []ConfigProblem) error {
+	var fatal []ConfigProblem
+	for _, problem := range problems {
+		if problem.Fatal {
+			fatal = append(fatal, problem)
+		} else {
+			log.Println(problem)
+		}
+	}
+	if len(fatal) > 0 {
+		return &ConfigError{Problems: fatal}
+	}
+	return nil
+}
 
-	err := cfgreader.LoadConfig(&tmpRules, rulePath)
-	if err != nil {
-		log.Println("Failed to load rules file", err)
+// loadRuleConfigs loads the rules of the module from its config file and keeps the ones shown at the display levels
+func loadRuleConfigs(cfg cfgreader.EarlybirdConfig, moduleName, fileName string) (rules []Rule, problems []ConfigProblem) {
+	if fileName == "" {
+		return nil, []ConfigProblem{{File: cfg.RulesConfigDir, Message: fmt.Sprintf("no rules file for module %s", moduleName), Fatal: true}}
 	}
+	tmpRules, problems := readRules(path.Join(cfg.RulesConfigDir, fileName))
 
 	for i := range tmpRules.Rules {
+		// Rules whose pattern doesn't compile are already reported
+		if tmpRules.Rules[i].CompiledPattern == nil {
+			continue
+		}
 		if customRules, ok := cfg.ModuleConfigs.Modules[moduleName]; ok && tmpRules.Rules[i].Severity <= customRules.DisplaySeverityLevel && tmpRules.Rules[i].Confidence <= customRules.DisplayConfidenceLevel {
-			tmpRules.Rules[i].Searcharea = tmpRules.Searcharea
-			tmpRules.Rules[i].CompiledPattern = regexp.MustCompile(tmpRules.Rules[i].Pattern)
-			rules.Rules = append(rules.Rules, tmpRules.Rules[i])
+			rules = append(rules, tmpRules.Rules[i])
 		} else if tmpRules.Rules[i].Severity <= cfg.SeverityDisplayLevel && tmpRules.Rules[i].Confidence <= cfg.ConfidenceDisplayLevel {
-			tmpRules.Rules[i].Searcharea = tmpRules.Searcharea
-			tmpRules.Rules[i].CompiledPattern = regexp.MustCompile(tmpRules.Rules[i].Pattern)
-			rules.Rules = append(rules.Rules, tmpRules.Rules[i])
+			rules = append(rules, tmpRules.Rules[i])
 		}
 	}
-	return rules.Rules
+	return rules, problems
 }
 
-// loadLabelConfigs loads the labels from the config file
-func loadLabelConfigs(dirPath string) (LabelConfigRules map[int]LabelConfigs, err error) {
-	LabelConfigRules = make(map[int]LabelConfigs)
+// readRules loads and compiles every rule of a rules file, reporting the rules it can't use
+func readRules(rulePath string) (rules Rules, problems []ConfigProblem) {
+	if err := cfgreader.LoadConfig(&rules, rulePath); err != nil {
+		return rules, []ConfigProblem{{File: rulePath, Message: "failed to load rules file: " + err.Error(), Fatal: true}}
+	}
 
-	err = filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		rule.Searcharea = rules.Searcharea
+		problem := ConfigProblem{File: rulePath, Position: fmt.Sprintf("rules[%d]", i), Code: rule.Code}
+		if rule.Pattern == "" {
+			problem.Message, problem.Fatal = "missing Pattern", true
+			problems = append(problems, problem)
+		} else if compiled, err := regexp.Compile(rule.Pattern); err != nil {
+			problem.Message, problem.Fatal = "invalid Pattern: "+err.Error(), true
+			problems = append(problems, problem)
+		} else {
+			rule.CompiledPattern = compiled
 		}
-
-		var tmpRules LabelConfigs
-		err = cfgreader.LoadConfig(&tmpRules, path)
-		if err != nil {
-			log.Fatal("Failed to load labels file", err)
+		if !knownPostprocess[rule.Postprocess] {
+			problem.Message, problem.Fatal = fmt.Sprintf("unknown Postprocess %q, the hits are not post-processed", rule.Postprocess), false
+			problems = append(problems, problem)
+		}
+		if rule.Verifier != "" && !verify.Registered(rule.Verifier) {
+			problem.Message, problem.Fatal = fmt.Sprintf("unknown Verifier %q, the hits are not verified", rule.Verifier), false
+			problems = append(problems, problem)
 		}
+	}
+	return rules, problems
+}
+
+// loadLabelConfigs loads the labels from the config files of the directories
+func loadLabelConfigs(dirPaths ...string) (LabelConfigRules map[int]LabelConfigs, problems []ConfigProblem) {
+	LabelConfigRules = make(map[int]LabelConfigs)
+
+	files, problems := configFiles(dirPaths...)
+	for _, path := range files {
+		tmpRules, fileProblems := readLabels(path)
+		problems = append(problems, fileProblems...)
 
 		for i := range tmpRules.Labels {
 			for _, code := range tmpRules.Labels[i].Codes {
@@ -159,63 +272,104 @@ func loadLabelConfigs(dirPath string) (LabelConfigRules map[int]LabelConfigs, er
 				LabelConfigRules[code] = AppendedLabelConfigRules
 			}
 		}
+	}
 
-		return nil
-	})
+	return LabelConfigRules, problems
+}
 
-	return LabelConfigRules, err
+// readLabels loads a labels file, reporting the labels that never apply
+func readLabels(path string) (labels LabelConfigs, problems []ConfigProblem) {
+	if err := cfgreader.LoadConfig(&labels, path); err != nil {
+		return labels, []ConfigProblem{{File: path, Message: "failed to load labels file: " + err.Error(), Fatal: true}}
+	}
+	for i, label := range labels.Labels {
+		if len(label.Codes) == 0 {
+			problems = append(problems, ConfigProblem{File: path, Position: fmt.Sprintf("Labels[%d]", i), Message: fmt.Sprintf("label %q has no codes and never applies", label.Label)})
+		}
+	}
+	return labels, problems
 }
 
-// loadFalsePositives loads in and compiles all the false positive rules for Earlybird
-func loadFalsePositives(dirPath string) (FalsePositiveRules map[int]FalsePositives, err error) {
+// loadFalsePositives loads in and compiles all the false positive rules of the directories for Earlybird
+func loadFalsePositives(dirPaths ...string) (FalsePositiveRules map[int]FalsePositives, problems []ConfigProblem) {
 	FalsePositiveRules = make(map[int]FalsePositives)
 
-	err = filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
-		}
-
-		var tmpRules FalsePositives
-		err = cfgreader.LoadConfig(&tmpRules, path)
-		if err != nil {
-			log.Fatal("Failed to load false positives file ", path, err)
-		}
+	files, problems := configFiles(dirPaths...)
+	for _, path := range files {
+		tmpRules, fileProblems := readFalsePositives(path)
+		problems = append(problems, fileProblems...)
 
 		for i := range tmpRules.FalsePositives {
-			tmpRules.FalsePositives[i].CompiledPattern = regexp.MustCompile(tmpRules.FalsePositives[i].Pattern)
+			if tmpRules.FalsePositives[i].CompiledPattern == nil {
+				continue
+			}
 			for _, code := range tmpRules.FalsePositives[i].Codes {
 				var AppendedFalsePositives FalsePositives
 				AppendedFalsePositives.FalsePositives = append(FalsePositiveRules[code].FalsePositives, tmpRules.FalsePositives[i])
 				FalsePositiveRules[code] = AppendedFalsePositives
 			}
 		}
+	}
 
-		return nil
-	})
+	return FalsePositiveRules, problems
+}
 
-	return FalsePositiveRules, err
+// readFalsePositives loads and compiles a false positives file, reporting the rules it can't use
+func readFalsePositives(path string) (falsePositives FalsePositives, problems []ConfigProblem) {
+	if err := cfgreader.LoadConfig(&falsePositives, path); err != nil {
+		return falsePositives, []ConfigProblem{{File: path, Message: "failed to load false positives file: " + err.Error(), Fatal: true}}
+	}
+	for i := range falsePositives.FalsePositives {
+		fp := &falsePositives.FalsePositives[i]
+		position := fmt.Sprintf("rules[%d]", i)
+		if fp.Pattern == "" {
+			problems = append(problems, ConfigProblem{File: path, Position: position, Message: "missing Pattern", Fatal: true})
+		} else if compiled, err := regexp.Compile(fp.Pattern); err != nil {
+			problems = append(problems, ConfigProblem{File: path, Position: position, Message: "invalid Pattern: " + err.Error(), Fatal: true})
+		} else {
+			fp.CompiledPattern = compiled
+		}
+		if len(fp.Codes) == 0 {
+			problems = append(problems, ConfigProblem{File: path, Position: position, Message: "false positive rule has no Codes and never applies"})
+		}
+	}
+	return falsePositives, problems
 }
 
 // loadSolutions loads in solutions from the json config file
-func loadSolutions(dirPath string) (solutionConfigs map[int]Solution, err error) {
+func loadSolutions(dirPaths ...string) (solutionConfigs map[int]Solution, problems []ConfigProblem) {
 	solutionConfigs = make(map[int]Solution)
 
-	err = filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if info.IsDir() {
-			return nil
-		}
-
+	files, problems := configFiles(dirPaths...)
+	for _, path := range files {
 		var tmp Solutions
-		err = cfgreader.LoadConfig(&tmp, path)
-		if err != nil {
-			log.Fatal("Failed to load solutions file", err)
+		if err := cfgreader.LoadConfig(&tmp, path); err != nil {
+			problems = append(problems, ConfigProblem{File: path, Message: "failed to load solutions file: " + err.Error(), Fatal: true})
+			continue
 		}
 		for i := range tmp.Solutions {
 			solutionConfigs[tmp.Solutions[i].ID] = tmp.Solutions[i]
 		}
+	}
 
-		return nil
-	})
+	return solutionConfigs, problems
+}
 
-	return solutionConfigs, err
+// configFiles lists the files in the config directories and their subdirectories, in the order of the directories
+func configFiles(dirPaths ...string) (files []string, problems []ConfigProblem) {
+	for _, dirPath := range dirPaths {
+		err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			problems = append(problems, ConfigProblem{File: dirPath, Message: "failed to read config directory: " + err.Error(), Fatal: true})
+		}
+	}
+	return files, problems
 }
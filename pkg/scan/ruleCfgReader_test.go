@@ -37,18 +37,21 @@ func init() {
 }
 
 func Test_loadRuleConfigs(t *testing.T) {
-	rules := loadRuleConfigs(config, "content", "content.yaml")
+	rules, problems := loadRuleConfigs(config, "content", "content.yaml")
 
+	if len(problems) > 0 {
+		t.Fatal(problems)
+	}
 	if len(rules) == 0 {
 		t.Errorf("loadRuleConfigs() = %v, failed to load rules", rules)
 	}
 }
 
 func Test_loadLabelConfigs(t *testing.T) {
-	gotLabelConfigRules, err := loadLabelConfigs(path.Join(config.ConfigDir, "labels"))
+	gotLabelConfigRules, problems := loadLabelConfigs(path.Join(config.ConfigDir, "labels"))
 
-	if err != nil {
-		t.Fatal(err)
+	if len(problems) > 0 {
+		t.Fatal(problems)
 	}
 
 	if len(gotLabelConfigRules) == 0 {
@@ -57,10 +60,10 @@ func Test_loadLabelConfigs(t *testing.T) {
 }
 
 func Test_loadFalsePositives(t *testing.T) {
-	gotFalsePositiveRules, err := loadFalsePositives(path.Join(config.ConfigDir, "falsepositives"))
+	gotFalsePositiveRules, problems := loadFalsePositives(path.Join(config.ConfigDir, "falsepositives"))
 
-	if err != nil {
-		t.Fatal(err)
+	if len(problems) > 0 {
+		t.Fatal(problems)
 	}
 
 	if len(gotFalsePositiveRules) == 0 {
@@ -69,13 +72,42 @@ func Test_loadFalsePositives(t *testing.T) {
 }
 
 func Test_loadSolutions(t *testing.T) {
-	gotSolutionConfigs, err := loadSolutions(path.Join(config.ConfigDir, "solutions"))
+	gotSolutionConfigs, problems := loadSolutions(path.Join(config.ConfigDir, "solutions"))
 
-	if err != nil {
-		t.Fatal(err)
+	if len(problems) > 0 {
+		t.Fatal(problems)
 	}
 
 	if len(gotSolutionConfigs) == 0 {
 		t.Errorf("loadSolutions() = %v, Failed to load any solutions", gotSolutionConfigs)
 	}
 }
+
+func TestNewEngine(t *testing.T) {
+	engineCfg := cfg
+	engineCfg.EnabledModulesMap = map[string]string{"content": "content.yaml"}
+	engine, err := NewEngine(engineCfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	if len(engine.Rules) == 0 || len(engine.Labels) == 0 || len(engine.FalsePositiveRules) == 0 {
+		t.Errorf("NewEngine() = %d rules, %d labels, %d false positive rules, want all loaded", len(engine.Rules), len(engine.Labels), len(engine.FalsePositiveRules))
+	}
+	if len(engine.Rules) >= len(CombinedRules) {
+		t.Errorf("NewEngine() loaded %d rules for one module, Init loaded %d for two", len(engine.Rules), len(CombinedRules))
+	}
+	// The engine compiles its own copy of the adjusted severity patterns and leaves the config alone
+	if len(engine.severityAdjustments) != len(engineCfg.AdjustedSeverityCategories) || len(engine.severityAdjustments[0].CompiledPatterns) == 0 {
+		t.Errorf("NewEngine() compiled adjusted severities %+v", engine.severityAdjustments)
+	}
+	for _, adjustment := range engineCfg.AdjustedSeverityCategories {
+		if adjustment.CompiledPatterns != nil {
+			t.Errorf("NewEngine() compiled the patterns of category %s into the config", adjustment.Category)
+		}
+	}
+
+	engineCfg.BaselineFile = path.Join(t.TempDir(), "missing.json")
+	if _, err := NewEngine(engineCfg); err == nil {
+		t.Errorf("NewEngine() with a missing baseline file, want an error")
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
+)
+
+// CheckExamples runs the Example and Examples of every rule through the scan and its post-processing, and reports
+// the ones the rule doesn't report. NegativeExamples the rule does report are failures too.
+func (e *Engine) CheckExamples(cfg cfgReader.EarlybirdConfig) (failures []ExampleFailure) {
+	//Comment rules are always checked
+	cfg.SkipComments = false
+	for _, rule := range e.Rules {
+		for _, example := range positiveExamples(rule) {
+			if !e.exampleHits(&cfg, rule, example) {
+				failures = append(failures, ExampleFailure{Code: rule.Code, Caption: rule.Caption, Example: example})
+			}
+		}
+		for _, example := range rule.NegativeExamples {
+			if e.exampleHits(&cfg, rule, example) {
+				failures = append(failures, ExampleFailure{Code: rule.Code, Caption: rule.Caption, Example: example, Negative: true})
+			}
+		}
+	}
+	//The rules of the modules come in no particular order
+	sort.SliceStable(failures, func(i, j int) bool { return failures[i].Code < failures[j].Code })
+	return failures
+}
+
+// positiveExamples returns the Example of the rule followed by its Examples
+func positiveExamples(rule Rule) []string {
+	var examples []string
+	if rule.Example != "" {
+		examples = append(examples, rule.Example)
+	}
+	return append(examples, rule.Examples...)
+}
+
+// exampleHits reports whether the rule, on its own, reports the example. Content examples are scanned like a file
+// with one line per example line, file name examples like the path of a file.
+func (e *Engine) exampleHits(cfg *cfgReader.EarlybirdConfig, rule Rule, example string) bool {
+	//Only the rule under test runs, so other rules can't hide or stand in for its result
+	single := &Engine{
+		Rules:              []Rule{rule},
+		Labels:             e.Labels,
+		FalsePositiveRules: e.FalsePositiveRules,
+		SolutionConfigs:    e.SolutionConfigs,
+	}
+	single.keywords = newKeywordMatcher(single.Rules)
+
+	if rule.Searcharea != "filename" {
+		var lines []Line
+		for i, value := range strings.Split(example, "\n") {
+			lines = append(lines, Line{LineNum: i + 1, LineValue: value, FileName: "example", FilePath: "buffer"})
+		}
+		file := indexLines(single.multilineKeys(), cfg.AnnotationsToSkipLine, lines)
+		for _, line := range lines {
+			if found, _ := single.scanLine(line, file, cfg); found {
+				return true
+			}
+		}
+	}
+	if rule.Searcharea != "body" {
+		//The file post-processing looks at the file content, which a file name example doesn't have
+		single.Rules[0].Postprocess = ""
+		found, _ := single.scanName(File{Name: filepath.Base(example), Path: example}, cfg)
+		return found
+	}
+	return false
+}
@@ -18,6 +18,7 @@ package scan
 
 import (
 	"bufio"
+	"context"
 	"crypto/sha1"
 	"io"
 	"io/fs"
@@ -28,12 +29,16 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/metrics"
 	"github.com/americanexpress/earlybird/v4/pkg/postprocess"
+	"github.com/americanexpress/earlybird/v4/pkg/verify"
 )
 
+// The rule state below is filled by Init and used by SearchFiles, callers holding their own Engine don't need it
 var (
 	//Labels is a map of all our labels, accessible by the rule unique code
 	Labels map[int]LabelConfigs
@@ -43,80 +48,203 @@ var (
 	FalsePositiveRules map[int]FalsePositives
 	//SolutionConfigs is a map of our solutions sorted by the rule unique code
 	SolutionConfigs map[int]Solution
+	//ruleKeywords finds the rules whose keywords occur in a line, so only their patterns need to run
+	ruleKeywords *keywordMatcher
+	//severityAdjustments are the adjusted severity categories of the config loaded by Init, with their patterns compiled
+	severityAdjustments []cfgReader.AdjustedSeverityCategory
+	//BaselineFingerprints is the set of hit fingerprints loaded from the baseline file, these hits are not reported
+	BaselineFingerprints map[string]bool
+	//Verifier tries found credentials against their provider when verification is enabled
+	Verifier *verify.Checker
 	//CompressPattern is a pattern used to identify compressed zip files
 	CompressPattern = regexp.MustCompile(compressRegex)
 	//ConvertPattern is a pattern used to identify files that need to be converted to plaintext to be scanned
 	ConvertPattern = regexp.MustCompile(convertRegex)
 	tempPattern    = regexp.MustCompile(tempRegex)
+	//stateMutex guards the rule state against a Reload while scans are starting
+	stateMutex sync.RWMutex
 )
 
-// SearchFiles will use the EarlybirdConfig, the provided file list, decompressed zip files and converted files temporary paths to send found secrets to the Hit channel
-func SearchFiles(cfg *cfgReader.EarlybirdConfig, files []File, compressPaths []string, convertPaths []string, hits chan<- Hit) {
+// SearchFiles scans the files with the rules loaded by Init, see Engine.SearchFiles
+func SearchFiles(ctx context.Context, cfg *cfgReader.EarlybirdConfig, files []File, compressPaths []string, convertPaths []string, hits chan<- Hit) {
+	globalEngine().SearchFiles(ctx, cfg, files, compressPaths, convertPaths, hits)
+}
+
+// SearchFileBatches scans the batches of files with the rules loaded by Init, see Engine.SearchFileBatches
+func SearchFileBatches(ctx context.Context, cfg *cfgReader.EarlybirdConfig, batches <-chan []File, hits chan<- Hit) {
+	globalEngine().SearchFileBatches(ctx, cfg, batches, hits)
+}
+
+// globalEngine wraps the package level rule state, so changes callers make to it are picked up by the next scan
+func globalEngine() *Engine {
+	stateMutex.RLock()
+	defer stateMutex.RUnlock()
+	return &Engine{
+		Rules:                CombinedRules,
+		Labels:               Labels,
+		FalsePositiveRules:   FalsePositiveRules,
+		SolutionConfigs:      SolutionConfigs,
+		BaselineFingerprints: BaselineFingerprints,
+		Verifier:             Verifier,
+		keywords:             ruleKeywords,
+		severityAdjustments:  severityAdjustments,
+	}
+}
+
+// SearchFiles will use the EarlybirdConfig, the provided file list, decompressed zip files and converted files temporary paths to send found secrets to the Hit channel.
+// When the context is cancelled or its deadline passes, the remaining work is dropped and cfg.ScanTruncated is set.
+func (e *Engine) SearchFiles(ctx context.Context, cfg *cfgReader.EarlybirdConfig, files []File, compressPaths []string, convertPaths []string, hits chan<- Hit) {
 	//Delete tmp file directory when we're done
 	defer DeleteFiles(compressPaths)
 	defer DeleteFiles(convertPaths)
+	batches := make(chan []File, 1)
+	batches <- files
+	close(batches)
+	e.SearchFileBatches(ctx, cfg, batches, hits)
+}
+
+// SearchFileBatches is SearchFiles for files produced while the scan runs, like the commits of a git history. Each batch
+// is scanned as it arrives, so the batches are never held in memory together. The scan ends once batches is closed,
+// batches sent after the context is done are dropped.
+func (e *Engine) SearchFileBatches(ctx context.Context, cfg *cfgReader.EarlybirdConfig, batches <-chan []File, hits chan<- Hit) {
 	defer close(hits)
+	e = e.withRuleProfile(ctx).withAcceptedRisks(cfg)
+	metrics.ScansStarted.Inc()
+	start := time.Now()
 
-	//Create our channels and mutex
-	var jobMutex = &sync.Mutex{}
-	jobs := make(chan WorkJob)
+	//The workers only report a failing hit, cfg is updated once they are done
+	failed := new(atomic.Bool)
+	var jobs chan WorkJob
 	wg := new(sync.WaitGroup)
+	if !cfg.ShardFiles {
+		//Create our channel and worker pool, the batches share it
+		jobs = make(chan WorkJob)
+		e.scanPool(ctx, cfg, wg, jobs, failed, hits)
+	}
+	for files := range batches {
+		//Keep receiving once cancelled, so the sender isn't left blocked
+		if ctx.Err() != nil {
+			continue
+		}
 
-	//Create our worker pool
-	scanPool(cfg, wg, jobMutex, jobs, hits)
+		//Scan the file names
+		e.nameScanner(ctx, cfg, files, failed, hits)
 
-	//Scan the file names
-	nameScanner(cfg, files, hits)
+		if cfg.ShardFiles {
+			//Every worker reads and scans whole files
+			e.fileSharder(ctx, cfg, files, failed, hits)
+		} else {
+			//Create work from file content for the scanPool
+			e.contentJobWriter(ctx, cfg, files, jobs)
+		}
+	}
+	if jobs != nil {
+		//Close our channel
+		close(jobs)
+		wg.Wait()
+	}
+	if failed.Load() {
+		cfg.FailScan = true
+	}
 
-	//Create work from file content for the scanPool
-	contentJobWriter(cfg, files, jobs)
+	//Work was skipped if we were cancelled, the report must not look complete
+	if ctx.Err() != nil {
+		cfg.ScanTruncated = true
+	}
+	recordScan(cfg, start)
+}
 
-	//Close our channels
-	close(jobs)
-	wg.Wait()
+// recordScan counts the finished scan and its duration
+func recordScan(cfg *cfgReader.EarlybirdConfig, start time.Time) {
+	result := "complete"
+	if cfg.ScanTruncated {
+		result = "truncated"
+	}
+	metrics.ScansFinished.WithLabelValues(result).Inc()
+	metrics.ScanDuration.Observe(time.Since(start).Seconds())
+}
+
+// recordHit counts a hit sent to the report
+func recordHit(hit Hit) {
+	metrics.Hits.WithLabelValues(strconv.Itoa(hit.Code), hit.Category, hit.Severity).Inc()
 }
 
 // scanPool searches incoming jobs for secrets and write findings to hits channel
-func scanPool(cfg *cfgReader.EarlybirdConfig, wg *sync.WaitGroup, jobMutex *sync.Mutex, jobs chan WorkJob, hits chan<- Hit) {
-	//Create duplicate map
-	dupeMap := make(map[string]bool) //HASH:true
+func (e *Engine) scanPool(ctx context.Context, cfg *cfgReader.EarlybirdConfig, wg *sync.WaitGroup, jobs <-chan WorkJob, failed *atomic.Bool, hits chan<- Hit) {
 	for w := 1; w <= cfg.WorkerCount; w++ {
 		wg.Add(1)
-		go func(w int) {
+		go func() {
+			defer wg.Done()
 			for j := range jobs {
-				if IsIgnoreAnnotation(cfg, j.WorkLine.LineValue) {
-					j.WorkLine.LineValue = ""
+				// Drain the remaining jobs without scanning them once cancelled
+				if ctx.Err() != nil {
+					continue
 				}
+				e.scanJob(ctx, cfg, j, failed, hits)
+			}
+		}()
+	}
+}
 
-				// Scan the line based on common password rules
-				hitFound, tmpHits := scanLine(j.WorkLine, j.FileLines, cfg)
-				if cfg.Suppress {
-					for i := range tmpHits {
-						tmpHits[i].MatchValue = maskValue(tmpHits[i].MatchValue)
-						tmpHits[i].LineValue = maskValue(tmpHits[i].LineValue)
-					}
-				}
-				if hitFound {
-					for _, hit := range tmpHits {
-						jobMutex.Lock() // put a mutex on it to avoid collisions/misses
-						if !hitUnique(dupeMap, hit) {
-							jobMutex.Unlock()
-							continue
-						}
-						jobMutex.Unlock()
-
-						if hit.ConfidenceID <= cfg.ConfidenceDisplayLevel {
-							hits <- hit //Push hits to channel
-						}
-
-						if !cfg.FailScan {
-							cfg.FailScan = determineScanFail(cfg, &hit)
-						}
-					}
-				}
+// scanJob scans the line of a job and sends the findings which are new, not suppressed and not baselined to the hits
+// channel, setting failed when one of them fails the scan
+func (e *Engine) scanJob(ctx context.Context, cfg *cfgReader.EarlybirdConfig, j WorkJob, failed *atomic.Bool, hits chan<- Hit) {
+	// Scan the line based on common password rules
+	var tmpHits []Hit
+	if !j.fileEnd {
+		_, tmpHits = e.scanLine(j.WorkLine, j.File, cfg)
+	}
+	// The hits of a streamed file wait for its last job, when its index is complete
+	streamed := j.File.streaming()
+	for _, hit := range j.File.hold(tmpHits) {
+		if streamed {
+			e.labelHeldHit(&hit, j.File)
+		}
+		if cfg.Suppress {
+			hit.MatchValue = maskValue(hit.MatchValue)
+			hit.LineValue = maskValue(hit.LineValue)
+		}
+
+		// Duplicates are tracked per file, so workers only contend when they scan lines of the same file
+		if !j.File.unique(hit) {
+			continue
+		}
+
+		// Hits under an ignore directive are only recorded, with the reason given for them
+		if directive, ok := j.File.suppressedBy(hit); ok {
+			recordSuppression(ctx, directive.suppression(hit))
+			continue
+		}
+		if !e.accepted.apply(ctx, &hit, cfg.SearchDir) {
+			continue
+		}
+
+		// Findings accepted in the baseline neither show up nor fail the scan
+		if e.isBaselined(hit) {
+			continue
+		}
+		displayed, fails := hit.ConfidenceID <= cfg.ConfidenceDisplayLevel, determineScanFail(cfg, &hit)
+		// Only the hits which are shown are worth sending over the network to verify
+		if displayed {
+			e.verify(&hit)
+		}
+		//A new baseline has to cover the hits which fail the scan without being shown too
+		if displayed || fails {
+			recordBaselineHit(ctx, hit)
+		}
+
+		if displayed {
+			//Push hits to channel, unless nobody is reading anymore
+			select {
+			case hits <- hit:
+				recordHit(hit)
+			case <-ctx.Done():
 			}
-			defer wg.Done()
-		}(w)
+		}
+
+		if fails {
+			failed.Store(true)
+		}
 	}
 }
 
@@ -125,78 +253,222 @@ func determineScanFail(cfg *cfgReader.EarlybirdConfig, hit *Hit) bool {
 	return hit.SeverityID <= cfg.SeverityFailLevel && hit.ConfidenceID <= cfg.ConfidenceFailLevel
 }
 
-// contentJobWriter creates work based off file content for scanning
-func contentJobWriter(cfg *cfgReader.EarlybirdConfig, files []File, jobs chan WorkJob) {
-	var e error
+// contentJobWriter creates work based off file content for scanning, with cfg.ReaderCount files read at the same time
+func (e *Engine) contentJobWriter(ctx context.Context, cfg *cfgReader.EarlybirdConfig, files []File, jobs chan<- WorkJob) {
+	e.readFiles(ctx, cfg, files, cfg.ReaderCount, func(job WorkJob) bool {
+		select {
+		case jobs <- job:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// fileSharder has each of the cfg.WorkerCount workers read and scan whole files, so the lines of a file never
+// leave the worker that read it
+func (e *Engine) fileSharder(ctx context.Context, cfg *cfgReader.EarlybirdConfig, files []File, failed *atomic.Bool, hits chan<- Hit) {
+	e.readFiles(ctx, cfg, files, cfg.WorkerCount, func(job WorkJob) bool {
+		e.scanJob(ctx, cfg, job, failed, hits)
+		return ctx.Err() == nil
+	})
+}
+
+// readFiles reads the files with the given number of readers, each file by a single reader which hands its jobs
+// to send in line order. Reading stops once the context is done or send returns false.
+func (e *Engine) readFiles(ctx context.Context, cfg *cfgReader.EarlybirdConfig, files []File, readers int, send func(WorkJob) bool) {
+	keys := e.multilineKeys()
+	queue := make(chan File)
+	wg := new(sync.WaitGroup)
+	for r := 0; r < max(readers, 1); r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for searchFile := range queue {
+				if !readFile(ctx, cfg, searchFile, keys, send) {
+					return
+				}
+			}
+		}()
+	}
+
 	// Loop through each File
+feed:
 	for _, searchFile := range files {
-		//FileOS refers to the file object that's open, not the file object which contains the name and path
-		if searchFile.Path == "buffer" || searchFile.Name == "buffer" {
-			for _, workline := range searchFile.Lines {
-				jobs <- WorkJob{
-					WorkLine:  workline,
-					FileLines: searchFile.Lines,
-				}
+		select {
+		case queue <- searchFile:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// readFile turns a file into jobs for send, returning false if the scan was cancelled
+func readFile(ctx context.Context, cfg *cfgReader.EarlybirdConfig, searchFile File, keys []labelKey, send func(WorkJob) bool) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	//FileOS refers to the file object that's open, not the file object which contains the name and path
+	if searchFile.Path == "buffer" || searchFile.Name == "buffer" {
+		file := indexLines(keys, cfg.AnnotationsToSkipLine, searchFile.Lines)
+		for _, workline := range searchFile.Lines {
+			metrics.BytesRead.Add(float64(len(workline.LineValue) + 1))
+			if !send(WorkJob{WorkLine: workline, File: file}) {
+				return false
 			}
-		} else {
-			//Don't do file read/scan on files we know will trigger the filename scan -- Don't open compressed files either
-			if !isExcludedFileType(cfg, searchFile.Name) && len(CompressPattern.FindStringSubmatch(searchFile.Name)) <= 0 {
-				fileInfo, err := os.Lstat(searchFile.Path)
-				if err == nil && fileInfo != nil && fileInfo.Mode()&fs.ModeSymlink != 0 {
-					continue
-				}
+		}
+	} else {
+		//Don't do file read/scan on files we know will trigger the filename scan -- Don't open compressed files either
+		if !isExcludedFileType(cfg, searchFile.Name) && len(CompressPattern.FindStringSubmatch(searchFile.Name)) <= 0 {
+			fileInfo, err := os.Lstat(searchFile.Path)
+			if err == nil && fileInfo != nil && fileInfo.Mode()&fs.ModeSymlink != 0 {
+				fileDone(ctx)
+				return true
+			}
+			ok, err := streamFile(ctx, cfg, searchFile, keys, send)
+			if err != nil {
+				//A file we can't read is reported as skipped rather than ending the scan
+				log.Println("Can't open file", err)
+				recordSkip(ctx, searchFile.Path)
+				fileDone(ctx)
+				return true
+			}
+			if !ok {
+				return false
+			}
+		}
+	}
+	metrics.FilesScanned.Inc()
+	fileDone(ctx)
+	return true
+}
 
-				fileOS, err := os.Open(searchFile.Path) //Open file path
-				if err != nil {
-					fileOS, err = os.Open(searchFile.Name) //If file path open fails, try file name
-					if err != nil {
-						log.Fatal("Can't open file", err)
-					}
-				}
-				defer fileOS.Close()
-
-				var work []WorkJob
-				var job WorkJob
-				job.FileLines = searchFile.Lines
-
-				//Search line by line
-				reader := bufio.NewReader(fileOS)
-				job.WorkLine.LineValue, e = readln(reader)
-				for e == nil {
-					job.WorkLine.LineNum = job.WorkLine.LineNum + 1
-					job.WorkLine.FileName = jobFileName(cfg.Gitrepo, searchFile.Name)
-					job.WorkLine.FilePath = searchFile.Path
-					job.FileLines = append(job.FileLines, job.WorkLine)
-
-					//Add our split up jobs to the work array
-					work = append(work, splitJob(job, cfg.WorkLength)...)
-					//Search next line to break out of loop
-					job.WorkLine.LineValue, e = readln(reader)
-					if e != nil && e != io.EOF {
-						log.Println("Error reading file:", e)
-					}
-				}
-				//Push our work to the jobs channel
-				for _, job := range work {
-					jobs <- job
-				}
+// streamFile hands the lines of a file to send as they are read, returning false if the scan was cancelled.
+// The file is read once: each line is indexed as it is sent, and the end of file job tells the workers the index is
+// complete. Only the index and the hits are kept, so memory doesn't grow with the file.
+func streamFile(ctx context.Context, cfg *cfgReader.EarlybirdConfig, searchFile File, keys []labelKey, send func(WorkJob) bool) (bool, error) {
+	fileOS, err := os.Open(searchFile.Path) //Open file path
+	if err != nil {
+		fileOS, err = os.Open(searchFile.Name) //If file path open fails, try file name
+		if err != nil {
+			return true, err
+		}
+	}
+	defer fileOS.Close()
+
+	//Index the file while sending its lines, counting what we read
+	file := newStreamedIndex()
+	indexer := newFileIndexer(keys, cfg.AnnotationsToSkipLine)
+	counter := &countingReader{r: fileOS}
+	fileName := jobFileName(cfg.Gitrepo, searchFile.Name)
+	read := readLines(bufio.NewReader(counter), func(line Line) bool {
+		indexer.add(line)
+		line.FileName = fileName
+		line.FilePath = searchFile.Path
+		//Hand over our split up jobs
+		for _, job := range splitJob(WorkJob{WorkLine: line, File: file}, cfg.WorkLength) {
+			file.sent()
+			if !send(job) {
+				return false
 			}
 		}
+		return true
+	})
+	metrics.BytesRead.Add(float64(counter.n))
+	if !read {
+		return false, nil
+	}
+	file.read(indexer)
+	return send(WorkJob{File: file, fileEnd: true}), nil
+}
+
+// readLines calls fn with each numbered line of the reader until it returns false, reporting whether all lines were read
+func readLines(reader *bufio.Reader, fn func(Line) bool) bool {
+	var line Line
+	value, err := readln(reader)
+	for err == nil {
+		line.LineNum++
+		line.LineValue = value
+		if !fn(line) {
+			return false
+		}
+		//Search next line to break out of loop
+		value, err = readln(reader)
+		if err != nil && err != io.EOF {
+			log.Println("Error reading file:", err)
+		}
+	}
+	return true
+}
+
+// Read counts the bytes read from the underlying reader
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// WithProgress returns a context which makes SearchFiles count the files it has handed to the workers in p
+func WithProgress(ctx context.Context, p *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// WithSkipLog returns a context which makes SearchFiles record the files it couldn't read in log
+func WithSkipLog(ctx context.Context, log *SkipLog) context.Context {
+	return context.WithValue(ctx, skipKey{}, log)
+}
+
+// recordSkip adds the path to the skip log of the scan, if the caller asked for one
+func recordSkip(ctx context.Context, path string) {
+	log, ok := ctx.Value(skipKey{}).(*SkipLog)
+	if !ok || log == nil {
+		return
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.paths = append(log.paths, path)
+}
+
+// Paths returns the paths of the files skipped so far
+func (l *SkipLog) Paths() []string {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.paths...)
+}
+
+// fileDone counts a file towards the progress of the scan, if the caller asked for it
+func fileDone(ctx context.Context) {
+	if p, ok := ctx.Value(progressKey{}).(*Progress); ok {
+		p.FilesDone.Add(1)
 	}
 }
 
 // nameScanner scans file names for sensitive values
-func nameScanner(cfg *cfgReader.EarlybirdConfig, files []File, hits chan<- Hit) {
+func (e *Engine) nameScanner(ctx context.Context, cfg *cfgReader.EarlybirdConfig, files []File, failed *atomic.Bool, hits chan<- Hit) {
 	for _, file := range files {
+		if ctx.Err() != nil {
+			return
+		}
 		// Scan the filename based on the Filename rules
-		hitFound, hit := scanName(file, CombinedRules, cfg)
-		if hitFound {
-
-			hits <- hit //push hit to channel
+		hitFound, hit := e.scanName(file, cfg)
+		if hitFound && e.accepted.apply(ctx, &hit, cfg.SearchDir) && !e.isBaselined(hit) {
+			recordBaselineHit(ctx, hit)
+			//push hit to channel
+			select {
+			case hits <- hit:
+				recordHit(hit)
+			case <-ctx.Done():
+				return
+			}
 
 			// If a hit severity is less than the failLevel and a hit confidence is less than the failLevel, set failScan = true
 			if cfg.LevelMap[hit.Severity] <= cfg.SeverityFailLevel && cfg.LevelMap[hit.Confidence] <= cfg.ConfidenceFailLevel {
-				cfg.FailScan = true
+				failed.Store(true)
 			}
 		}
 	}
@@ -231,7 +503,7 @@ func isExcludedFileType(cfg *cfgReader.EarlybirdConfig, filename string) (exclud
 
 func hitUnique(dupeMap map[string]bool, hit Hit) bool {
 	digest := sha1.New()
-	_, err := digest.Write([]byte(hit.Filename + strconv.Itoa(hit.Line) + hit.MatchValue))
+	_, err := digest.Write([]byte(hit.Filename + strconv.Itoa(hit.Line) + hit.MatchValue + hit.Commit))
 	if err != nil {
 		log.Println("Failed to produce digest of hit", err)
 	}
@@ -246,15 +518,32 @@ func hitUnique(dupeMap map[string]bool, hit Hit) bool {
 }
 
 // Take a line and run through the rules, looking for a hit
-func scanLine(line Line, fileLines []Line, cfg *cfgReader.EarlybirdConfig) (isHit bool, hits []Hit) {
-	for _, rule := range CombinedRules {
+func (e *Engine) scanLine(line Line, file *fileIndex, cfg *cfgReader.EarlybirdConfig) (isHit bool, hits []Hit) {
+	var keywordFound []bool
+	//The matcher only applies to the rules it was built from
+	if e.keywords != nil && e.keywords.count == len(e.Rules) {
+		keywordFound = e.keywords.match(line.LineValue)
+	}
+	for i, rule := range e.Rules {
 		var hit Hit
 		//Skip rules that do not apply
 		if rule.Searcharea == "filename" || cfg.SkipComments && rule.Category == "comment" {
 			continue
 		}
+		//Skip rules whose keywords are not in the line
+		if len(rule.Keywords) > 0 && keywordFound != nil && !keywordFound[i] {
+			continue
+		}
 
-		patternMatch, matchValue := findHit(line.LineValue, rule.CompiledPattern)
+		var patternMatch bool
+		var matchValue string
+		if e.timed() {
+			start := time.Now()
+			patternMatch, matchValue = findHit(line.LineValue, rule.CompiledPattern)
+			e.recordEvaluation(i, time.Since(start), patternMatch)
+		} else {
+			patternMatch, matchValue = findHit(line.LineValue, rule.CompiledPattern)
+		}
 
 		if !patternMatch {
 			continue
@@ -267,7 +556,7 @@ func scanLine(line Line, fileLines []Line, cfg *cfgReader.EarlybirdConfig) (isHi
 		hit.Caption = rule.Caption
 		hit.Category = rule.Category
 		if cfg.ShowSolutions {
-			hit.Solution = SolutionConfigs[rule.SolutionID].Text
+			hit.Solution = e.SolutionConfigs[rule.SolutionID].Text
 		}
 		hit.CWE = rule.CWE
 		hit.Line = line.LineNum
@@ -279,13 +568,21 @@ func scanLine(line Line, fileLines []Line, cfg *cfgReader.EarlybirdConfig) (isHi
 			hit.Filename = line.FileName
 		}
 		hit.Time = time.Now().UTC().Format(time.RFC3339)
-		hit.determineSeverity(cfg, &rule)
+		hit.Fingerprint = Fingerprint(hit, cfg.SearchDir)
+		hit.setCommit(line.Commit)
+		e.determineSeverity(&hit, cfg, &rule)
 
-		// Apply labels to the hit if appropriate
-		labelHit(&hit, fileLines)
+		// Apply labels to the hit if appropriate, a streamed file is only labelled once its index is complete
+		if !file.streaming() {
+			e.labelHit(&hit, file)
+		}
+		if line.Removed {
+			hit.Labels = append(hit.Labels, removedLineLabel)
+		}
 
 		//Check if our hit has any false positives
-		isStillHit := hit.postProcess(cfg, &rule)
+		isStillHit, isFalsePositive := hit.postProcess(cfg, &rule, e.FalsePositiveRules)
+		e.recordOutcome(i, isStillHit, isFalsePositive)
 		if isStillHit {
 			isHit = true
 			hits = append(hits, hit)
@@ -295,17 +592,42 @@ func scanLine(line Line, fileLines []Line, cfg *cfgReader.EarlybirdConfig) (isHi
 	return isHit, hits
 }
 
+// verify tries the credential of the hit against its provider, when verification is enabled and its rule names a verifier
+func (e *Engine) verify(hit *Hit) {
+	if e.Verifier == nil {
+		return
+	}
+	for _, rule := range e.Rules {
+		if rule.Code == hit.Code && rule.Verifier != "" {
+			hit.Verified = e.Verifier.Check(verify.Finding{
+				Code:       hit.Code,
+				Verifier:   rule.Verifier,
+				MatchValue: hit.MatchValue,
+				LineValue:  hit.LineValue,
+			})
+			return
+		}
+	}
+}
+
 // Take a filename and run through the rules, looking for a hit
-func scanName(file File, rules []Rule, cfg *cfgReader.EarlybirdConfig) (isHit bool, hit Hit) {
+func (e *Engine) scanName(file File, cfg *cfgReader.EarlybirdConfig) (isHit bool, hit Hit) {
 	if file.Path == "buffer" {
 		file.Path = file.Name
 	}
-	for _, rule := range rules {
+	for i, rule := range e.Rules {
 		if rule.Searcharea == "body" { //Skip rules that do not apply
 			continue
 		}
 
-		patternMatch, _ := findHit(file.Path, rule.CompiledPattern)
+		var patternMatch bool
+		if e.timed() {
+			start := time.Now()
+			patternMatch, _ = findHit(file.Path, rule.CompiledPattern)
+			e.recordEvaluation(i, time.Since(start), patternMatch)
+		} else {
+			patternMatch, _ = findHit(file.Path, rule.CompiledPattern)
+		}
 
 		// If we found a match to the Regexp pattern, build a Hit
 		if patternMatch {
@@ -318,21 +640,24 @@ func scanName(file File, rules []Rule, cfg *cfgReader.EarlybirdConfig) (isHit bo
 			hit.Confidence = getLevelNameFromID(rule.Confidence, cfg.LevelMap)
 			hit.ConfidenceID = rule.Confidence
 			if cfg.ShowSolutions {
-				hit.Solution = SolutionConfigs[rule.SolutionID].Text
+				hit.Solution = e.SolutionConfigs[rule.SolutionID].Text
 			}
 			hit.Line = 0
 			hit.Filename = file.Path
 			hit.MatchValue = file.Name
 			hit.LineValue = file.Name
 			hit.Time = time.Now().UTC().Format(time.RFC3339)
+			hit.Fingerprint = Fingerprint(hit, cfg.SearchDir)
+			hit.setCommit(file.Commit)
 
 			// Check if the severity needs to be adjusted based on filepath
-			hit.determineSeverity(cfg, &rule)
+			e.determineSeverity(&hit, cfg, &rule)
 
 			// Check if the hit has any false positives
-			fpHit := findFalsePositive(hit)
+			fpHit := findFalsePositive(hit, e.FalsePositiveRules)
 
 			isStillHit := hit.filePostProcess(cfg, &rule, file)
+			e.recordOutcome(i, !fpHit && isStillHit, fpHit)
 			if fpHit || !isStillHit {
 				return false, hit
 			}
@@ -397,8 +722,10 @@ func splitJob(inJob WorkJob, worklength int) (work []WorkJob) {
 				FileName:  inJob.WorkLine.FileName,
 				FilePath:  inJob.WorkLine.FilePath,
 				LineValue: value,
+				Commit:    inJob.WorkLine.Commit,
+				Removed:   inJob.WorkLine.Removed,
 			},
-			FileLines: inJob.FileLines,
+			File: inJob.File,
 		}
 		work = append(work, outJob)
 	}
@@ -450,9 +777,17 @@ func splitSubN(s string, n int) []string {
 	return results
 }
 
+// labelHeldHit labels a hit held until its file was read, ahead of the labels it was given when scanned
+func (e *Engine) labelHeldHit(hit *Hit, file *fileIndex) {
+	labels := hit.Labels
+	hit.Labels = nil
+	e.labelHit(hit, file)
+	hit.Labels = append(hit.Labels, labels...)
+}
+
 // From the configs in labels.json, apply labels to each hit as appropriate
-func labelHit(hit *Hit, fileLines []Line) {
-	rules, ok := Labels[hit.Code]
+func (e *Engine) labelHit(hit *Hit, file *fileIndex) {
+	rules, ok := e.Labels[hit.Code]
 	if !ok {
 		return
 	}
@@ -479,7 +814,7 @@ func labelHit(hit *Hit, fileLines []Line) {
 
 		criteriaMatched := 0
 		for _, key := range rule.Keys {
-			if substringExistsInLines(fileLines, key) {
+			if file.hasKey(key) {
 				criteriaMatched++
 			}
 		}
@@ -490,9 +825,10 @@ func labelHit(hit *Hit, fileLines []Line) {
 	}
 }
 
-func (hit *Hit) determineSeverity(cfg *cfgReader.EarlybirdConfig, rule *Rule) {
+// determineSeverity sets the severity of the hit, the one of its rule unless an adjusted severity category of the engine matches
+func (e *Engine) determineSeverity(hit *Hit, cfg *cfgReader.EarlybirdConfig, rule *Rule) {
 	// check if for the given category we need to adjust the severity based on user config
-	for _, adjustedSeverityCategoryCfg := range cfg.AdjustedSeverityCategories {
+	for _, adjustedSeverityCategoryCfg := range e.severityAdjustments {
 		if adjustedSeverityCategoryCfg.Category == rule.Category {
 			for _, p := range adjustedSeverityCategoryCfg.CompiledPatterns {
 				var test string
@@ -551,13 +887,14 @@ func (hit *Hit) filePostProcess(cfg *cfgReader.EarlybirdConfig, rule *Rule, file
 	return isHit
 }
 
-func (hit *Hit) postProcess(cfg *cfgReader.EarlybirdConfig, rule *Rule) (isHit bool) {
-	fpHit := false
+// postProcess checks the hit against the false positive rules and the post-processing of its rule, isFalsePositive
+// tells whether a false positive rule removed it
+func (hit *Hit) postProcess(cfg *cfgReader.EarlybirdConfig, rule *Rule, falsePositiveRules map[int]FalsePositives) (isHit, isFalsePositive bool) {
 	if !cfg.IgnoreFPRules {
-		fpHit = findFalsePositive(*hit)
+		isFalsePositive = findFalsePositive(*hit, falsePositiveRules)
 	}
 	switch {
-	case fpHit:
+	case isFalsePositive:
 		isHit = false
 		// Check if a password is valid and weak.  Exclude if invalid, label as 'weak' if weak
 	case rule.Postprocess == "password":
@@ -643,10 +980,22 @@ func (hit *Hit) postProcess(cfg *cfgReader.EarlybirdConfig, rule *Rule) (isHit b
 			isHit = false
 			break
 		}
+		isHit = true
 	default:
 		isHit = true
 	}
-	return isHit
+	return isHit, isFalsePositive
+}
+
+// setCommit records which commit introduced the hit
+func (hit *Hit) setCommit(commit *Commit) {
+	if commit == nil {
+		return
+	}
+	hit.Commit = commit.Hash
+	hit.Author = commit.Author
+	hit.AuthorEmail = commit.Email
+	hit.CommitDate = commit.Date
 }
 
 // removeTempPrefix removes the temp path prefix if it exists
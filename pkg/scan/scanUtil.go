@@ -35,17 +35,6 @@ func findHit(target string, CompiledPattern *regexp.Regexp) (isHit bool, retMatc
 	return false, ""
 }
 
-// substringExistsInLines Search for a regexp pattern occurring anywhere in a file
-func substringExistsInLines(fileLines []Line, str string) bool {
-	reg := regexp.MustCompile("(?i)" + str)
-	for _, line := range fileLines {
-		if reg.MatchString(line.LineValue) {
-			return true
-		}
-	}
-	return false
-}
-
 // substringExistsInString check if sub exists in string
 func substringExistsInString(str string, substr string) bool {
 	m := search.New(language.English, search.IgnoreCase)
@@ -90,9 +79,9 @@ func shouldStrip(b byte) bool {
 }
 
 // Verify that a hit is a false positive
-func findFalsePositive(hit Hit) (isFP bool) {
+func findFalsePositive(hit Hit, falsePositiveRules map[int]FalsePositives) (isFP bool) {
 	var scan bool
-	if rules, ok := FalsePositiveRules[hit.Code]; ok { //Veriy a false positive rule exists for this hit code
+	if rules, ok := falsePositiveRules[hit.Code]; ok { //Veriy a false positive rule exists for this hit code
 		for _, rule := range rules.FalsePositives {
 			scan = true
 			if len(rule.FileExtensions) > 0 { //Check if this rule only applies to certain files
@@ -179,3 +168,21 @@ func getFileURL(giturl, filepath string) (fileurl string) {
 	fileurl = filepath + ":" + fileurl
 	return fileurl
 }
+
+// HitCommits lists the distinct commits that introduced the hits, in the order they were first found
+func HitCommits(hits []Hit) (commits []Commit) {
+	seen := make(map[string]bool)
+	for _, hit := range hits {
+		if hit.Commit == "" || seen[hit.Commit] {
+			continue
+		}
+		seen[hit.Commit] = true
+		commits = append(commits, Commit{
+			Hash:   hit.Commit,
+			Author: hit.Author,
+			Email:  hit.AuthorEmail,
+			Date:   hit.CommitDate,
+		})
+	}
+	return commits
+}
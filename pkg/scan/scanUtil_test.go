@@ -55,38 +55,6 @@ func Test_findHit(t *testing.T) {
 	}
 }
 
-func Test_substringExistsInLines(t *testing.T) {
-	type args struct {
-		fileLines []Line
-		str       string
-	}
-	tests := []struct {
-		name string
-		args args
-		want bool
-	}{
-		{
-			name: "Find secret word hideme in lines",
-			args: args{
-				fileLines: []Line{
-					{LineValue: "Nothing to see here"},
-					{LineValue: "Not sure what you're looking hideme for"},
-					{LineValue: "This line shouldn't be searched"},
-				},
-				str: "hideme",
-			},
-			want: true,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := substringExistsInLines(tt.args.fileLines, tt.args.str); got != tt.want {
-				t.Errorf("substringExistsInLines() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func Test_substringExistsInString(t *testing.T) {
 	type args struct {
 		str    string
@@ -271,3 +239,16 @@ func Test_getFileURL(t *testing.T) {
 		})
 	}
 }
+
+func TestHitCommits(t *testing.T) {
+	hits := []Hit{
+		{Code: 3001, Commit: "abc", Author: "Foo Bar", AuthorEmail: "foo@bar.com", CommitDate: "2020-01-17T18:34:35Z"},
+		{Code: 3002, Commit: "abc", Author: "Foo Bar", AuthorEmail: "foo@bar.com", CommitDate: "2020-01-17T18:34:35Z"},
+		{Code: 3003},
+		{Code: 3001, Commit: "def", Author: "Jane Doe"},
+	}
+	got := HitCommits(hits)
+	if len(got) != 2 || got[0].Hash != "abc" || got[0].Email != "foo@bar.com" || got[1].Hash != "def" {
+		t.Errorf("HitCommits() = %+v, want commits abc and def", got)
+	}
+}
@@ -17,14 +17,23 @@
 package scan
 
 import (
+	"context"
 	"bufio"
 	"crypto/sha1"
 	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/metrics"
 	"github.com/americanexpress/earlybird/v4/pkg/utils"
+	"github.com/americanexpress/earlybird/v4/pkg/verify"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"os"
 	"path"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 )
 
@@ -111,7 +120,7 @@ func TestScanFiles(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			go SearchFiles(tt.args.cfg, tt.args.files, tt.args.compressPaths, tt.args.convertPaths, hits)
+			go SearchFiles(context.Background(), tt.args.cfg, tt.args.files, tt.args.compressPaths, tt.args.convertPaths, hits)
 			for i := range hits {
 				if i.Code != tt.args.wantCode {
 					t.Errorf("ScanFiles() found code %v, want code %v", i.Code, tt.args.wantCode)
@@ -210,11 +219,9 @@ func Test_hitUnique(t *testing.T) {
 }
 
 func Test_scanLine(t *testing.T) {
-	var fileLines []Line
 	type args struct {
-		line      Line
-		fileLines []Line
-		rules     []Rule
+		line  Line
+		rules []Rule
 	}
 	tests := []struct {
 		name      string
@@ -227,7 +234,6 @@ func Test_scanLine(t *testing.T) {
 				line: Line{
 					LineValue: "password=TrueFinding7842!",
 				},
-				fileLines: fileLines,
 			},
 			wantIsHit: true,
 		},
@@ -237,7 +243,6 @@ func Test_scanLine(t *testing.T) {
 				line: Line{
 					LineValue: "password = fall123",
 				},
-				fileLines: fileLines,
 			},
 			wantIsHit: true,
 		},
@@ -247,7 +252,6 @@ func Test_scanLine(t *testing.T) {
 				line: Line{
 					LineValue: "using fall in a general sentence should not error",
 				},
-				fileLines: fileLines,
 			},
 			wantIsHit: false,
 		},
@@ -257,7 +261,6 @@ func Test_scanLine(t *testing.T) {
 				line: Line{
 					LineValue: `twitterApiSecret:"111aAa222bBb333cCc444dDd555eEe666fFf777"`,
 				},
-				fileLines: fileLines,
 			},
 			wantIsHit: true,
 		},
@@ -267,14 +270,13 @@ func Test_scanLine(t *testing.T) {
 				line: Line{
 					LineValue: `twitter="twitter";//This LineValue emulates extremely long one-liner code files that can cause false positives "111aAa222bBb333cCc444dDd555eEe666fFf777"`,
 				},
-				fileLines: fileLines,
 			},
 			wantIsHit: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotIsHit, _ := scanLine(tt.args.line, tt.args.fileLines, &cfg)
+			gotIsHit, _ := globalEngine().scanLine(tt.args.line, nil, &cfg)
 			if gotIsHit != tt.wantIsHit {
 				t.Errorf("scanLine() gotIsHit = %v, want %v", gotIsHit, tt.wantIsHit)
 			}
@@ -283,6 +285,10 @@ func Test_scanLine(t *testing.T) {
 }
 
 func Test_scanName(t *testing.T) {
+	filenameRules, problems := loadRuleConfigs(cfg, "filename", "filename.yaml")
+	if len(problems) > 0 {
+		t.Fatal(problems)
+	}
 	type args struct {
 		file  File
 		rules []Rule
@@ -296,7 +302,7 @@ func Test_scanName(t *testing.T) {
 		{
 			name: "Check if keyfile matches",
 			args: args{
-				rules: loadRuleConfigs(cfg, "filename", "filename.yaml"),
+				rules: filenameRules,
 				file: File{
 					Name: "findme.pem",
 					Path: "/bad/findme.pem",
@@ -307,7 +313,7 @@ func Test_scanName(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotIsHit, gotHit := scanName(tt.args.file, tt.args.rules, &cfg)
+			gotIsHit, gotHit := (&Engine{Rules: tt.args.rules}).scanName(tt.args.file, &cfg)
 
 			if gotIsHit != tt.wantIsHit {
 				t.Errorf("scanName() gotIsHit = %v, want %v", gotIsHit, tt.wantIsHit)
@@ -510,7 +516,7 @@ func Test_labelHit(t *testing.T) {
 		Filename:   "sample.py",
 		MatchValue: "tomcat_password = '123'",
 	}
-	labelHit(hit, []Line{})
+	globalEngine().labelHit(hit, nil)
 	if len(hit.Labels) != 0 {
 		t.Errorf("LabelHit() = [], failed to label hit")
 	}
@@ -588,7 +594,7 @@ func Test_determineSeverity(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.hit.determineSeverity(&cfg, &rule)
+			globalEngine().determineSeverity(tt.hit, &cfg, &rule)
 
 			if tt.hit.SeverityID != tt.expectedSeverityId && tt.hit.Severity != tt.expectedSeverity {
 				t.Errorf("hit.Severity = %s, want %s, hit.SeverityId = %d, want %d", tt.hit.Severity, tt.expectedSeverity, tt.hit.SeverityID, tt.expectedSeverityId)
@@ -699,3 +705,454 @@ func Test_determineScanFail(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchFiles_cancelled(t *testing.T) {
+	var lines []Line
+	for i := 1; i <= 1000; i++ {
+		lines = append(lines, Line{LineNum: i, LineValue: `password = "SecretValue1673"`, FileName: "file.py", FilePath: "buffer"})
+	}
+	files := []File{{Name: "file.py", Path: "buffer", Lines: lines}}
+	scanCfg := cfg
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hits := make(chan Hit)
+	go SearchFiles(ctx, &scanCfg, files, nil, nil, hits)
+	// Nobody reads the hits while the scan is cancelled, it must still finish and close the channel
+	for range hits {
+	}
+	if !scanCfg.ScanTruncated {
+		t.Errorf("SearchFiles() did not mark the cancelled scan as truncated")
+	}
+
+	scanCfg = cfg
+	hits = make(chan Hit)
+	go SearchFiles(context.Background(), &scanCfg, files[:0], nil, nil, hits)
+	for range hits {
+	}
+	if scanCfg.ScanTruncated {
+		t.Errorf("SearchFiles() marked a complete scan as truncated")
+	}
+}
+
+// writeCorpus generates a tree of files from the synthetic corpus, spread over a few directories
+func writeCorpus(tb testing.TB, fileCount, lineCount int) (files []File, size int64) {
+	dir := tb.TempDir()
+	var content strings.Builder
+	for _, line := range syntheticCorpus(lineCount) {
+		content.WriteString(line.LineValue + "\n")
+	}
+	for i := 0; i < fileCount; i++ {
+		name := filepath.Join(dir, "pkg"+strconv.Itoa(i%10), "file"+strconv.Itoa(i)+".go")
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content.String()), 0666); err != nil {
+			tb.Fatal(err)
+		}
+		files = append(files, File{Name: name, Path: name})
+		size += int64(content.Len())
+	}
+	return files, size
+}
+
+func TestSearchFiles_readers(t *testing.T) {
+	files, _ := writeCorpus(t, 12, 80)
+	tests := []struct {
+		name    string
+		readers int
+		shard   bool
+	}{
+		{name: "One reader", readers: 1},
+		{name: "Parallel readers", readers: 4},
+		{name: "Unset readers", readers: 0},
+		{name: "Sharded files", shard: true},
+	}
+	// Every mode reports each hit exactly once
+	var want []string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanCfg := cfg
+			scanCfg.ReaderCount = tt.readers
+			scanCfg.ShardFiles = tt.shard
+			progress := &Progress{}
+			hits := make(chan Hit)
+			go SearchFiles(WithProgress(context.Background(), progress), &scanCfg, files, nil, nil, hits)
+			var found []string
+			for hit := range hits {
+				found = append(found, hit.Filename+":"+strconv.Itoa(hit.Line)+":"+strconv.Itoa(hit.Code))
+			}
+			sort.Strings(found)
+			if len(found) == 0 {
+				t.Fatal("SearchFiles() found no hits")
+			}
+			if want == nil {
+				want = found
+			} else if !reflect.DeepEqual(found, want) {
+				t.Errorf("SearchFiles() found %d hits, want the %d hits of a single reader", len(found), len(want))
+			}
+			if got := progress.FilesDone.Load(); got != int64(len(files)) {
+				t.Errorf("SearchFiles() counted %d files done, want %d", got, len(files))
+			}
+		})
+	}
+
+	t.Run("Cancelled sharded scan", func(t *testing.T) {
+		scanCfg := cfg
+		scanCfg.ShardFiles = true
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		hits := make(chan Hit)
+		go SearchFiles(ctx, &scanCfg, files, nil, nil, hits)
+		for range hits {
+		}
+		if !scanCfg.ScanTruncated {
+			t.Errorf("SearchFiles() did not mark the cancelled scan as truncated")
+		}
+	})
+}
+
+func TestSearchFileBatches(t *testing.T) {
+	files, _ := writeCorpus(t, 12, 80)
+
+	// Scanning the files in batches reports the hits of scanning them at once
+	var want []string
+	for _, batchSize := range []int{len(files), 1, 5} {
+		t.Run("Batches of "+strconv.Itoa(batchSize), func(t *testing.T) {
+			scanCfg := cfg
+			batches := make(chan []File)
+			go func() {
+				defer close(batches)
+				for i := 0; i < len(files); i += batchSize {
+					batches <- files[i:min(i+batchSize, len(files))]
+				}
+			}()
+			hits := make(chan Hit)
+			go SearchFileBatches(context.Background(), &scanCfg, batches, hits)
+			var found []string
+			for hit := range hits {
+				found = append(found, hit.Filename+":"+strconv.Itoa(hit.Line)+":"+strconv.Itoa(hit.Code))
+			}
+			sort.Strings(found)
+			if len(found) == 0 || !scanCfg.FailScan {
+				t.Fatalf("SearchFileBatches() found %d hits, failed %v, want failing hits", len(found), scanCfg.FailScan)
+			}
+			if want == nil {
+				want = found
+			} else if !reflect.DeepEqual(found, want) {
+				t.Errorf("SearchFileBatches() found %d hits, want the %d hits of a single batch", len(found), len(want))
+			}
+		})
+	}
+}
+
+func BenchmarkSearchFiles(b *testing.B) {
+	files, size := writeCorpus(b, 100, 400)
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, bench := range []struct {
+		name    string
+		readers int
+		shard   bool
+	}{
+		{name: "1 reader", readers: 1},
+		{name: "4 readers", readers: 4},
+		{name: "16 readers", readers: 16},
+		{name: "sharded files", shard: true},
+	} {
+		b.Run(strings.ReplaceAll(bench.name, " ", "_"), func(b *testing.B) {
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				scanCfg := cfg
+				scanCfg.ReaderCount = bench.readers
+				scanCfg.ShardFiles = bench.shard
+				hits := make(chan Hit)
+				go engine.SearchFiles(context.Background(), &scanCfg, files, nil, nil, hits)
+				for range hits {
+				}
+			}
+		})
+	}
+}
+
+func TestWithProgress(t *testing.T) {
+	var files []File
+	for i := 1; i <= 3; i++ {
+		files = append(files, File{Name: "file.py", Path: "buffer", Lines: []Line{{LineNum: 1, LineValue: "host = 'localhost'", FileName: "file.py", FilePath: "buffer"}}})
+	}
+	scanCfg := cfg
+	progress := &Progress{}
+	hits := make(chan Hit)
+	go SearchFiles(WithProgress(context.Background(), progress), &scanCfg, files, nil, nil, hits)
+	for range hits {
+	}
+	if got := progress.FilesDone.Load(); got != int64(len(files)) {
+		t.Errorf("SearchFiles() counted %d files done, want %d", got, len(files))
+	}
+}
+
+func TestEngine_SearchFiles(t *testing.T) {
+	files := []File{
+		{
+			Name: "file.py",
+			Path: "buffer",
+			Lines: []Line{
+				{LineValue: `password = "SecretValue1673"`, LineNum: 1, FileName: "file.py", FilePath: "buffer"},
+			},
+		},
+	}
+
+	// Engines with different modules scan side by side without sharing rules
+	tests := []struct {
+		name     string
+		modules  map[string]string
+		wantHits bool
+	}{
+		{name: "Password rules", modules: map[string]string{"password-secret": "password-secret.yaml"}, wantHits: true},
+		{name: "Content rules only", modules: map[string]string{"content": "content.yaml"}, wantHits: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			engineCfg := cfg
+			engineCfg.EnabledModulesMap = tt.modules
+			engineCfg.AdjustedSeverityCategories = nil
+			engine, err := NewEngine(engineCfg)
+			if err != nil {
+				t.Fatalf("NewEngine() error = %v", err)
+			}
+
+			hits := make(chan Hit)
+			go engine.SearchFiles(context.Background(), &engineCfg, files, nil, nil, hits)
+			var found []Hit
+			for hit := range hits {
+				found = append(found, hit)
+			}
+			if (len(found) > 0) != tt.wantHits {
+				t.Errorf("Engine.SearchFiles() found %v, want hits %v", found, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestEngine_SearchFiles_metrics(t *testing.T) {
+	line := `password = "SecretValue1673"`
+	files := []File{{Name: "file.py", Path: "buffer", Lines: []Line{{LineValue: line, LineNum: 1, FileName: "file.py", FilePath: "buffer"}}}}
+	engineCfg := cfg
+	engineCfg.EnabledModulesMap = map[string]string{"password-secret": "password-secret.yaml"}
+	engineCfg.AdjustedSeverityCategories = nil
+	engine, err := NewEngine(engineCfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	metrics.Enable()
+
+	started, complete := testutil.ToFloat64(metrics.ScansStarted), testutil.ToFloat64(metrics.ScansFinished.WithLabelValues("complete"))
+	filesScanned, bytesRead := testutil.ToFloat64(metrics.FilesScanned), testutil.ToFloat64(metrics.BytesRead)
+	hits := make(chan Hit)
+	go engine.SearchFiles(context.Background(), &engineCfg, files, nil, nil, hits)
+	var found []Hit
+	for hit := range hits {
+		found = append(found, hit)
+	}
+	if len(found) == 0 {
+		t.Fatal("Engine.SearchFiles() found no hits")
+	}
+
+	if got := testutil.ToFloat64(metrics.ScansStarted) - started; got != 1 {
+		t.Errorf("scans started grew by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.ScansFinished.WithLabelValues("complete")) - complete; got != 1 {
+		t.Errorf("complete scans grew by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.FilesScanned) - filesScanned; got != 1 {
+		t.Errorf("files scanned grew by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.BytesRead) - bytesRead; got != float64(len(line)+1) {
+		t.Errorf("bytes read grew by %v, want %d", got, len(line)+1)
+	}
+	hit := found[0]
+	if got := testutil.ToFloat64(metrics.Hits.WithLabelValues(strconv.Itoa(hit.Code), hit.Category, hit.Severity)); got < 1 {
+		t.Errorf("hits of rule %d = %v, want at least 1", hit.Code, got)
+	}
+	if got := testutil.ToFloat64(metrics.RuleEvaluations.WithLabelValues(strconv.Itoa(hit.Code))); got < 1 {
+		t.Errorf("evaluations of rule %d = %v, want at least 1", hit.Code, got)
+	}
+}
+
+// namedVerifier accepts every credential
+type namedVerifier struct{}
+
+func (namedVerifier) Verify(finding verify.Finding, opts verify.Options) (string, error) {
+	return verify.StatusVerified, nil
+}
+
+func TestEngine_verify(t *testing.T) {
+	verify.Register("scan-test", namedVerifier{})
+	engine := &Engine{
+		Rules:    []Rule{{Code: 1, Verifier: "scan-test"}, {Code: 2}},
+		Verifier: verify.NewChecker("", nil, 100, false),
+	}
+	tests := []struct {
+		name string
+		code int
+		want string
+	}{
+		{name: "Rule naming a verifier", code: 1, want: verify.StatusVerified},
+		{name: "Rule without a verifier", code: 2, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hit := Hit{Code: tt.code, MatchValue: "secret"}
+			engine.verify(&hit)
+			if hit.Verified != tt.want {
+				t.Errorf("verify() = %q, want %q", hit.Verified, tt.want)
+			}
+		})
+	}
+}
+
+// countingVerifier counts the credentials it is asked to verify
+type countingVerifier struct {
+	count *atomic.Int64
+}
+
+func (v countingVerifier) Verify(finding verify.Finding, opts verify.Options) (string, error) {
+	v.count.Add(1)
+	return verify.StatusInvalid, nil
+}
+
+func TestSearchFiles_verifyDisplayedHits(t *testing.T) {
+	count := new(atomic.Int64)
+	verify.Register("scan-count", countingVerifier{count: count})
+	files := []File{{Name: "file.py", Path: "buffer", Lines: []Line{
+		{LineValue: `password = "SecretValue1673"`, LineNum: 1, FileName: "file.py", FilePath: "buffer"},
+	}}}
+	engineCfg := cfg
+	engineCfg.EnabledModulesMap = map[string]string{"password-secret": "password-secret.yaml"}
+	engineCfg.AdjustedSeverityCategories = nil
+	engineCfg.Verify = true
+	engine, err := NewEngine(engineCfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	for i := range engine.Rules {
+		engine.Rules[i].Verifier = "scan-count"
+	}
+
+	tests := []struct {
+		name            string
+		confidenceLevel int
+		wantVerified    bool
+	}{
+		{name: "Hits below the display threshold", confidenceLevel: 0},
+		{name: "Displayed hits", confidenceLevel: engineCfg.ConfidenceDisplayLevel, wantVerified: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count.Store(0)
+			scanCfg := engineCfg
+			scanCfg.ConfidenceDisplayLevel = tt.confidenceLevel
+			hits := make(chan Hit)
+			go engine.SearchFiles(context.Background(), &scanCfg, files, nil, nil, hits)
+			var found int
+			for range hits {
+				found++
+			}
+			if verified := count.Load() > 0; verified != tt.wantVerified || (tt.wantVerified && found == 0) {
+				t.Errorf("SearchFiles() verified %d credentials of %d hits, want verified %v", count.Load(), found, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestWithRuleProfile(t *testing.T) {
+	files := []File{{Name: "file.py", Path: "buffer", Lines: []Line{
+		{LineValue: `password = "SecretValue1673"`, LineNum: 1, FileName: "file.py", FilePath: "buffer"},
+		{LineValue: `password = "${SECRET_VALUE}"`, LineNum: 2, FileName: "file.py", FilePath: "buffer"},
+		{LineValue: `host = 'localhost'`, LineNum: 3, FileName: "file.py", FilePath: "buffer"},
+	}}}
+	engineCfg := cfg
+	engineCfg.EnabledModulesMap = map[string]string{"password-secret": "password-secret.yaml"}
+	engineCfg.AdjustedSeverityCategories = nil
+	engine, err := NewEngine(engineCfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+
+	profile := NewRuleProfile()
+	hits := make(chan Hit)
+	go engine.SearchFiles(WithRuleProfile(context.Background(), profile), &engineCfg, files, nil, nil, hits)
+	for range hits {
+	}
+
+	codes := make(map[int]bool)
+	for _, rule := range engine.Rules {
+		codes[rule.Code] = true
+	}
+	stats := profile.Stats()
+	if len(stats) != len(codes) {
+		t.Fatalf("Stats() returned %d rules, want one per rule code %d", len(stats), len(codes))
+	}
+	var hitCount, falsePositives int64
+	for i, s := range stats {
+		if i > 0 && s.MatchTime > stats[i-1].MatchTime {
+			t.Errorf("Stats() rule %d took longer than the rule before it", s.Code)
+		}
+		if s.Hits+s.FalsePositives > s.Matches {
+			t.Errorf("rule %d has %d hits and %d false positives out of %d matches", s.Code, s.Hits, s.FalsePositives, s.Matches)
+		}
+		hitCount += s.Hits
+		falsePositives += s.FalsePositives
+	}
+	if hitCount == 0 || falsePositives == 0 {
+		t.Errorf("Stats() recorded %d hits and %d false positives, want both", hitCount, falsePositives)
+	}
+}
+
+func TestEngine_CheckExamples(t *testing.T) {
+	engine := &Engine{
+		Rules: []Rule{
+			{Code: 9001, Caption: "Token", Searcharea: "body", CompiledPattern: regexp.MustCompile(`token=[a-z0-9]{8,}`),
+				Example: "token=abcd1234", Examples: []string{"# header\ntoken=zyxw9876"}, NegativeExamples: []string{"token=short"}},
+			{Code: 9002, Caption: "Broken token", Searcharea: "body", CompiledPattern: regexp.MustCompile(`key=[0-9]{8}`),
+				Example: "key=abcdefgh", NegativeExamples: []string{"key=12345678"}},
+			{Code: 9003, Caption: "Key file", Searcharea: "filename", CompiledPattern: regexp.MustCompile(`\.pem$`), Postprocess: "pem",
+				Example: "certs/server.pem", NegativeExamples: []string{"certs/server.crt"}},
+		},
+	}
+	want := []ExampleFailure{
+		{Code: 9002, Caption: "Broken token", Example: "key=abcdefgh"},
+		{Code: 9002, Caption: "Broken token", Example: "key=12345678", Negative: true},
+	}
+	if got := engine.CheckExamples(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("Engine.CheckExamples() = %+v, want %+v", got, want)
+	}
+}
+
+func TestEngine_CheckExamples_shippedRules(t *testing.T) {
+	rulesCfg := cfg
+	rulesCfg.EnabledModulesMap = map[string]string{}
+	files, err := filepath.Glob(filepath.Join(cfg.RulesConfigDir, "*.yaml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no rule files in %s: %v", cfg.RulesConfigDir, err)
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		rulesCfg.EnabledModulesMap[strings.TrimSuffix(name, filepath.Ext(name))] = name
+	}
+	engine, err := NewEngine(rulesCfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	for _, failure := range engine.CheckExamples(rulesCfg) {
+		if failure.Negative {
+			t.Errorf("rule %d reports its negative example %q", failure.Code, failure.Example)
+		} else {
+			t.Errorf("rule %d doesn't report its example %q", failure.Code, failure.Example)
+		}
+	}
+}
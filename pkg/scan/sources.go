@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+)
+
+// configLayer is the config directory or a rule source, with its rules, false positives, labels and solutions directories
+type configLayer struct {
+	name                                                 string
+	priority                                             int
+	rulesDir, falsePositivesDir, labelsDir, solutionsDir string
+}
+
+// configLayers returns the config directory followed by the rule sources, from the lowest priority to the highest.
+// Of the layers with the same priority, the config directory comes first and the sources keep their order.
+func configLayers(cfg cfgreader.EarlybirdConfig) []configLayer {
+	layers := []configLayer{{
+		rulesDir:          cfg.RulesConfigDir,
+		falsePositivesDir: cfg.FalsePositivesConfigDir,
+		labelsDir:         cfg.LabelsConfigDir,
+		solutionsDir:      cfg.SolutionsConfigDir,
+	}}
+	for _, source := range cfg.RuleSources {
+		layers = append(layers, configLayer{
+			name:              source.Name,
+			priority:          source.Priority,
+			rulesDir:          filepath.Join(source.Dir, "rules"),
+			falsePositivesDir: filepath.Join(source.Dir, "falsepositives"),
+			labelsDir:         filepath.Join(source.Dir, "labels"),
+			solutionsDir:      filepath.Join(source.Dir, "solutions"),
+		})
+	}
+	sort.SliceStable(layers, func(i, j int) bool { return layers[i].priority < layers[j].priority })
+	return layers
+}
+
+// layerDirs returns the directories of the layers which exist, of the config directory even when it doesn't since it's required
+func layerDirs(layers []configLayer, dir func(layer configLayer) string) (dirs []string) {
+	for _, layer := range layers {
+		if _, err := os.Stat(dir(layer)); layer.name == "" || err == nil {
+			dirs = append(dirs, dir(layer))
+		}
+	}
+	return dirs
+}
+
+// loadModuleRules loads the rules file of the module from every layer which has it, the rules are returned per layer
+func loadModuleRules(cfg cfgreader.EarlybirdConfig, layers []configLayer, moduleName, fileName string) (layerRules [][]Rule, problems []ConfigProblem) {
+	layerRules = make([][]Rule, len(layers))
+	var found bool
+	for i, layer := range layers {
+		if _, err := os.Stat(path.Join(layer.rulesDir, fileName)); fileName == "" || err != nil {
+			continue
+		}
+		found = true
+		layerCfg := cfg
+		layerCfg.RulesConfigDir = layer.rulesDir
+		var loadProblems []ConfigProblem
+		layerRules[i], loadProblems = loadRuleConfigs(layerCfg, moduleName, fileName)
+		problems = append(problems, loadProblems...)
+	}
+	// Report the module's file missing from the config directory
+	if !found {
+		_, problems = loadRuleConfigs(cfg, moduleName, fileName)
+	}
+	return layerRules, problems
+}
+
+// overrideRules merges the rules of the layers, a code defined in a layer replaces the rules with that code of the lower layers
+func overrideRules(layers []configLayer, layerRules [][]Rule, verbose bool) (rules []Rule) {
+	//overriding is the layer, by code, which defines the rules with the code
+	overriding := make(map[int]int)
+	for i := range layers {
+		for _, rule := range layerRules[i] {
+			if previous, ok := overriding[rule.Code]; ok && previous != i && verbose {
+				log.Printf("Rule %d of %s overrides the rule of %s", rule.Code, layers[i].displayName(), layers[previous].displayName())
+			}
+			overriding[rule.Code] = i
+		}
+	}
+	for i := range layers {
+		for _, rule := range layerRules[i] {
+			if overriding[rule.Code] == i {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+// displayName names the layer in messages
+func (l configLayer) displayName() string {
+	if l.name == "" {
+		return "the config directory"
+	}
+	return "rule source " + l.name
+}
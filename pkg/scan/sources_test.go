@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
+)
+
+func TestNewEngine_ruleSources(t *testing.T) {
+	rule := func(code int, caption string) string {
+		return "  - Code: " + strconv.Itoa(code) + "\n    Pattern: \"token=\\\\w+\"\n    Caption: " + caption + "\n    Severity: 2\n    Confidence: 2\n"
+	}
+	cfg := writeConfig(t, map[string]string{
+		"rules/custom.yaml":                     "rules:\n" + rule(9001, "Upstream token") + rule(9002, "Upstream secret"),
+		"solutions/solutions.yaml":              "solutions:\n  - id: 1\n    text: Upstream solution\n",
+		"internal/rules/custom.yaml":            "rules:\n" + rule(9001, "Internal token"),
+		"internal/rules/internal.yaml":          "rules:\n" + rule(9100, "Internal only"),
+		"internal/falsepositives/internal.yaml": "rules:\n  - Codes: [9001]\n    Pattern: \"token=test\"\n",
+		"internal/solutions/solutions.yaml":     "solutions:\n  - id: 1\n    text: Internal solution\n",
+		"fallback/rules/custom.yaml":            "rules:\n" + rule(9002, "Fallback secret") + rule(9003, "Fallback only"),
+		"fallback/falsepositives/fallback.yaml": "rules:\n  - Codes: [9001]\n    Pattern: \"token=dummy\"\n",
+		"fallback/labels/labels.yaml":           "Labels:\n  - label: fallback\n    keys: [fallback]\n    codes: [9003]\n",
+	})
+	configDir := filepath.Dir(cfg.RulesConfigDir)
+	cfg.RuleSources = []cfgReader.RuleSource{
+		{Name: "internal", Priority: 10, Dir: filepath.Join(configDir, "internal")},
+		{Name: "fallback", Priority: -1, Dir: filepath.Join(configDir, "fallback")},
+		{Name: "missing", Priority: 5, Dir: filepath.Join(configDir, "missing")},
+	}
+	cfg.EnabledModulesMap = map[string]string{"custom": "custom.yaml", "internal": "internal.yaml"}
+	cfg.ShowSolutions = true
+
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	captions := make(map[int]string)
+	for _, rule := range engine.Rules {
+		if _, ok := captions[rule.Code]; ok {
+			t.Errorf("NewEngine() loaded code %d twice", rule.Code)
+		}
+		captions[rule.Code] = rule.Caption
+	}
+	want := map[int]string{9001: "Internal token", 9002: "Upstream secret", 9003: "Fallback only", 9100: "Internal only"}
+	for code, caption := range want {
+		if captions[code] != caption {
+			t.Errorf("NewEngine() rule %d = %q, want %q", code, captions[code], caption)
+		}
+	}
+	if len(captions) != len(want) {
+		t.Errorf("NewEngine() loaded rules %v, want %v", captions, want)
+	}
+	if got := len(engine.FalsePositiveRules[9001].FalsePositives); got != 2 {
+		t.Errorf("NewEngine() loaded %d false positives for 9001, want the ones of both sources", got)
+	}
+	if got := engine.SolutionConfigs[1].Text; got != "Internal solution" {
+		t.Errorf("NewEngine() solution 1 = %q, want the one of the highest priority", got)
+	}
+	if got := len(engine.Labels[9003].Labels); got != 1 {
+		t.Errorf("NewEngine() loaded %d labels for 9003, want 1", got)
+	}
+
+	// Duplicate codes across sources are overrides, not lint problems
+	for _, problem := range LintConfig(cfg) {
+		if problem.Fatal || problem.Code != 0 {
+			t.Errorf("LintConfig() reported %v", problem)
+		}
+	}
+}
@@ -17,9 +17,36 @@
 package scan
 
 import (
+	"io"
 	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/verify"
 )
 
+// Engine owns a compiled rule set with its labels, false positive rules and solutions.
+// Engines don't share any state, so one process can scan with several rule sets at the same time.
+type Engine struct {
+	Rules              []Rule
+	Labels             map[int]LabelConfigs
+	FalsePositiveRules map[int]FalsePositives
+	SolutionConfigs    map[int]Solution
+	//BaselineFingerprints are the hits left out of the results
+	BaselineFingerprints map[string]bool
+	//Verifier tries found credentials against their provider, verification is off when nil
+	Verifier *verify.Checker
+	keywords *keywordMatcher
+	//ruleCounters are the rule profile counters of the Rules, in the same order, nil when the scan isn't profiled
+	ruleCounters []*ruleCounters
+	//accepted are the entries of the suppressions file of the scanned directory, nil when there is none
+	accepted *acceptedRisks
+	//severityAdjustments are the adjusted severity categories of the config with their patterns compiled
+	severityAdjustments []cfgreader.AdjustedSeverityCategory
+}
+
 // Rules is the exported definition of the Rules structure for Earlybird
 type Rules struct {
 	Rules      []Rule `json:"rules"`
@@ -34,6 +61,13 @@ type Rule struct {
 	Searcharea                                        string
 	CWE                                               []string
 	Example                                           string
+	// Examples are more values the rule must report, NegativeExamples are values it must not report, see CheckExamples
+	Examples         []string
+	NegativeExamples []string
+	// Keywords are literals one of which must be in the line for the pattern to be evaluated, rules without keywords are always evaluated
+	Keywords []string
+	// Verifier names the verifier which tries the hits against their provider with -verify, see verify.Register
+	Verifier string
 }
 
 // Hit is a match in a file against a specific rule
@@ -53,20 +87,38 @@ type Hit struct {
 	Labels       []string `json:"labels"`
 	CWE          []string `json:"cwe"`
 	Time         string   `json:"time"`
+	Fingerprint  string   `json:"fingerprint"`
+	Commit       string   `json:"commit,omitempty"`
+	Author       string   `json:"author,omitempty"`
+	AuthorEmail  string   `json:"author_email,omitempty"`
+	CommitDate   string   `json:"commit_date,omitempty"`
+	Verified     string   `json:"verified,omitempty"`
 }
 
 // File to scan
 type File struct {
-	Name  string
-	Path  string
-	Lines []Line
-	Raw   []byte
+	Name   string
+	Path   string
+	Lines  []Line
+	Raw    []byte
+	Commit *Commit
 }
 
 // Line in a file to scan
 type Line struct {
 	LineNum                       int
 	LineValue, FilePath, FileName string
+	Commit                        *Commit
+	// Removed is set for lines a commit deleted, when removed lines are scanned
+	Removed bool
+}
+
+// Commit is the git commit a file or line was taken from in history and diff scans
+type Commit struct {
+	Hash   string `json:"hash"`
+	Author string `json:"author"`
+	Email  string `json:"email"`
+	Date   string `json:"date"`
 }
 
 // Report is the Earlybird end output
@@ -78,17 +130,230 @@ type Report struct {
 	Modules       []string `json:"modules"`
 	Hits          []Hit    `json:"hits"`
 	HitCount      int      `json:"hit_count"`
+	Truncated     bool     `json:"truncated,omitempty"`
+	Commits       []Commit `json:"commits,omitempty"`
 	FilesScanned  int      `json:"files_scanned"`
 	RulesObserved int      `json:"rules_observed"`
 	StartTime     string   `json:"start_time"`
 	EndTime       string   `json:"end_time"`
 	Duration      string   `json:"duration"`
+	// Suppressed are the hits left out by inline ignore directives, with the reason given for each
+	Suppressed []Suppression `json:"suppressed,omitempty"`
+}
+
+// Progress counts the files a scan has read, see WithProgress
+type Progress struct {
+	FilesDone atomic.Int64
+}
+
+// progressKey is the context key of the Progress of a scan
+type progressKey struct{}
+
+// RuleProfile records how much every rule cost during a scan and how many of its matches turned into hits, see WithRuleProfile
+type RuleProfile struct {
+	mu    sync.Mutex
+	rules map[int]*ruleCounters
+}
+
+// RuleStats are the numbers a RuleProfile recorded for one rule
+type RuleStats struct {
+	Code     int    `json:"code"`
+	Caption  string `json:"caption"`
+	Category string `json:"category"`
+	//Evaluations is the number of lines and file names the rule pattern ran on
+	Evaluations int64 `json:"evaluations"`
+	//MatchTime is the total time spent running the rule pattern
+	MatchTime time.Duration `json:"match_time_ns"`
+	//Matches is the number of times the rule pattern matched
+	Matches int64 `json:"matches"`
+	//Hits is the number of matches left after the post-processing and the false positive rules
+	Hits int64 `json:"hits"`
+	//FalsePositives is the number of matches the false positive rules removed
+	FalsePositives int64 `json:"false_positives"`
+}
+
+// ruleCounters are the live counters behind RuleStats
+type ruleCounters struct {
+	code           int
+	caption        string
+	category       string
+	evaluations    atomic.Int64
+	matchTime      atomic.Int64
+	matches        atomic.Int64
+	hits           atomic.Int64
+	falsePositives atomic.Int64
+}
+
+// ExampleFailure is a rule example which CheckExamples found to behave differently than intended
+type ExampleFailure struct {
+	Code    int
+	Caption string
+	Example string
+	// Negative is set for a negative example that was reported, otherwise a positive example was not reported
+	Negative bool
+}
+
+// ConfigProblem is a mistake found in a rules, false positives, labels or solutions file
+type ConfigProblem struct {
+	File string `json:"file"`
+	// Position is the entry of the file the problem is in, e.g. rules[3], empty for problems with the whole file
+	Position string `json:"position,omitempty"`
+	Code     int    `json:"code,omitempty"`
+	Message  string `json:"message"`
+	// Fatal problems keep the config from loading, the others make parts of it silently do nothing
+	Fatal bool `json:"fatal"`
+}
+
+// ConfigError is returned by NewEngine with every problem that keeps the config from loading
+type ConfigError struct {
+	Problems []ConfigProblem
+}
+
+// Suppression is a hit left out of the report by an inline ignore directive or the suppressions file
+type Suppression struct {
+	Code        int    `json:"code"`
+	Caption     string `json:"caption"`
+	Category    string `json:"category"`
+	Severity    string `json:"severity"`
+	Filename    string `json:"filename"`
+	Line        int    `json:"line"`
+	Fingerprint string `json:"fingerprint"`
+	// Directive is the annotation as written, e.g. EARLYBIRD-IGNORE-NEXT-LINE[3013], or the suppressions file
+	Directive     string `json:"directive"`
+	DirectiveLine int    `json:"directive_line,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	Owner         string `json:"owner,omitempty"`
+	Expires       string `json:"expires,omitempty"`
+}
+
+// SuppressionFile is a .earlybird-suppressions.yaml, the findings a team has accepted the risk of
+type SuppressionFile struct {
+	Suppressions []AcceptedRisk `json:"suppressions"`
+}
+
+// AcceptedRisk accepts the hits with a fingerprint, or the hits of a rule in the files matching a path glob
+type AcceptedRisk struct {
+	Fingerprint   string `json:"fingerprint,omitempty"`
+	Code          int    `json:"code,omitempty"`
+	Path          string `json:"path,omitempty"`
+	Justification string `json:"justification"`
+	Owner         string `json:"owner"`
+	// Expires is the last day the risk is accepted, as YYYY-MM-DD, it never expires when empty
+	Expires string `json:"expires,omitempty"`
+	expired bool
+}
+
+// acceptedRisks are the entries of the suppressions file a scan applies
+type acceptedRisks struct {
+	source string
+	risks  []AcceptedRisk
+}
+
+// SuppressionLog collects the suppressions of a scan, see WithSuppressions
+type SuppressionLog struct {
+	mu      sync.Mutex
+	entries []Suppression
+}
+
+// suppressionsKey is the context key of the SuppressionLog of a scan
+type suppressionsKey struct{}
+
+// BaselineLog collects the hits a new baseline records, see WithBaselineLog
+type BaselineLog struct {
+	mu   sync.Mutex
+	hits []Hit
+}
+
+// baselineKey is the context key of the BaselineLog of a scan
+type baselineKey struct{}
+
+// SkipLog collects the files a scan couldn't read, see WithSkipLog
+type SkipLog struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+// skipKey is the context key of the SkipLog of a scan
+type skipKey struct{}
+
+// ignoreDirective is an inline annotation that suppresses the hits of some or all rules on the lines it covers
+type ignoreDirective struct {
+	text   string
+	kind   string
+	codes  []int // every rule when empty
+	reason string
+	line   int
+}
+
+// ignoreBlock is the range of lines between an -START directive and its -END
+type ignoreBlock struct {
+	directive ignoreDirective
+	from, to  int
+}
+
+// ignoreIndex holds the ignore directives of a file by the lines they cover
+type ignoreIndex struct {
+	file   []ignoreDirective
+	lines  map[int][]ignoreDirective
+	blocks []ignoreBlock
+}
+
+// ignoreIndexBuilder collects the ignore directives of a file while its lines are read
+type ignoreIndexBuilder struct {
+	annotations []string
+	index       *ignoreIndex
+	open        []ignoreDirective
+	last        int
+}
+
+// labelKey is a key of a multiline label, compiled once per scan
+type labelKey struct {
+	key     string
+	pattern *regexp.Regexp
+}
+
+// fileIndex is what the workers need to know about the whole file: the multiline label keys it contains and its ignore
+// directives, along with the hits already found in it
+type fileIndex struct {
+	keys     map[string]bool
+	ignores  *ignoreIndex
+	dupeLock sync.Mutex
+	dupes    map[string]bool //HASH:true
+	//A streamed file is scanned while it's read, so its hits are held until the reader has indexed every line
+	streamed bool
+	holdLock sync.Mutex
+	held     []Hit
+	pending  int //Jobs of the file which aren't scanned yet, plus the end of file job the reader still has to send
+}
+
+// fileIndexer builds the fileIndex of a file one line at a time, so the file never has to be held in memory
+type fileIndexer struct {
+	keys    []labelKey
+	found   map[string]bool
+	ignores ignoreIndexBuilder
+}
+
+// ruleProfileKey is the context key of the RuleProfile of a scan
+type ruleProfileKey struct{}
+
+// countingReader counts the bytes read from r, for the bytes read metric
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Baseline is the set of hit fingerprints accepted by a previous scan
+type Baseline struct {
+	Version      string   `json:"version"`
+	CreatedAt    string   `json:"created_at"`
+	Fingerprints []string `json:"fingerprints"`
 }
 
-// WorkJob As we add jobs to the pool, they need to contain the line being scanned and the file content (in Lines)
+// WorkJob As we add jobs to the pool, they need to contain the line being scanned and the index of its file
 type WorkJob struct {
-	WorkLine  Line
-	FileLines []Line
+	WorkLine Line
+	File     *fileIndex
+	fileEnd  bool //Sent once the whole file is read, it carries no line
 }
 
 // FalsePositives are the rules to match false positives post process
@@ -103,6 +368,7 @@ type FalsePositive struct {
 	CompiledPattern *regexp.Regexp
 	FileExtensions  []string
 	UseFullLine     bool
+	Description     string
 }
 
 // Solutions to each rule / finding
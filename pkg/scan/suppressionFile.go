@@ -0,0 +1,155 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/wildcard"
+)
+
+// withAcceptedRisks returns the engine applying the suppressions file of the scan, see suppressionsFile.
+// A file which fails to load is logged and not applied, so the hits it was meant to suppress are reported.
+func (e *Engine) withAcceptedRisks(cfg *cfgReader.EarlybirdConfig) *Engine {
+	source := suppressionsFile(cfg)
+	if source == "" {
+		return e
+	}
+	risks, err := loadSuppressionFile(source, time.Now())
+	if err != nil {
+		log.Println("Not applying suppressions file:", err)
+		return e
+	}
+	for _, risk := range risks {
+		if risk.expired {
+			log.Printf("Suppression of %s owned by %s expired on %s", risk.target(), risk.Owner, risk.Expires)
+		}
+	}
+	accepted := *e
+	accepted.accepted = &acceptedRisks{source: source, risks: risks}
+	return &accepted
+}
+
+// suppressionsFile is the file set with -suppressions, or the .earlybird-suppressions.yaml of the scanned directory if it has one
+func suppressionsFile(cfg *cfgReader.EarlybirdConfig) string {
+	if cfg.SuppressionsFile != "" {
+		return cfg.SuppressionsFile
+	}
+	if cfg.SearchDir == "" {
+		return ""
+	}
+	defaultFile := filepath.Join(cfg.SearchDir, SuppressionsFileName)
+	if _, err := os.Stat(defaultFile); err != nil {
+		return ""
+	}
+	return defaultFile
+}
+
+// loadSuppressionFile reads and checks the entries of a suppressions file, marking the ones which expired before now
+func loadSuppressionFile(source string, now time.Time) ([]AcceptedRisk, error) {
+	var suppressionFile SuppressionFile
+	if err := cfgReader.LoadConfigStrict(&suppressionFile, source); err != nil {
+		return nil, fmt.Errorf("%s: %v", source, err)
+	}
+	var problems []string
+	for i := range suppressionFile.Suppressions {
+		risk := &suppressionFile.Suppressions[i]
+		position := fmt.Sprintf("suppressions[%d]", i)
+		if risk.Fingerprint == "" && (risk.Code == 0 || risk.Path == "") {
+			problems = append(problems, position+" needs a fingerprint, or a code and a path")
+		}
+		if risk.Fingerprint != "" && (risk.Code != 0 || risk.Path != "") {
+			problems = append(problems, position+" has both a fingerprint and a code or path")
+		}
+		if _, err := path.Match(risk.Path, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("%s has a bad path: %v", position, err))
+		}
+		if strings.TrimSpace(risk.Justification) == "" {
+			problems = append(problems, position+" needs a justification")
+		}
+		if strings.TrimSpace(risk.Owner) == "" {
+			problems = append(problems, position+" needs an owner")
+		}
+		if risk.Expires != "" {
+			expires, err := time.Parse(expiryLayout, risk.Expires)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s has a bad expiry date, want YYYY-MM-DD: %v", position, err))
+				continue
+			}
+			// The risk stays accepted through the whole day it expires on
+			risk.expired = !now.Before(expires.AddDate(0, 0, 1))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s: %s", source, strings.Join(problems, "; "))
+	}
+	return suppressionFile.Suppressions, nil
+}
+
+// apply reports whether the hit is still to be reported. Hits of an accepted risk are recorded as suppressions,
+// hits whose every matching risk has expired are labelled so the report shows why they came back.
+func (a *acceptedRisks) apply(ctx context.Context, hit *Hit, searchDir string) bool {
+	if a == nil {
+		return true
+	}
+	expired := false
+	for _, risk := range a.risks {
+		if !risk.matches(*hit, searchDir) {
+			continue
+		}
+		// Another entry may still accept the hit, e.g. a renewed one listed after the expired one
+		if risk.expired {
+			expired = true
+			continue
+		}
+		suppression := newSuppression(*hit)
+		suppression.Directive = a.source
+		suppression.Reason = risk.Justification
+		suppression.Owner = risk.Owner
+		suppression.Expires = risk.Expires
+		recordSuppression(ctx, suppression)
+		return false
+	}
+	if expired {
+		hit.Labels = append(hit.Labels, expiredLabel)
+	}
+	return true
+}
+
+// matches reports whether the risk accepts the hit, paths are relative to the scanned directory
+func (r AcceptedRisk) matches(hit Hit, searchDir string) bool {
+	if r.Fingerprint != "" {
+		return r.Fingerprint == hit.Fingerprint
+	}
+	return r.Code == hit.Code && wildcard.PathMatch(r.Path, fingerprintPath(hit.Filename, searchDir))
+}
+
+// target describes the hits the risk accepts, for the log
+func (r AcceptedRisk) target() string {
+	if r.Fingerprint != "" {
+		return "fingerprint " + r.Fingerprint
+	}
+	return fmt.Sprintf("rule %d in %s", r.Code, r.Path)
+}
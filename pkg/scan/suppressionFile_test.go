@@ -0,0 +1,218 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_loadSuppressionFile(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		content     string
+		wantErr     string
+		wantExpired []bool
+	}{
+		{
+			name: "Fingerprint and rule entries",
+			content: `suppressions:
+  - fingerprint: 0123abcd
+    justification: Rotated test credential
+    owner: payments-team
+  - code: 3013
+    path: test/**/*.py
+    justification: Fixtures
+    owner: qa-team
+    expires: 2026-06-15
+  - code: 3013
+    path: docs/*
+    justification: Samples
+    owner: docs-team
+    expires: 2026-06-14
+`,
+			wantExpired: []bool{false, false, true},
+		},
+		{
+			name: "Missing justification and owner",
+			content: `suppressions:
+  - fingerprint: 0123abcd
+`,
+			wantErr: "suppressions[0] needs a justification; suppressions[0] needs an owner",
+		},
+		{
+			name: "Rule without a path",
+			content: `suppressions:
+  - code: 3013
+    justification: Fixtures
+    owner: qa-team
+`,
+			wantErr: "suppressions[0] needs a fingerprint, or a code and a path",
+		},
+		{
+			name: "Bad expiry date",
+			content: `suppressions:
+  - fingerprint: 0123abcd
+    justification: Fixtures
+    owner: qa-team
+    expires: next year
+`,
+			wantErr: "suppressions[0] has a bad expiry date",
+		},
+		{
+			name: "Misspelt field",
+			content: `suppressions:
+  - fingerprint: 0123abcd
+    justfication: Fixtures
+    owner: qa-team
+`,
+			wantErr: "unknown field",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := filepath.Join(t.TempDir(), SuppressionsFileName)
+			if err := os.WriteFile(source, []byte(tt.content), 0666); err != nil {
+				t.Fatal(err)
+			}
+			risks, err := loadSuppressionFile(source, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadSuppressionFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadSuppressionFile() error = %v", err)
+			}
+			if len(risks) != len(tt.wantExpired) {
+				t.Fatalf("loadSuppressionFile() loaded %d entries, want %d", len(risks), len(tt.wantExpired))
+			}
+			for i, risk := range risks {
+				if risk.expired != tt.wantExpired[i] {
+					t.Errorf("loadSuppressionFile() entry %d expired = %v, want %v", i, risk.expired, tt.wantExpired[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_acceptedRisks_apply(t *testing.T) {
+	hit := Hit{Code: 3013, Filename: "/repo/test/fixtures.py", Fingerprint: "0123abcd"}
+	expired := AcceptedRisk{Fingerprint: "0123abcd", Justification: "Rotated", Owner: "app-team", Expires: "2000-01-01", expired: true}
+	valid := AcceptedRisk{Code: 3013, Path: "test/**", Justification: "Fixtures", Owner: "qa-team", Expires: "2999-01-01"}
+	other := AcceptedRisk{Code: 3013, Path: "docs/**", Justification: "Samples", Owner: "docs-team"}
+	tests := []struct {
+		name         string
+		risks        []AcceptedRisk
+		wantReported bool
+		wantLabel    bool
+		wantOwner    string
+	}{
+		{name: "No matching entry", risks: []AcceptedRisk{other}, wantReported: true},
+		{name: "Valid entry", risks: []AcceptedRisk{other, valid}, wantOwner: "qa-team"},
+		{name: "Expired entry", risks: []AcceptedRisk{expired, other}, wantReported: true, wantLabel: true},
+		{name: "Expired entry before a valid one", risks: []AcceptedRisk{expired, valid}, wantOwner: "qa-team"},
+		{name: "Valid entry before an expired one", risks: []AcceptedRisk{valid, expired}, wantOwner: "qa-team"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &SuppressionLog{}
+			got := hit
+			risks := &acceptedRisks{source: SuppressionsFileName, risks: tt.risks}
+			if reported := risks.apply(WithSuppressions(context.Background(), log), &got, "/repo"); reported != tt.wantReported {
+				t.Errorf("apply() = %v, want %v", reported, tt.wantReported)
+			}
+			if labelled := strings.Contains(strings.Join(got.Labels, ","), expiredLabel); labelled != tt.wantLabel {
+				t.Errorf("apply() labels = %v, want the %q label %v", got.Labels, expiredLabel, tt.wantLabel)
+			}
+			entries := log.Entries()
+			if tt.wantOwner == "" {
+				if len(entries) != 0 {
+					t.Errorf("apply() recorded suppressions %+v, want none", entries)
+				}
+				return
+			}
+			if len(entries) != 1 || entries[0].Owner != tt.wantOwner {
+				t.Errorf("apply() recorded suppressions %+v, want one owned by %s", entries, tt.wantOwner)
+			}
+		})
+	}
+}
+
+func TestSearchFiles_suppressionFile(t *testing.T) {
+	dir := t.TempDir()
+	secret := []byte(`password = "SecretValue1673"`)
+	if err := os.MkdirAll(filepath.Join(dir, "test"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	files := []File{
+		{Name: "app.py", Path: filepath.Join(dir, "app.py")},
+		{Name: "fixtures.py", Path: filepath.Join(dir, "test", "fixtures.py")},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f.Path, secret, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scanCfg := cfg
+	scanCfg.SearchDir = dir
+	search := func() (found []Hit, suppressions []Suppression) {
+		log := &SuppressionLog{}
+		hits := make(chan Hit)
+		go SearchFiles(WithSuppressions(context.Background(), log), &scanCfg, files, nil, nil, hits)
+		for hit := range hits {
+			found = append(found, hit)
+		}
+		return found, log.Entries()
+	}
+
+	// Accept the hits of the test fixtures by rule and path, and the one of the app by a fingerprint which has expired
+	found, _ := search()
+	content := "suppressions:\n"
+	for _, hit := range found {
+		if strings.HasPrefix(hit.Filename, filepath.Join(dir, "test")) {
+			content += fmt.Sprintf("  - code: %d\n    path: test/**\n    justification: Fixtures\n    owner: qa-team\n    expires: 2999-01-01\n", hit.Code)
+		} else {
+			content += fmt.Sprintf("  - fingerprint: %s\n    justification: Rotated\n    owner: app-team\n    expires: 2000-01-01\n", hit.Fingerprint)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, SuppressionsFileName), []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	found, suppressions := search()
+	if len(found) == 0 || len(suppressions) == 0 {
+		t.Fatalf("SearchFiles() reported %d hits and %d suppressions, want both", len(found), len(suppressions))
+	}
+	for _, hit := range found {
+		if !strings.HasSuffix(hit.Filename, "app.py") || !strings.Contains(strings.Join(hit.Labels, ","), expiredLabel) {
+			t.Errorf("SearchFiles() reported %s without the %q label, labels %v", hit.Filename, expiredLabel, hit.Labels)
+		}
+	}
+	for _, suppression := range suppressions {
+		if !strings.HasSuffix(suppression.Filename, "fixtures.py") || suppression.Owner != "qa-team" || suppression.Reason != "Fixtures" {
+			t.Errorf("SearchFiles() recorded suppression %+v", suppression)
+		}
+	}
+}
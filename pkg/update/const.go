@@ -0,0 +1,39 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package configupdate
+
+const (
+	//manifestFile lists the version of a rule pack and the SHA-256 hash of each of its files
+	manifestFile = "manifest.json"
+	//signatureFile is the detached Ed25519 signature of the manifest, raw or base64 encoded
+	signatureFile = "manifest.json.sig"
+	//stagingDir is where an update is assembled in the config directory before it's swapped in
+	stagingDir = ".update-staging"
+	//rollbackDir keeps what the last update or rollback replaced
+	rollbackDir = ".update-rollback"
+	//digestPrefix starts the pin of a tarball rule source
+	digestPrefix = "sha256:"
+	//maxDownloadSize bounds the files and bundles of a rule pack
+	maxDownloadSize int64 = 100 << 20
+)
+
+var (
+	//packDirs are the directories of the config directory a rule pack may have files in
+	packDirs = []string{"rules", "falsepositives", "labels", "solutions"}
+	//packEntries are the entries of the config directory an update replaces
+	packEntries = append(append([]string{}, packDirs...), "sources", manifestFile, signatureFile)
+)
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package configupdate
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/americanexpress/earlybird/v4/pkg/utils"
+)
+
+// LoadPublicKey reads the Ed25519 public key rule packs are signed with from a PEM file, like the one written by
+// `openssl pkey -in key.pem -pubout`
+func LoadPublicKey(keyPath string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("no PEM encoded public key in %s", keyPath)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key %s: %v", keyPath, err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not an Ed25519 key", keyPath)
+	}
+	return edKey, nil
+}
+
+// verifyManifest checks the manifest was signed with the key, then parses it and checks its file list
+func verifyManifest(data, signature []byte, key ed25519.PublicKey) (manifest Manifest, err error) {
+	if len(key) != ed25519.PublicKeySize {
+		return manifest, errors.New("no public key to verify the rule pack with, set rule_pack_public_key in earlybird.json")
+	}
+	if !ed25519.Verify(key, data, decodeSignature(signature)) {
+		return manifest, errors.New("the rule pack manifest signature doesn't match the public key")
+	}
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("parsing the rule pack manifest: %v", err)
+	}
+	if manifest.Version == "" {
+		return manifest, errors.New("the rule pack manifest has no version")
+	}
+	for _, file := range manifest.Files {
+		if err = checkPackPath(file.Path); err != nil {
+			return manifest, err
+		}
+		if len(file.SHA256) != sha256.Size*2 {
+			return manifest, fmt.Errorf("the rule pack manifest has no SHA-256 hash for %s", file.Path)
+		}
+	}
+	return manifest, nil
+}
+
+// decodeSignature accepts the raw signature, like the one written by `openssl pkeyutl -sign -rawin`, or its base64 encoding
+func decodeSignature(signature []byte) []byte {
+	if len(signature) == ed25519.SignatureSize {
+		return signature
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return signature
+	}
+	return decoded
+}
+
+// checkPackPath only lets a rule pack have files in the rules, false positives, labels and solutions directories
+func checkPackPath(filePath string) error {
+	cleaned := path.Clean(filePath)
+	parts := strings.Split(cleaned, "/")
+	if cleaned != filePath || len(parts) < 2 || !utils.Contains(packDirs, parts[0]) || utils.Contains(parts, "..") {
+		return fmt.Errorf("the rule pack file %q is not in one of the %s directories", filePath, strings.Join(packDirs, ", "))
+	}
+	return nil
+}
+
+// checkHash compares the SHA-256 hash of the data to the hex encoded hash
+func checkHash(data []byte, want string) error {
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+		return fmt.Errorf("SHA-256 hash %s doesn't match %s", got, want)
+	}
+	return nil
+}
+
+// compareVersions compares dotted versions like 1.10.2 part by part, numerically when both parts are numbers, missing parts are 0
+func compareVersions(a, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		aPart, bPart := "0", "0"
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		aNum, aErr := strconv.Atoi(aPart)
+		bNum, bErr := strconv.Atoi(bPart)
+		switch {
+		case aErr == nil && bErr == nil && aNum != bNum:
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && aPart != bPart:
+			return strings.Compare(aPart, bPart)
+		}
+	}
+	return 0
+}
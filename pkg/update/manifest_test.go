@@ -0,0 +1,155 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package configupdate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// signedPack returns the signed manifest of the files, keyed by their path in the pack
+func signedPack(t *testing.T, key ed25519.PrivateKey, version string, files map[string]string) (data, signature []byte) {
+	t.Helper()
+	manifest := Manifest{Version: version}
+	for filePath, content := range files {
+		sum := sha256.Sum256([]byte(content))
+		manifest.Files = append(manifest.Files, ManifestFile{Path: filePath, SHA256: hex.EncodeToString(sum[:])})
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, ed25519.Sign(key, data)
+}
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return public, private
+}
+
+func Test_verifyManifest(t *testing.T) {
+	public, private := newKey(t)
+	otherPublic, _ := newKey(t)
+	data, signature := signedPack(t, private, "1.2.0", map[string]string{"rules/content.yaml": "rules: []"})
+	badPath, badPathSignature := signedPack(t, private, "1.2.0", map[string]string{"earlybird.json": "{}"})
+
+	tests := []struct {
+		name      string
+		data      []byte
+		signature []byte
+		key       ed25519.PublicKey
+		wantErr   bool
+	}{
+		{name: "Signed manifest", data: data, signature: signature, key: public},
+		{name: "Base64 encoded signature", data: data, signature: []byte(base64.StdEncoding.EncodeToString(signature) + "\n"), key: public},
+		{name: "Other key", data: data, signature: signature, key: otherPublic, wantErr: true},
+		{name: "No key", data: data, signature: signature, wantErr: true},
+		{name: "Changed manifest", data: append([]byte(" "), data...), signature: signature, key: public, wantErr: true},
+		{name: "File outside of the rule directories", data: badPath, signature: badPathSignature, key: public, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := verifyManifest(tt.data, tt.signature, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (manifest.Version != "1.2.0" || len(manifest.Files) != 1) {
+				t.Errorf("verifyManifest() = %v, want version 1.2.0 with one file", manifest)
+			}
+		})
+	}
+}
+
+func Test_checkPackPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{path: "rules/content.yaml"},
+		{path: "falsepositives/nested/fp.yaml"},
+		{path: "rules", wantErr: true},
+		{path: "earlybird.json", wantErr: true},
+		{path: "rules/../earlybird.json", wantErr: true},
+		{path: "/rules/content.yaml", wantErr: true},
+		{path: "../rules/content.yaml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if err := checkPackPath(tt.path); (err != nil) != tt.wantErr {
+				t.Errorf("checkPackPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_compareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.2.0", b: "1.2.0", want: 0},
+		{a: "1.10.0", b: "1.9.3", want: 1},
+		{a: "v1.2", b: "1.2.0", want: 0},
+		{a: "1.2.0", b: "1.2.1", want: -1},
+		{a: "2021.05", b: "2021.04", want: 1},
+		{a: "1.0.0-rc1", b: "1.0.0-rc2", want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if got := compareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("compareVersions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	public, _ := newKey(t)
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "rulepack.pub")
+	if err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadPublicKey(keyPath)
+	if err != nil {
+		t.Fatalf("LoadPublicKey() error = %v", err)
+	}
+	if !got.Equal(public) {
+		t.Errorf("LoadPublicKey() = %x, want %x", got, public)
+	}
+	if _, err = LoadPublicKey(filepath.Join(t.TempDir(), "missing.pub")); err == nil {
+		t.Errorf("LoadPublicKey() of a missing file returned no error")
+	}
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package configupdate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+// fetchSource fetches a git or tarball rule source, checks its signed manifest and installs the files it lists into the
+// sources directory of the staged config when its version is newer than the installed one, local directory sources are
+// read in place and not fetched
+func fetchSource(stage string, source cfgreader.RuleSource, opts Options) error {
+	if source.Type != cfgreader.SourceGit && source.Type != cfgreader.SourceTarball {
+		return nil
+	}
+	if source.Pin == "" {
+		return fmt.Errorf("a %s source needs a pin", source.Type)
+	}
+	tmpDir, err := os.MkdirTemp("", "ebsource")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var root string
+	if source.Type == cfgreader.SourceGit {
+		root, err = cloneSource(tmpDir, source, opts.GitUser, opts.GitPassword)
+	} else {
+		root, err = downloadSource(tmpDir, source)
+	}
+	if err != nil {
+		return err
+	}
+
+	key := opts.PublicKey
+	if source.PublicKey != "" {
+		keyPath := source.PublicKey
+		if !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(opts.ConfigDir, keyPath)
+		}
+		if key, err = LoadPublicKey(keyPath); err != nil {
+			return err
+		}
+	}
+	manifest, data, signature, err := readSignedManifest(root, key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(stage, cfgreader.SourcesDir, source.Name)
+	installed := readManifest(dir)
+	if installed.Version != "" {
+		switch compareVersions(manifest.Version, installed.Version) {
+		case -1:
+			return fmt.Errorf("the version %s is older than the installed version %s", manifest.Version, installed.Version)
+		case 0:
+			log.Println("Rule source", source.Name, installed.Version, "is up to date")
+			return nil
+		}
+	}
+	log.Println("Updating rule source", source.Name, "to version", manifest.Version)
+	return installPack(dir, installed, manifest, data, signature, func(filePath string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(root, filepath.FromSlash(filePath)))
+	})
+}
+
+// readSignedManifest reads and verifies the manifest at the root of a fetched rule source
+func readSignedManifest(root string, key ed25519.PublicKey) (manifest Manifest, data, signature []byte, err error) {
+	if data, err = os.ReadFile(filepath.Join(root, manifestFile)); err != nil {
+		return manifest, nil, nil, fmt.Errorf("the rule source has no %s: %v", manifestFile, err)
+	}
+	if signature, err = os.ReadFile(filepath.Join(root, signatureFile)); err != nil {
+		return manifest, nil, nil, fmt.Errorf("the rule source has no %s: %v", signatureFile, err)
+	}
+	manifest, err = verifyManifest(data, signature, key)
+	return manifest, data, signature, err
+}
+
+// cloneSource clones the git repository of the rule source and checks out its pin, a tag, branch or commit
+func cloneSource(tmpDir string, source cfgreader.RuleSource, username, password string) (string, error) {
+	options := &git.CloneOptions{URL: source.URL}
+	if username != "" {
+		options.Auth = &http.BasicAuth{Username: username, Password: password}
+	}
+	repo, err := git.PlainClone(tmpDir, false, options)
+	if err != nil {
+		return "", fmt.Errorf("cloning %s: %v", source.URL, err)
+	}
+
+	// Branches other than the default one are only known as remote branches
+	hash, err := repo.ResolveRevision(plumbing.Revision(source.Pin))
+	if err != nil {
+		hash, err = repo.ResolveRevision(plumbing.Revision("origin/" + source.Pin))
+	}
+	if err != nil {
+		return "", fmt.Errorf("pin %s not found in %s", source.Pin, source.URL)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	if err = worktree.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true}); err != nil {
+		return "", fmt.Errorf("checking out %s: %v", source.Pin, err)
+	}
+	return tmpDir, nil
+}
+
+// downloadSource downloads the .tar.gz bundle of the rule source, checks it against the pinned digest and extracts it.
+// A bundle with its files in a single top directory, like the archives of git hosts, has that directory as its root.
+func downloadSource(tmpDir string, source cfgreader.RuleSource) (string, error) {
+	if !strings.HasPrefix(source.Pin, digestPrefix) {
+		return "", fmt.Errorf("the pin of a tarball must be its %s<hex> digest", digestPrefix)
+	}
+	archive := filepath.Join(tmpDir, "bundle.tar.gz")
+	if err := downloadFile(archive, source.URL); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	if digest := digestPrefix + hex.EncodeToString(sum[:]); !strings.EqualFold(digest, source.Pin) {
+		return "", fmt.Errorf("the bundle digest %s doesn't match the pin %s", digest, source.Pin)
+	}
+
+	root := filepath.Join(tmpDir, "bundle")
+	if err = extractTarGz(archive, root); err != nil {
+		return "", err
+	}
+	if _, err = os.Stat(filepath.Join(root, manifestFile)); os.IsNotExist(err) {
+		if entries, _ := os.ReadDir(root); len(entries) == 1 && entries[0].IsDir() {
+			return filepath.Join(root, entries[0].Name()), nil
+		}
+	}
+	return root, nil
+}
+
+// extractTarGz extracts the directories and regular files of the archive into the directory, entries which would be
+// written outside of it are refused
+func extractTarGz(archive, dir string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("reading bundle: %v", err)
+	}
+	defer gzipReader.Close()
+
+	var total int64
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading bundle: %v", err)
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("bundle entry %s is outside of the bundle", header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if total += header.Size; total > maxDownloadSize {
+				return errors.New("the bundle is larger than the download limit once extracted")
+			}
+			if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			content, err := io.ReadAll(io.LimitReader(tarReader, header.Size))
+			if err != nil {
+				return fmt.Errorf("reading bundle: %v", err)
+			}
+			if err = os.WriteFile(target, content, 0644); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package configupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// tarGz archives the files, keyed by their path in the archive
+func tarGz(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, content := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUpdate_tarballSource(t *testing.T) {
+	public, private := newKey(t)
+	files := map[string]string{"rules/internal.yaml": "internal", "falsepositives/internal.yaml": "fp"}
+	data, signature := signedPack(t, private, "2.0.0", files)
+	bundle := map[string][]byte{"pack-2.0.0/" + manifestFile: data, "pack-2.0.0/" + signatureFile: signature, "pack-2.0.0/README.md": []byte("not installed")}
+	for name, content := range files {
+		bundle["pack-2.0.0/"+name] = []byte(content)
+	}
+	archive := tarGz(t, bundle)
+	escape := tarGz(t, map[string][]byte{"../escape.yaml": nil})
+	sum, escapeSum := sha256.Sum256(archive), sha256.Sum256(escape)
+	server := packServer(t, map[string][]byte{"internal.tar.gz": archive, "escape.tar.gz": escape})
+
+	tests := []struct {
+		name    string
+		source  cfgreader.RuleSource
+		wantErr string
+	}{
+		{name: "Pinned bundle", source: cfgreader.RuleSource{Name: "internal", Type: cfgreader.SourceTarball, URL: server.URL + "/pack/internal.tar.gz", Pin: digestPrefix + hex.EncodeToString(sum[:])}},
+		{name: "Unpinned bundle", source: cfgreader.RuleSource{Name: "internal", Type: cfgreader.SourceTarball, URL: server.URL + "/pack/internal.tar.gz"}, wantErr: "needs a pin"},
+		{name: "Bundle with another digest", source: cfgreader.RuleSource{Name: "internal", Type: cfgreader.SourceTarball, URL: server.URL + "/pack/internal.tar.gz", Pin: digestPrefix + strings.Repeat("0", 64)}, wantErr: "doesn't match the pin"},
+		{name: "Pin which is not a digest", source: cfgreader.RuleSource{Name: "internal", Type: cfgreader.SourceTarball, URL: server.URL + "/pack/internal.tar.gz", Pin: "v2"}, wantErr: "digest"},
+		{name: "Bundle writing outside of its directory", source: cfgreader.RuleSource{Name: "internal", Type: cfgreader.SourceTarball, URL: server.URL + "/pack/escape.tar.gz", Pin: digestPrefix + hex.EncodeToString(escapeSum[:])}, wantErr: "outside"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDir := t.TempDir()
+			_, err := Update(Options{ConfigDir: configDir, PublicKey: public, Sources: []cfgreader.RuleSource{tt.source}})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Update() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			sourceDir := filepath.Join(configDir, cfgreader.SourcesDir, "internal")
+			if got := readFile(t, filepath.Join(sourceDir, "rules", "internal.yaml")); got != "internal" {
+				t.Errorf("Update() installed rules/internal.yaml = %q, want %q", got, "internal")
+			}
+			if _, err = os.Stat(filepath.Join(sourceDir, "README.md")); err == nil {
+				t.Errorf("Update() installed a file the manifest doesn't list")
+			}
+		})
+	}
+}
+
+func TestUpdate_gitSource(t *testing.T) {
+	public, private := newKey(t)
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// commit signs the rules as a rule pack version, commits it and tags it with the version
+	commit := func(version, rulesFile, rules string) {
+		data, signature := signedPack(t, private, version, map[string]string{rulesFile: rules})
+		for name, content := range map[string][]byte{manifestFile: data, signatureFile: signature, rulesFile: []byte(rules)} {
+			os.MkdirAll(filepath.Join(repoDir, filepath.Dir(name)), 0755)
+			if err := os.WriteFile(filepath.Join(repoDir, name), content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := worktree.Add(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		author := &object.Signature{Name: "Foo Bar", Email: "foo@bar.com", When: time.Now()}
+		hash, err := worktree.Commit("release "+version, &git.CommitOptions{Author: author, Committer: author})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = repo.CreateTag("v"+version, hash, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit("1.0.0", "rules/internal.yaml", "first")
+	commit("1.1.0", "rules/internal.yaml", "second")
+	commit("2.0.0", "rules/renamed.yaml", "third")
+
+	tests := []struct {
+		name    string
+		pins    []string
+		want    map[string]string
+		wantErr string
+	}{
+		{name: "Tag", pins: []string{"v1.0.0"}, want: map[string]string{"internal.yaml": "first"}},
+		{name: "Newer tag", pins: []string{"v1.0.0", "v1.1.0"}, want: map[string]string{"internal.yaml": "second"}},
+		{name: "File dropped by the newer tag", pins: []string{"v1.1.0", "v2.0.0"}, want: map[string]string{"renamed.yaml": "third"}},
+		{name: "Same tag", pins: []string{"v1.1.0", "v1.1.0"}, want: map[string]string{"internal.yaml": "second"}},
+		{name: "Older tag", pins: []string{"v1.1.0", "v1.0.0"}, wantErr: "older than the installed version 1.1.0"},
+		{name: "No pin", pins: []string{""}, wantErr: "needs a pin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDir := t.TempDir()
+			var err error
+			for _, pin := range tt.pins {
+				source := cfgreader.RuleSource{Name: "internal", Type: cfgreader.SourceGit, URL: repoDir, Pin: pin}
+				if _, err = Update(Options{ConfigDir: configDir, PublicKey: public, Sources: []cfgreader.RuleSource{source}}); err != nil {
+					break
+				}
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Update() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			rulesDir := filepath.Join(configDir, cfgreader.SourcesDir, "internal", "rules")
+			entries, err := os.ReadDir(rulesDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Errorf("Update() installed %d rule files, want %d", len(entries), len(tt.want))
+			}
+			for name, want := range tt.want {
+				if got := readFile(t, filepath.Join(rulesDir, name)); got != want {
+					t.Errorf("Update() installed rules/%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
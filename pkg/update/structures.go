@@ -0,0 +1,50 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package configupdate
+
+import (
+	"crypto/ed25519"
+
+	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
+)
+
+// Manifest lists the files of a rule pack, paths are relative to the config directory
+type Manifest struct {
+	Version string         `json:"version"`
+	Files   []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a file of a rule pack with the hex encoded SHA-256 hash of its content
+type ManifestFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// Options are the rule pack and the rule sources an update fetches into the config directory
+type Options struct {
+	ConfigDir string
+	// PackURL is where the manifest and files of the rule pack are downloaded from, no pack is downloaded when empty
+	PackURL string
+	// PublicKey verifies the rule pack and the sources without a key of their own
+	PublicKey ed25519.PublicKey
+	// Sources are the rule sources, only the git and tarball ones are fetched
+	Sources []cfgreader.RuleSource
+	// GitUser and GitPassword authenticate the clones of git sources
+	GitUser, GitPassword string
+	// Validate checks the staged config directory loads before it's swapped in
+	Validate func(configDir string) error
+}
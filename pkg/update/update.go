@@ -17,6 +17,8 @@
 package configupdate
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -24,61 +26,270 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 
 	cfgreader "github.com/americanexpress/earlybird/v4/pkg/config"
 )
 
-//UpdateConfigFiles updates all of the modules via the defined module URL in earlybird.json
-func UpdateConfigFiles(configDir, rulesConfigDir, appConfigPath, appConfigURL string, ruleModulesFilenameMap map[string]string) error {
-	for _, fileName := range ruleModulesFilenameMap {
-		moduleFilePath := path.Join(rulesConfigDir, fileName)
-		log.Println("Updating ", moduleFilePath)
+// Update downloads the signed rule pack and fetches the rule sources into a staging directory of the config directory,
+// checks them and swaps them in for the current rules, which are kept for Rollback. Nothing changes when a step fails.
+// It returns the version of the installed rule pack.
+func Update(opts Options) (version string, err error) {
+	if opts.PackURL == "" && !hasRemoteSources(opts.Sources) {
+		return "", errors.New("nothing to update, set rule_pack_url or rule_sources in earlybird.json")
+	}
+	stage := filepath.Join(opts.ConfigDir, stagingDir)
+	if err = os.RemoveAll(stage); err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(stage)
+	// Start from the current config, so the files which are not part of the rule pack are kept
+	for _, entry := range packEntries {
+		if err = copyTree(filepath.Join(opts.ConfigDir, entry), filepath.Join(stage, entry)); err != nil {
+			return "", fmt.Errorf("staging the current config: %v", err)
+		}
+	}
 
-		parsedUrl, err := url.Parse(cfgreader.Settings.ConfigBaseUrl)
+	version = readManifest(stage).Version
+	updated := hasRemoteSources(opts.Sources)
+	if opts.PackURL != "" {
+		var packUpdated bool
+		if version, packUpdated, err = updatePack(stage, opts); err != nil {
+			return "", err
+		}
+		updated = updated || packUpdated
+	}
+	// Keep what an earlier update replaced when nothing changed
+	if !updated {
+		return version, nil
+	}
+	for _, source := range opts.Sources {
+		if err = fetchSource(stage, source, opts); err != nil {
+			return "", fmt.Errorf("rule source %s: %v", source.Name, err)
+		}
+	}
 
-		if err != nil {
-			return err
+	if opts.Validate != nil {
+		if err = opts.Validate(stage); err != nil {
+			return "", fmt.Errorf("the updated config doesn't load, nothing was changed: %v", err)
 		}
+	}
+	return version, swapIn(opts.ConfigDir, stage, filepath.Join(opts.ConfigDir, rollbackDir))
+}
 
-		parsedUrl.Path = path.Join(parsedUrl.Path, fileName)
+// Rollback restores the rules the last update replaced, rolling back twice restores the update. It returns the
+// version of the restored rule pack.
+func Rollback(configDir string) (version string, err error) {
+	rollback := filepath.Join(configDir, rollbackDir)
+	if _, err = os.Stat(rollback); err != nil {
+		return "", errors.New("there is no earlier rule pack to roll back to")
+	}
+	replaced := filepath.Join(configDir, stagingDir)
+	if err = swapIn(configDir, rollback, replaced); err != nil {
+		return "", err
+	}
+	if err = os.RemoveAll(rollback); err != nil {
+		return "", err
+	}
+	return readManifest(configDir).Version, os.Rename(replaced, rollback)
+}
 
-		err = downloadFile(moduleFilePath, parsedUrl.String())
+// updatePack downloads the rule pack into the staged config when its signed manifest has a newer version
+func updatePack(stage string, opts Options) (version string, updated bool, err error) {
+	data, err := fetch(packURL(opts.PackURL, manifestFile))
+	if err != nil {
+		return "", false, err
+	}
+	signature, err := fetch(packURL(opts.PackURL, signatureFile))
+	if err != nil {
+		return "", false, err
+	}
+	manifest, err := verifyManifest(data, signature, opts.PublicKey)
+	if err != nil {
+		return "", false, err
+	}
+
+	installed := readManifest(stage)
+	if installed.Version != "" {
+		switch compareVersions(manifest.Version, installed.Version) {
+		case -1:
+			return "", false, fmt.Errorf("the rule pack version %s is older than the installed version %s", manifest.Version, installed.Version)
+		case 0:
+			log.Println("Rule pack", installed.Version, "is up to date")
+			return installed.Version, false, nil
+		}
+	}
+	log.Println("Updating the rule pack to version", manifest.Version)
+	err = installPack(stage, installed, manifest, data, signature, func(filePath string) ([]byte, error) {
+		return fetch(packURL(opts.PackURL, filePath))
+	})
+	return manifest.Version, err == nil, err
+}
+
+// installPack writes the files of the manifest read with read into the directory, after checking their hashes, and
+// removes the files of the previous manifest which the pack no longer has
+func installPack(dir string, previous, manifest Manifest, data, signature []byte, read func(filePath string) ([]byte, error)) error {
+	files := make(map[string]bool)
+	for _, file := range manifest.Files {
+		files[file.Path] = true
+	}
+	for _, file := range previous.Files {
+		if !files[file.Path] && checkPackPath(file.Path) == nil {
+			if err := os.Remove(filepath.Join(dir, filepath.FromSlash(file.Path))); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
 
+	for _, file := range manifest.Files {
+		content, err := read(file.Path)
 		if err != nil {
 			return err
 		}
+		if err = checkHash(content, file.SHA256); err != nil {
+			return fmt.Errorf("rule pack file %s: %v", file.Path, err)
+		}
+		filePath := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err = os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		if err = os.WriteFile(filePath, content, 0644); err != nil {
+			return fmt.Errorf("writing file at %s: %v", filePath, err)
+		}
 	}
-
-	log.Println("Updating ", appConfigPath)
-	return downloadFile(path.Join(configDir, "earlybird.json"), appConfigURL)
+	// Keep the signed manifest, it records the installed version and lets the files be checked again
+	if err := os.WriteFile(filepath.Join(dir, manifestFile), data, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, signatureFile), signature, 0644)
 }
 
-func downloadFile(path string, url string) error {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
+// readManifest reads the manifest of the installed rule pack, the manifest is empty when there is none
+func readManifest(dir string) (manifest Manifest) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err == nil {
+		_ = json.Unmarshal(data, &manifest)
+	}
+	return manifest
+}
 
-	if err != nil {
+// swapIn moves the rule pack entries of fromDir into the config directory and the ones they replace into keepDir,
+// the moves already made are undone when one fails
+func swapIn(configDir, fromDir, keepDir string) (err error) {
+	if err = os.RemoveAll(keepDir); err != nil {
+		return err
+	}
+	if err = os.Mkdir(keepDir, 0755); err != nil {
 		return err
 	}
+	type move struct{ from, to string }
+	var moves []move
+	rename := func(from, to string) error {
+		if _, statErr := os.Lstat(from); os.IsNotExist(statErr) {
+			return nil
+		}
+		if renameErr := os.Rename(from, to); renameErr != nil {
+			return renameErr
+		}
+		moves = append(moves, move{from: from, to: to})
+		return nil
+	}
+	for _, entry := range packEntries {
+		current := filepath.Join(configDir, entry)
+		if err = rename(current, filepath.Join(keepDir, entry)); err == nil {
+			err = rename(filepath.Join(fromDir, entry), current)
+		}
+		if err != nil {
+			for i := len(moves) - 1; i >= 0; i-- {
+				if undoErr := os.Rename(moves[i].to, moves[i].from); undoErr != nil {
+					log.Println("Failed to restore", moves[i].from, undoErr)
+				}
+			}
+			return fmt.Errorf("swapping in %s: %v", entry, err)
+		}
+	}
+	return nil
+}
 
-	req.Header.Add("Accept", "application/json")
-	resp, err := client.Do(req)
+// copyTree copies a file or a directory with its content, nothing is copied when it doesn't exist
+func copyTree(from, to string) error {
+	return filepath.Walk(from, func(filePath string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && filePath == from {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, filePath)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, info.Mode().Perm())
+	})
+}
+
+// hasRemoteSources is true when a rule source needs fetching
+func hasRemoteSources(sources []cfgreader.RuleSource) bool {
+	for _, source := range sources {
+		if source.Type == cfgreader.SourceGit || source.Type == cfgreader.SourceTarball {
+			return true
+		}
+	}
+	return false
+}
 
+// packURL returns the URL of a file of the rule pack
+func packURL(baseURL, filePath string) string {
+	parsedURL, err := url.Parse(baseURL)
 	if err != nil {
-		return fmt.Errorf("downloading file from %s: %v", url, err)
+		return baseURL
+	}
+	parsedURL.Path = path.Join(parsedURL.Path, filePath)
+	return parsedURL.String()
+}
+
+// fetch downloads the content at the URL
+func fetch(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("downloading file from %s: %v", url, err)
 	}
 
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("received non 200 status code: status=%d, response=%v", resp.StatusCode, string(b))
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("received non 200 status code from %s: status=%d, response=%v", url, resp.StatusCode, string(b))
 	}
 
-	b, err := io.ReadAll(resp.Body)
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %v", err)
+	}
+	if int64(len(b)) > maxDownloadSize {
+		return nil, fmt.Errorf("file at %s is larger than %d bytes", url, maxDownloadSize)
+	}
+	return b, nil
+}
 
+func downloadFile(path string, url string) error {
+	b, err := fetch(url)
 	if err != nil {
-		return fmt.Errorf("reading response body: %v", err)
+		return err
 	}
 	err = os.WriteFile(path, b, 0666)
 	if err != nil {
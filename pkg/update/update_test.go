@@ -17,7 +17,12 @@
 package configupdate
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -34,3 +39,125 @@ func Test_downloadFile(t *testing.T) {
 		os.Remove(path) //Delete downloaded file
 	}
 }
+
+// packServer serves the files of a rule pack, keyed by their path
+func packServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[strings.TrimPrefix(r.URL.Path, "/pack/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// publish signs the files as a rule pack version and serves them with their manifest
+func publish(t *testing.T, served map[string][]byte, private []byte, version string, files map[string]string) {
+	for filePath := range served {
+		delete(served, filePath)
+	}
+	data, signature := signedPack(t, private, version, files)
+	served[manifestFile], served[signatureFile] = data, signature
+	for filePath, content := range files {
+		served[filePath] = []byte(content)
+	}
+}
+
+func readFile(t *testing.T, filePath string) string {
+	t.Helper()
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return ""
+	}
+	return string(content)
+}
+
+func TestUpdate(t *testing.T) {
+	public, private := newKey(t)
+	served := make(map[string][]byte)
+	server := packServer(t, served)
+	configDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configDir, "rules"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(configDir, "rules", "content.yaml"), []byte("old"), 0644)
+	os.WriteFile(filepath.Join(configDir, "rules", "custom.yaml"), []byte("custom"), 0644)
+	opts := Options{ConfigDir: configDir, PackURL: server.URL + "/pack", PublicKey: public}
+
+	// The first update replaces the module and fetches a new one, the local module is kept
+	publish(t, served, private, "1.0.0", map[string]string{"rules/content.yaml": "v1", "rules/new.yaml": "new", "labels/labels.yaml": "labels"})
+	version, err := Update(opts)
+	if err != nil || version != "1.0.0" {
+		t.Fatalf("Update() = %v, %v, want version 1.0.0", version, err)
+	}
+	for name, want := range map[string]string{"rules/content.yaml": "v1", "rules/new.yaml": "new", "labels/labels.yaml": "labels", "rules/custom.yaml": "custom"} {
+		if got := readFile(t, filepath.Join(configDir, name)); got != want {
+			t.Errorf("Update() left %s = %q, want %q", name, got, want)
+		}
+	}
+
+	// Files which don't match the manifest, older versions and configs which don't validate change nothing
+	publish(t, served, private, "1.1.0", map[string]string{"rules/content.yaml": "v2"})
+	served["rules/content.yaml"] = []byte("tampered")
+	if _, err = Update(opts); err == nil || !strings.Contains(err.Error(), "SHA-256") {
+		t.Errorf("Update() of a changed file error = %v, want a hash mismatch", err)
+	}
+	publish(t, served, private, "0.9.0", map[string]string{"rules/content.yaml": "v0"})
+	if _, err = Update(opts); err == nil {
+		t.Errorf("Update() to an older version returned no error")
+	}
+	publish(t, served, private, "1.1.0", map[string]string{"rules/content.yaml": "v2"})
+	invalid := opts
+	invalid.Validate = func(string) error { return errors.New("invalid pattern") }
+	if _, err = Update(invalid); err == nil {
+		t.Errorf("Update() of a config which doesn't validate returned no error")
+	}
+	if got := readFile(t, filepath.Join(configDir, "rules", "content.yaml")); got != "v1" {
+		t.Errorf("Failed updates changed rules/content.yaml to %q", got)
+	}
+
+	// The next version removes the files it no longer has
+	var validated string
+	opts.Validate = func(stage string) error {
+		validated = readFile(t, filepath.Join(stage, "rules", "content.yaml"))
+		return nil
+	}
+	if version, err = Update(opts); err != nil || version != "1.1.0" {
+		t.Fatalf("Update() = %v, %v, want version 1.1.0", version, err)
+	}
+	if validated != "v2" {
+		t.Errorf("Update() validated %q, want the staged update", validated)
+	}
+	if got := readFile(t, filepath.Join(configDir, "rules", "new.yaml")); got != "" {
+		t.Errorf("Update() kept rules/new.yaml which the pack no longer has")
+	}
+	if version, err = Update(opts); err != nil || version != "1.1.0" {
+		t.Errorf("Update() to the installed version = %v, %v, want version 1.1.0", version, err)
+	}
+
+	// Rolling back restores the last replaced version, rolling back again restores the update
+	for _, want := range []struct{ version, content string }{{"1.0.0", "v1"}, {"1.1.0", "v2"}} {
+		version, err = Rollback(configDir)
+		if err != nil || version != want.version {
+			t.Fatalf("Rollback() = %v, %v, want version %s", version, err, want.version)
+		}
+		if got := readFile(t, filepath.Join(configDir, "rules", "content.yaml")); got != want.content {
+			t.Errorf("Rollback() left rules/content.yaml = %q, want %q", got, want.content)
+		}
+	}
+}
+
+func TestRollback_nothingToRestore(t *testing.T) {
+	if _, err := Rollback(t.TempDir()); err == nil {
+		t.Errorf("Rollback() without an update returned no error")
+	}
+}
+
+func TestUpdate_nothingToUpdate(t *testing.T) {
+	if _, err := Update(Options{ConfigDir: t.TempDir()}); err == nil {
+		t.Errorf("Update() without a rule pack or sources returned no error")
+	}
+}
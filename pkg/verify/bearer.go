@@ -0,0 +1,34 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package verify
+
+import (
+	"regexp"
+)
+
+var bearerTokenPattern = regexp.MustCompile(bearerTokenRegex)
+
+// Verify sends the token found in the match value as a Bearer token to the endpoint of the provider.
+// A URL next to the token in the scanned content is never used, it could send the token anywhere.
+func (b Bearer) Verify(finding Finding, opts Options) (string, error) {
+	// The token is the value of the key/value pair, i.e. the last run of token characters
+	tokens := bearerTokenPattern.FindAllString(finding.MatchValue, -1)
+	if len(tokens) == 0 || b.Endpoint == "" {
+		return StatusUnknown, nil
+	}
+	return authStatus(opts.Client, overrideEndpoint(b.Endpoint, opts.EndpointOverride), "Bearer "+tokens[len(tokens)-1])
+}
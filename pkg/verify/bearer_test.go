@@ -0,0 +1,71 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package verify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBearer_Verify(t *testing.T) {
+	// Stand-in for the npm whoami endpoint
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/-/whoami" || r.Header.Get("Authorization") != "Bearer 0b1e2d3c-4a5b-6c7d-8e9f-0a1b2c3d4e5f" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	opts := Options{EndpointOverride: server.URL, Client: server.Client()}
+	npm := Bearer{Endpoint: npmWhoamiURL}
+
+	tests := []struct {
+		name    string
+		finding Finding
+		want    string
+	}{
+		{
+			name:    "Live token",
+			finding: Finding{MatchValue: "//registry.npmjs.org/:_authToken=0b1e2d3c-4a5b-6c7d-8e9f-0a1b2c3d4e5f"},
+			want:    StatusVerified,
+		},
+		{
+			name:    "Revoked token",
+			finding: Finding{MatchValue: "//registry.npmjs.org/:_authToken=11111111-2222-3333-4444-555555555555"},
+			want:    StatusInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := npm.Verify(tt.finding, opts)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("No provider endpoint", func(t *testing.T) {
+		finding := Finding{MatchValue: "auth_token=0b1e2d3c-4a5b-6c7d-8e9f-0a1b2c3d4e5f", LineValue: "auth_token=0b1e2d3c-4a5b-6c7d-8e9f-0a1b2c3d4e5f " + server.URL + "/-/whoami"}
+		got, err := Bearer{}.Verify(finding, Options{Client: server.Client()})
+		if err != nil || got != StatusUnknown {
+			t.Errorf("Verify() = %v, %v, want %v without contacting the URL on the line", got, err, StatusUnknown)
+		}
+	})
+}
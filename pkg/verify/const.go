@@ -0,0 +1,41 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package verify
+
+import "time"
+
+const (
+	//StatusVerified means the provider accepted the credential
+	StatusVerified string = "verified"
+	//StatusInvalid means the provider rejected the credential
+	StatusInvalid string = "invalid"
+	//StatusUnknown means the credential could not be checked, e.g., the provider was unreachable
+	StatusUnknown string = "unknown"
+
+	//NpmVerifier tries npm auth tokens against the npm registry
+	NpmVerifier string = "npm"
+	//JWKSVerifier checks JWT signatures with the keys of the issuer
+	JWKSVerifier string = "jwks"
+
+	requestTimeout   time.Duration = 10 * time.Second
+	defaultRate      int           = 5
+	bearerTokenRegex string        = `[A-Za-z0-9\-._~+/]+=*`
+	jwtRegex         string        = `ey[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`
+	openIDConfigPath string        = "/.well-known/openid-configuration"
+	jwksPath         string        = "/.well-known/jwks.json"
+	npmWhoamiURL     string        = "https://registry.npmjs.org/-/whoami"
+)
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package verify
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+var jwtPattern = regexp.MustCompile(jwtRegex)
+
+// Verify checks the JWT signature against the keys its issuer (`iss` claim) publishes, when the issuer is a trusted host.
+// Tokens with a valid signature that haven't expired are verified, anything else the issuer did not sign is invalid.
+func (JWKS) Verify(finding Finding, opts Options) (string, error) {
+	rawToken := jwtPattern.FindString(finding.MatchValue)
+	if rawToken == "" {
+		return StatusUnknown, nil
+	}
+
+	unverified, _, err := jwt.NewParser().ParseUnverified(rawToken, jwt.MapClaims{})
+	if err != nil {
+		return StatusInvalid, nil
+	}
+	// Symmetric and unsigned tokens can't be checked with public keys
+	switch unverified.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA:
+	default:
+		return StatusUnknown, nil
+	}
+	issuer, err := unverified.Claims.GetIssuer()
+	if err != nil || !strings.HasPrefix(issuer, "http") {
+		return StatusUnknown, nil
+	}
+	// Anyone can mint a token naming their own server as the issuer
+	issuerURL, err := url.Parse(issuer)
+	if err != nil || !opts.trusted(issuerURL.Hostname()) {
+		return StatusUnknown, nil
+	}
+
+	keys, err := fetchJWKS(opts, overrideEndpoint(strings.TrimSuffix(issuer, "/"), opts.EndpointOverride))
+	if err != nil {
+		return StatusUnknown, err
+	}
+
+	_, err = jwt.NewParser().Parse(rawToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		for _, key := range keys.Keys {
+			if kid == "" || key.Kid == kid {
+				return key.publicKey()
+			}
+		}
+		return nil, fmt.Errorf("no key %s published by %s", kid, issuer)
+	})
+	switch {
+	case err == nil:
+		return StatusVerified, nil
+	case errors.Is(err, jwt.ErrTokenUnverifiable), errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenExpired), errors.Is(err, jwt.ErrTokenNotValidYet):
+		return StatusInvalid, nil
+	default:
+		return StatusUnknown, err
+	}
+}
+
+// fetchJWKS loads the issuer keys from the jwks_uri of its OpenID configuration, falling back to the well-known JWKS path
+func fetchJWKS(opts Options, issuer string) (keys jwkSet, err error) {
+	jwksURI := issuer + jwksPath
+	var config openIDConfig
+	if getJSON(opts.Client, issuer+openIDConfigPath, &config) == nil && config.JWKSURI != "" {
+		jwksURI = overrideEndpoint(config.JWKSURI, opts.EndpointOverride)
+	}
+	err = getJSON(opts.Client, jwksURI, &keys)
+	return keys, err
+}
+
+// getJSON decodes the JSON body of a successful GET request
+func getJSON(client *http.Client, endpoint string, v interface{}) error {
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// publicKey builds the RSA or EC public key described by the JWK
+func (key jwk) publicKey() (interface{}, error) {
+	switch key.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch key.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", key.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(key.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", key.Kty)
+	}
+}
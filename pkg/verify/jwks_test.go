@@ -0,0 +1,105 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package verify
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWKS_Verify(t *testing.T) {
+	const issuer = "https://issuer.example.com"
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stand-in issuer publishing the public half of key
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case openIDConfigPath:
+			json.NewEncoder(w).Encode(openIDConfig{JWKSURI: issuer + "/keys"})
+		case "/keys":
+			json.NewEncoder(w).Encode(jwkSet{Keys: []jwk{{
+				Kid: "key-1",
+				Kty: "RSA",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	opts := Options{EndpointOverride: server.URL, Client: server.Client()}
+
+	sign := func(method jwt.SigningMethod, signingKey interface{}, expires time.Time) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{"iss": issuer, "exp": expires.Unix()})
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(signingKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "token: " + signed
+	}
+
+	// Without the override only trusted issuers are contacted, the token names its issuer itself
+	untrusted := Options{TrustedHosts: []string{"login.example.com"}, Client: server.Client()}
+
+	tests := []struct {
+		name       string
+		matchValue string
+		opts       Options
+		want       string
+	}{
+		{name: "Signed by the issuer", matchValue: sign(jwt.SigningMethodRS256, key, time.Now().Add(time.Hour)), opts: opts, want: StatusVerified},
+		{name: "Not signed by the issuer", matchValue: sign(jwt.SigningMethodRS256, otherKey, time.Now().Add(time.Hour)), opts: opts, want: StatusInvalid},
+		{name: "Expired", matchValue: sign(jwt.SigningMethodRS256, key, time.Now().Add(-time.Hour)), opts: opts, want: StatusInvalid},
+		{name: "Symmetric signature", matchValue: sign(jwt.SigningMethodHS256, []byte("secret"), time.Now().Add(time.Hour)), opts: opts, want: StatusUnknown},
+		{name: "Issuer not trusted", matchValue: sign(jwt.SigningMethodRS256, key, time.Now().Add(time.Hour)), opts: untrusted, want: StatusUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = 0
+			got, err := JWKS{}.Verify(Finding{MatchValue: tt.matchValue}, tt.opts)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+			if tt.opts.EndpointOverride == "" && requests > 0 {
+				t.Errorf("Verify() fetched keys from an issuer that isn't trusted")
+			}
+		})
+	}
+}
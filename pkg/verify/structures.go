@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package verify
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Finding is the part of a hit a verifier needs to try the credential
+type Finding struct {
+	Code int
+	// Verifier is the name of the verifier the rule of the hit asks for
+	Verifier   string
+	MatchValue string
+	LineValue  string
+}
+
+// Options are shared by every verifier during a scan
+type Options struct {
+	// EndpointOverride replaces the scheme and host of every endpoint a verifier contacts, e.g., to test against a local stand-in server
+	EndpointOverride string
+	// TrustedHosts are the hosts a verifier may send a credential to when the scanned content names the host, e.g., a JWT issuer
+	TrustedHosts []string
+	Client       *http.Client
+}
+
+// Verifier tries a found credential against its provider and returns one of the Status values
+type Verifier interface {
+	Verify(finding Finding, opts Options) (status string, err error)
+}
+
+// Checker runs the registered verifiers at a limited rate and remembers the result for each credential
+type Checker struct {
+	opts     Options
+	verbose  bool
+	interval time.Duration
+	mutex    sync.Mutex
+	next     time.Time
+	results  map[string]string
+}
+
+// Bearer verifies tokens as `Authorization: Bearer` against Endpoint
+type Bearer struct {
+	Endpoint string
+}
+
+// JWKS verifies the signature of a JWT with the keys published by its issuer, when the issuer is a trusted host
+type JWKS struct{}
+
+// jwkSet is the JSON Web Key Set published by a JWT issuer
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// jwk is a single RSA or EC public key in a JSON Web Key Set
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// openIDConfig is the part of the OpenID provider metadata pointing at the issuer keys
+type openIDConfig struct {
+	JWKSURI string `json:"jwks_uri"`
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package verify
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	registryMutex = &sync.RWMutex{}
+	//registry holds the verifiers by the name rules refer to them with in their Verifier field
+	registry = map[string]Verifier{
+		NpmVerifier:  Bearer{Endpoint: npmWhoamiURL},
+		JWKSVerifier: JWKS{},
+	}
+)
+
+// Register plugs a verifier in under a name rules can refer to, replacing any verifier already registered with it
+func Register(name string, verifier Verifier) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[name] = verifier
+}
+
+// Registered reports whether a verifier is registered with the name
+func Registered(name string) bool {
+	_, ok := lookup(name)
+	return ok
+}
+
+// lookup returns the verifier registered with a name
+func lookup(name string) (verifier Verifier, ok bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	verifier, ok = registry[name]
+	return verifier, ok
+}
+
+// NewChecker creates a Checker sending at most rate verification requests per second.
+// Verifiers which contact a host named in the scanned content only do so for the trustedHosts.
+func NewChecker(endpointOverride string, trustedHosts []string, rate int, verbose bool) *Checker {
+	if rate <= 0 {
+		rate = defaultRate
+	}
+	return &Checker{
+		opts: Options{
+			EndpointOverride: endpointOverride,
+			TrustedHosts:     trustedHosts,
+			Client:           &http.Client{Timeout: requestTimeout},
+		},
+		verbose:  verbose,
+		interval: time.Second / time.Duration(rate),
+		results:  make(map[string]string),
+	}
+}
+
+// Check verifies the finding with the verifier its rule names.
+// It returns an empty status when the rule names no verifier or one which isn't registered.
+func (c *Checker) Check(finding Finding) string {
+	if finding.Verifier == "" {
+		return ""
+	}
+	verifier, ok := lookup(finding.Verifier)
+	if !ok {
+		if c.verbose {
+			log.Println("No verifier", finding.Verifier, "registered for rule", finding.Code)
+		}
+		return ""
+	}
+
+	// The same secret is often found several times, only try it once
+	key := finding.Verifier + ":" + strconv.Itoa(finding.Code) + ":" + finding.MatchValue
+	c.mutex.Lock()
+	status, done := c.results[key]
+	c.mutex.Unlock()
+	if done {
+		return status
+	}
+
+	c.wait()
+	status, err := verifier.Verify(finding, c.opts)
+	if err != nil {
+		if c.verbose {
+			log.Println("Failed to verify hit for rule", finding.Code, err)
+		}
+		status = StatusUnknown
+	}
+
+	c.mutex.Lock()
+	c.results[key] = status
+	c.mutex.Unlock()
+	return status
+}
+
+// wait blocks until the rate limit allows another verification request
+func (c *Checker) wait() {
+	c.mutex.Lock()
+	now := time.Now()
+	if c.next.Before(now) {
+		c.next = now
+	}
+	delay := c.next.Sub(now)
+	c.next = c.next.Add(c.interval)
+	c.mutex.Unlock()
+	time.Sleep(delay)
+}
+
+// overrideEndpoint replaces the scheme and host of the endpoint with those of the override, keeping the path
+func overrideEndpoint(endpoint, override string) string {
+	if override == "" {
+		return endpoint
+	}
+	if endpoint == "" {
+		return override
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return override
+	}
+	overrideURL, err := url.Parse(override)
+	if err != nil || overrideURL.Host == "" {
+		return endpoint
+	}
+	endpointURL.Scheme = overrideURL.Scheme
+	endpointURL.Host = overrideURL.Host
+	return endpointURL.String()
+}
+
+// trusted reports whether a host named in the scanned content may be contacted. Only the hosts the operator trusts
+// are, or every host when all requests go to the endpoint override anyway.
+func (opts Options) trusted(host string) bool {
+	if opts.EndpointOverride != "" {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, trustedHost := range opts.TrustedHosts {
+		if strings.ToLower(trustedHost) == host {
+			return true
+		}
+	}
+	return false
+}
+
+// authStatus sends a GET request with the authorization header and maps the response code to a status
+func authStatus(client *http.Client, endpoint, authorization string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return StatusUnknown, err
+	}
+	req.Header.Set("Authorization", authorization)
+	resp, err := client.Do(req)
+	if err != nil {
+		return StatusUnknown, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return StatusVerified, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return StatusInvalid, nil
+	default:
+		return StatusUnknown, nil
+	}
+}
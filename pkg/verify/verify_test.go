@@ -0,0 +1,105 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package verify
+
+import (
+	"testing"
+	"time"
+)
+
+type countingVerifier struct {
+	calls int
+}
+
+func (v *countingVerifier) Verify(finding Finding, opts Options) (string, error) {
+	v.calls++
+	return StatusVerified, nil
+}
+
+func TestChecker_Check(t *testing.T) {
+	counter := &countingVerifier{}
+	Register("counting", counter)
+	defer func() {
+		registryMutex.Lock()
+		delete(registry, "counting")
+		registryMutex.Unlock()
+	}()
+
+	checker := NewChecker("", nil, 20, false)
+	start := time.Now()
+	finding := Finding{Code: 9999, Verifier: "counting", MatchValue: "secret"}
+	if got := checker.Check(finding); got != StatusVerified {
+		t.Errorf("Check() = %v, want %v", got, StatusVerified)
+	}
+	// The same secret is answered from the results, a different one waits for the rate limit
+	checker.Check(finding)
+	checker.Check(Finding{Code: 9999, Verifier: "counting", MatchValue: "other"})
+	if counter.calls != 2 {
+		t.Errorf("Check() called the verifier %d times, want 2", counter.calls)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Check() sent 2 requests in %v, want at most 20 per second", elapsed)
+	}
+
+	if got := checker.Check(Finding{Code: 1}); got != "" {
+		t.Errorf("Check() for a rule without a verifier = %v, want no status", got)
+	}
+	if got := checker.Check(Finding{Code: 1, Verifier: "unknown", MatchValue: "secret"}); got != "" {
+		t.Errorf("Check() without a registered verifier = %v, want no status", got)
+	}
+}
+
+func TestOptions_trusted(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		host string
+		want bool
+	}{
+		{name: "Trusted host", opts: Options{TrustedHosts: []string{"login.example.com"}}, host: "Login.Example.com", want: true},
+		{name: "Host named in the content", opts: Options{TrustedHosts: []string{"login.example.com"}}, host: "attacker.example", want: false},
+		{name: "Nothing trusted", opts: Options{}, host: "login.example.com", want: false},
+		{name: "Endpoint override", opts: Options{EndpointOverride: "http://127.0.0.1:8080"}, host: "attacker.example", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.trusted(tt.host); got != tt.want {
+				t.Errorf("trusted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_overrideEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		override string
+		want     string
+	}{
+		{name: "No override", endpoint: "https://api.example.com/v1/me", override: "", want: "https://api.example.com/v1/me"},
+		{name: "Keep the path", endpoint: "https://api.example.com/v1/me?x=1", override: "http://127.0.0.1:8080", want: "http://127.0.0.1:8080/v1/me?x=1"},
+		{name: "No endpoint", endpoint: "", override: "http://127.0.0.1:8080", want: "http://127.0.0.1:8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overrideEndpoint(tt.endpoint, tt.override); got != tt.want {
+				t.Errorf("overrideEndpoint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
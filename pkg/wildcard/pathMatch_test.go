@@ -0,0 +1,48 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package wildcard
+
+import "testing"
+
+func TestPathMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"test/fixtures.py", "test/fixtures.py", true},
+		{"test/*.py", "test/fixtures.py", true},
+		{"test/*.py", "test/unit/fixtures.py", false},
+		{"test/**/*.py", "test/fixtures.py", true},
+		{"test/**/*.py", "test/unit/fixtures.py", true},
+		{"**/secrets.env", "secrets.env", true},
+		{"**/secrets.env", "deploy/dev/secrets.env", true},
+		{"**", "any/file", true},
+		{"build/**", "build/out/app", true},
+		{"build/**", "build", false},
+		{"/test/*", "test/a", true},
+		{"*.py", "src/app.py", false},
+		{"src/[^a]*.go", "src/main.go", true},
+		{"src/[^m]*.go", "src/main.go", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if got := PathMatch(tt.pattern, tt.name); got != tt.want {
+				t.Errorf("PathMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
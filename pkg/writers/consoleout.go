@@ -137,6 +137,14 @@ func hitToConsole(hit scan.Hit, progress int, showFullLine bool) string {
 	if hit.Solution != "" {
 		sb.WriteString(outputIndent + columnSolution + ": " + hit.Solution)
 	}
+	if hit.Commit != "" {
+		sb.WriteString(outputIndent + columnCommit + ": " + hit.Commit)
+		sb.WriteString(outputIndent + columnAuthor + ": " + displayAuthor(hit.Author, hit.AuthorEmail))
+		sb.WriteString(outputIndent + columnCommitDate + ": " + hit.CommitDate)
+	}
+	if hit.Verified != "" {
+		sb.WriteString(outputIndent + columnVerified + ": " + hit.Verified)
+	}
 	sb.WriteString("\n")
 	return sb.String()
 }
@@ -150,6 +158,13 @@ func displayCWE(cwe []string) (result string) {
 	return result
 }
 
+func displayAuthor(author, email string) string {
+	if email == "" {
+		return author
+	}
+	return author + " <" + email + ">"
+}
+
 // strip control characters, DELETE, and non-ASCII unicode from the string.
 func printableASCII(s string) string {
 	printable := make([]byte, 0, len(s))
@@ -31,9 +31,26 @@ const (
 	columnLabels         string = "Labels"
 	columnCWE            string = "Associated CWEs"
 	columnSolution       string = "Solution"
+	columnCommit         string = "Commit"
+	columnAuthor         string = "Author"
+	columnCommitDate     string = "Commit Date"
+	columnVerified       string = "Verified"
 	outputTotalIssuesFnd string = "\t***** Total issues found *****"
 	outputTotalIssues    string = "\t%5d TOTAL ISSUES\n"
 	outputBytesWritten   string = " bytes written to "
 	outputIndent         string = "\n\t"
 	outputNone           string = "None"
+	sarifSchema          string = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion         string = "2.1.0"
+	sarifToolName        string = "go-earlybird"
+	sarifToolURI         string = "https://github.com/americanexpress/earlybird"
+	sarifSrcRoot         string = "SRCROOT"
+	sarifLevelError      string = "error"
+	sarifLevelWarning    string = "warning"
+	sarifLevelNote       string = "note"
+	sarifLevelNone       string = "none"
+	profileTopRules      int    = 10
+	profileExpensive     string = "\t***** Most expensive rules *****"
+	profileNoisiest      string = "\t***** Noisiest rules (matches removed by false positive rules) *****"
+	profileHeader        string = "Code\tCaption\tEvaluations\tMatch time\tAvg per line\tMatches\tHits\tFalse positives\tFP rate"
 )
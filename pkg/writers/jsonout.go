@@ -27,7 +27,8 @@ import (
 )
 
 // WriteJSON takes the hits, converts them into JSON report and passing report to reportToJSONWriter().
-func WriteJSON(hits <-chan scan.Hit, config cfgReader.EarlybirdConfig, fileContext file.Context, fileName string) (err error) {
+// The config is read once the hits channel is closed, so the report reflects how the scan ended.
+func WriteJSON(hits <-chan scan.Hit, config *cfgReader.EarlybirdConfig, fileContext file.Context, fileName string) (err error) {
 	start := time.Now()
 	var Hits []scan.Hit
 	for hit := range hits {
@@ -37,16 +38,19 @@ func WriteJSON(hits <-chan scan.Hit, config cfgReader.EarlybirdConfig, fileConte
 	report := scan.Report{
 		Hits:          Hits,
 		HitCount:      len(Hits),
-		Skipped:       fileContext.SkippedFiles,
+		Truncated:     config.ScanTruncated,
+		Commits:       scan.HitCommits(Hits),
+		Skipped:       fileContext.Skipped(),
 		Ignore:        fileContext.IgnorePatterns,
 		Version:       config.Version,
 		Modules:       config.EnabledModules,
 		Threshold:     config.SeverityDisplayLevel,
-		FilesScanned:  len(fileContext.Files),
+		FilesScanned:  fileContext.FilesScanned(),
 		RulesObserved: len(scan.CombinedRules),
 		StartTime:     start.UTC().Format(time.RFC3339),
 		EndTime:       time.Now().UTC().Format(time.RFC3339),
 		Duration:      fmt.Sprintf("%d ms", time.Since(start)/time.Millisecond),
+		Suppressed:    fileContext.Suppressions.Entries(),
 	}
 	_, err = reportToJSONWriter(report, fileName)
 	return err
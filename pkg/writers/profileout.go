@@ -0,0 +1,98 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package writers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+)
+
+// ruleProfileReport is the JSON form of a rule profile
+type ruleProfileReport struct {
+	Rules     []scan.RuleStats `json:"rules"`
+	Noisiest  []scan.RuleStats `json:"noisiest"`
+	TotalTime time.Duration    `json:"total_match_time_ns"`
+}
+
+// WriteRuleProfile writes the rules that cost the most time and the rules whose matches were mostly false positives,
+// as JSON when the format is json and as tables otherwise. The stats are expected in the order RuleProfile.Stats returns them.
+func WriteRuleProfile(stats []scan.RuleStats, format string, w io.Writer) error {
+	noisiest := noisiestRules(stats)
+	if format == "json" {
+		report := ruleProfileReport{Rules: stats, Noisiest: noisiest}
+		for _, s := range stats {
+			report.TotalTime += s.MatchTime
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(report)
+	}
+
+	if err := writeProfileTable(w, profileExpensive, stats); err != nil {
+		return err
+	}
+	return writeProfileTable(w, profileNoisiest, noisiest)
+}
+
+// noisiestRules returns the rules with false positives, the largest share of false positives first
+func noisiestRules(stats []scan.RuleStats) []scan.RuleStats {
+	noisiest := []scan.RuleStats{}
+	for _, s := range stats {
+		if s.FalsePositives > 0 {
+			noisiest = append(noisiest, s)
+		}
+	}
+	sort.SliceStable(noisiest, func(i, j int) bool {
+		if noisiest[i].FalsePositiveRate() != noisiest[j].FalsePositiveRate() {
+			return noisiest[i].FalsePositiveRate() > noisiest[j].FalsePositiveRate()
+		}
+		return noisiest[i].FalsePositives > noisiest[j].FalsePositives
+	})
+	return noisiest
+}
+
+// writeProfileTable writes the first profileTopRules rules under the title
+func writeProfileTable(w io.Writer, title string, stats []scan.RuleStats) error {
+	if len(stats) > profileTopRules {
+		stats = stats[:profileTopRules]
+	}
+	if _, err := fmt.Fprintf(w, "\n%s\n\n", title); err != nil {
+		return err
+	}
+	if len(stats) == 0 {
+		_, err := fmt.Fprintf(w, "\t%s\n", outputNone)
+		return err
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, profileHeader)
+	for _, s := range stats {
+		var average time.Duration
+		if s.Evaluations > 0 {
+			average = s.MatchTime / time.Duration(s.Evaluations)
+		}
+		fmt.Fprintf(table, "%d\t%s\t%d\t%s\t%s\t%d\t%d\t%d\t%.0f%%\n", s.Code, s.Caption, s.Evaluations, s.MatchTime.Round(time.Microsecond),
+			average, s.Matches, s.Hits, s.FalsePositives, s.FalsePositiveRate()*100)
+	}
+	return table.Flush()
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package writers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+)
+
+func TestWriteRuleProfile(t *testing.T) {
+	stats := []scan.RuleStats{
+		{Code: 3013, Caption: "Slow password rule", Evaluations: 100, MatchTime: 40 * time.Millisecond, Matches: 10, Hits: 9, FalsePositives: 1},
+		{Code: 3014, Caption: "Noisy secret rule", Evaluations: 100, MatchTime: 2 * time.Millisecond, Matches: 10, Hits: 1, FalsePositives: 9},
+		{Code: 3015, Caption: "Quiet rule", Evaluations: 100, MatchTime: time.Millisecond},
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		check  func(t *testing.T, out string)
+	}{
+		{
+			name:   "Tables ranked by cost and false positive rate",
+			format: "console",
+			check: func(t *testing.T, out string) {
+				expensive := strings.Index(out, profileExpensive)
+				noisiest := strings.Index(out, profileNoisiest)
+				if expensive < 0 || noisiest < expensive {
+					t.Fatalf("WriteRuleProfile() is missing a table:\n%s", out)
+				}
+				if strings.Index(out[noisiest:], "3014") > strings.Index(out[noisiest:], "3013") {
+					t.Errorf("WriteRuleProfile() didn't rank the noisiest rule first:\n%s", out)
+				}
+				if strings.Contains(out[noisiest:], "3015") {
+					t.Errorf("WriteRuleProfile() listed a rule without false positives as noisy:\n%s", out)
+				}
+			},
+		},
+		{
+			name:   "JSON report",
+			format: "json",
+			check: func(t *testing.T, out string) {
+				var report ruleProfileReport
+				if err := json.Unmarshal([]byte(out), &report); err != nil {
+					t.Fatalf("WriteRuleProfile() wrote invalid JSON: %v", err)
+				}
+				if len(report.Rules) != 3 || len(report.Noisiest) != 2 || report.Noisiest[0].Code != 3014 {
+					t.Errorf("WriteRuleProfile() = %+v, want all rules and 3014 as the noisiest", report)
+				}
+				if report.TotalTime != 43*time.Millisecond {
+					t.Errorf("WriteRuleProfile() total time = %v, want 43ms", report.TotalTime)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteRuleProfile(stats, tt.format, &b); err != nil {
+				t.Fatalf("WriteRuleProfile() error = %v", err)
+			}
+			tt.check(t, b.String())
+		})
+	}
+}
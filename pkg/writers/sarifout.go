@@ -0,0 +1,250 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package writers
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string                     `json:"name"`
+	Version        string                     `json:"version,omitempty"`
+	InformationURI string                     `json:"informationUri"`
+	Rules          []sarifReportingDescriptor `json:"rules"`
+}
+
+type sarifReportingDescriptor struct {
+	ID               string                 `json:"id"`
+	Name             string                 `json:"name,omitempty"`
+	ShortDescription sarifMessage           `json:"shortDescription"`
+	Help             *sarifMessage          `json:"help,omitempty"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	RuleIndex  int                    `json:"ruleIndex"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int           `json:"startLine"`
+	Snippet   *sarifMessage `json:"snippet,omitempty"`
+}
+
+// WriteSARIF converts the hits into a SARIF 2.1.0 log, using the rules of the scan with their solutions as the tool's rule table
+func WriteSARIF(hits <-chan scan.Hit, config cfgReader.EarlybirdConfig, rules []scan.Rule, solutions map[int]scan.Solution, fileName string) (err error) {
+	var Hits []scan.Hit
+	for hit := range hits {
+		Hits = append(Hits, hit)
+	}
+
+	_, err = reportToJSONWriter(hitsToSARIF(Hits, config, rules, solutions), fileName)
+	return err
+}
+
+// hitsToSARIF builds the SARIF log with one reporting descriptor per rule code and one result per hit
+func hitsToSARIF(hits []scan.Hit, config cfgReader.EarlybirdConfig, rules []scan.Rule, solutions map[int]scan.Solution) sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           sarifToolName,
+				Version:        config.Version,
+				InformationURI: sarifToolURI,
+				Rules:          make([]sarifReportingDescriptor, 0, len(rules)),
+			},
+		},
+		Results: make([]sarifResult, 0, len(hits)),
+	}
+
+	ruleIndex := make(map[int]int)
+	names := make(map[string]bool)
+	for _, rule := range rules {
+		if _, ok := ruleIndex[rule.Code]; ok {
+			continue
+		}
+		ruleIndex[rule.Code] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, ruleToDescriptor(rule, solutions, names))
+	}
+
+	if config.SearchDir != "" {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLocation{
+			sarifSrcRoot: {URI: "file://" + filepath.ToSlash(strings.TrimSuffix(config.SearchDir, string(filepath.Separator))) + "/"},
+		}
+	}
+
+	for _, hit := range hits {
+		index, ok := ruleIndex[hit.Code]
+		if !ok {
+			// Filename rules and rules loaded after the table was built still need a descriptor to point at
+			index = len(run.Tool.Driver.Rules)
+			ruleIndex[hit.Code] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, ruleToDescriptor(scan.Rule{Code: hit.Code, Caption: hit.Caption, Category: hit.Category, CWE: hit.CWE}, solutions, names))
+		}
+		run.Results = append(run.Results, hitToSARIFResult(hit, index, config.SearchDir))
+	}
+
+	return sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}
+}
+
+// ruleToDescriptor describes the rule, its solution comes from the rule or else from the solutions of the scan.
+// The names already given to other rules are in names, a rule whose caption gives a taken name is told apart by its code.
+func ruleToDescriptor(rule scan.Rule, solutions map[int]scan.Solution, names map[string]bool) sarifReportingDescriptor {
+	name := ruleName(rule.Caption)
+	if name == "" || names[name] {
+		name += "Rule" + strconv.Itoa(rule.Code)
+	}
+	names[name] = true
+	descriptor := sarifReportingDescriptor{
+		ID:               strconv.Itoa(rule.Code),
+		Name:             name,
+		ShortDescription: sarifMessage{Text: rule.Caption},
+		Properties: map[string]interface{}{
+			"category": rule.Category,
+		},
+	}
+	if len(rule.CWE) > 0 {
+		descriptor.Properties["cwe"] = rule.CWE
+		descriptor.Properties["tags"] = append([]string{"security"}, rule.CWE...)
+	}
+
+	solution := rule.Solution
+	if solution == "" {
+		solution = solutions[rule.SolutionID].Text
+	}
+	if solution != "" {
+		descriptor.Help = &sarifMessage{Text: solution}
+	}
+	return descriptor
+}
+
+// ruleName turns the caption of a rule into the PascalCase identifier SARIF expects as a rule name,
+// e.g. PotentialPasswordInFile
+func ruleName(caption string) string {
+	var name strings.Builder
+	for _, word := range strings.FieldsFunc(caption, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		runes := []rune(word)
+		name.WriteRune(unicode.ToUpper(runes[0]))
+		name.WriteString(string(runes[1:]))
+	}
+	return name.String()
+}
+
+func hitToSARIFResult(hit scan.Hit, ruleIndex int, searchDir string) sarifResult {
+	location := sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(hit.Filename)},
+	}
+	if searchDir != "" {
+		if rel, err := filepath.Rel(searchDir, hit.Filename); err == nil && !strings.HasPrefix(rel, "..") {
+			location.ArtifactLocation = sarifArtifactLocation{URI: filepath.ToSlash(rel), URIBaseID: sarifSrcRoot}
+		}
+	}
+	// Filename hits are reported on line 0, which SARIF does not allow in a region
+	if hit.Line > 0 {
+		location.Region = &sarifRegion{StartLine: hit.Line}
+		if hit.LineValue != "" {
+			location.Region.Snippet = &sarifMessage{Text: hit.LineValue}
+		}
+	}
+
+	properties := map[string]interface{}{
+		"severity":   hit.Severity,
+		"confidence": hit.Confidence,
+	}
+	if len(hit.Labels) > 0 {
+		properties["labels"] = hit.Labels
+	}
+	if hit.Commit != "" {
+		properties["commit"] = hit.Commit
+		properties["author"] = hit.Author
+		properties["author_email"] = hit.AuthorEmail
+		properties["commit_date"] = hit.CommitDate
+	}
+	if hit.Verified != "" {
+		properties["verified"] = hit.Verified
+	}
+
+	return sarifResult{
+		RuleID:     strconv.Itoa(hit.Code),
+		RuleIndex:  ruleIndex,
+		Level:      sarifLevel(hit.SeverityID),
+		Message:    sarifMessage{Text: hit.Caption},
+		Locations:  []sarifLocation{{PhysicalLocation: location}},
+		Properties: properties,
+	}
+}
+
+// sarifLevel maps the Earlybird severity ID onto the SARIF result level
+func sarifLevel(severityID int) string {
+	switch {
+	case severityID <= 0:
+		return sarifLevelNone
+	case severityID <= 2: // critical, high
+		return sarifLevelError
+	case severityID == 3: // medium
+		return sarifLevelWarning
+	default: // low, info
+		return sarifLevelNote
+	}
+}
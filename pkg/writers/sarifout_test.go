@@ -0,0 +1,122 @@
+/*
+ * Copyright 2021 American Express
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package writers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	cfgReader "github.com/americanexpress/earlybird/v4/pkg/config"
+	"github.com/americanexpress/earlybird/v4/pkg/scan"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	HitChan := make(chan scan.Hit)
+	go func() {
+		HitChan <- scan.Hit{
+			Code:       3003,
+			Line:       1,
+			Filename:   "sample.py",
+			MatchValue: "tomcat_password = '123'",
+			SeverityID: 2,
+		}
+		close(HitChan)
+	}()
+
+	rules := []scan.Rule{{Code: 3003, Caption: "Potential password", Category: "password-secret", CWE: []string{"CWE-798"}}}
+	if err := WriteSARIF(HitChan, cfgReader.EarlybirdConfig{Version: "Test 1.0"}, rules, nil, ""); err != nil {
+		t.Errorf("WriteSARIF() error = %v", err)
+	}
+}
+
+func Test_hitsToSARIF(t *testing.T) {
+	config := cfgReader.EarlybirdConfig{Version: "Test 1.0", SearchDir: "/tmp/repo"}
+	rules := []scan.Rule{
+		{Code: 3003, Caption: "Potential password", Category: "password-secret", CWE: []string{"CWE-798"}, Solution: "Remove it"},
+		{Code: 3004, Caption: "Potential key", Category: "key", SolutionID: 2},
+		{Code: 3005, Caption: "Potential key", Category: "key"},
+	}
+	solutions := map[int]scan.Solution{2: {Text: "Rotate the key"}}
+	hits := []scan.Hit{
+		{Code: 3004, Line: 12, Filename: "/tmp/repo/src/main.go", SeverityID: 3, Labels: []string{"db"}},
+		{Code: 7001, Line: 0, Filename: "/tmp/repo/id_rsa", SeverityID: 1, Caption: "Private key file"},
+	}
+
+	log := hitsToSARIF(hits, config, rules, solutions)
+	b, err := json.Marshal(log)
+	if err != nil {
+		t.Fatalf("hitsToSARIF() produced a log that failed to encode: %v", err)
+	}
+	var js map[string]interface{}
+	if json.Unmarshal(b, &js) != nil || js["version"] != sarifVersion {
+		t.Fatalf("hitsToSARIF() = %s, want a SARIF %s log", b, sarifVersion)
+	}
+
+	run := log.Runs[0]
+	if got, want := len(run.Tool.Driver.Rules), 4; got != want {
+		t.Errorf("hitsToSARIF() rule table has %d descriptors, want %d", got, want)
+	}
+	if run.Tool.Driver.Rules[0].Help == nil || run.Tool.Driver.Rules[0].Help.Text != "Remove it" {
+		t.Errorf("hitsToSARIF() did not carry the rule solution into help: %+v", run.Tool.Driver.Rules[0])
+	}
+	if run.Tool.Driver.Rules[1].Help == nil || run.Tool.Driver.Rules[1].Help.Text != "Rotate the key" {
+		t.Errorf("hitsToSARIF() did not carry the solution of the scan into help: %+v", run.Tool.Driver.Rules[1])
+	}
+	var names []string
+	for _, rule := range run.Tool.Driver.Rules {
+		names = append(names, rule.Name)
+	}
+	if want := []string{"PotentialPassword", "PotentialKey", "PotentialKeyRule3005", "PrivateKeyFile"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("hitsToSARIF() rule names = %v, want %v", names, want)
+	}
+
+	first := run.Results[0]
+	if first.RuleIndex != 1 || first.Level != sarifLevelWarning {
+		t.Errorf("hitsToSARIF() first result = %+v, want rule index 1 at level %s", first, sarifLevelWarning)
+	}
+	location := first.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "src/main.go" || location.ArtifactLocation.URIBaseID != sarifSrcRoot || location.Region.StartLine != 12 {
+		t.Errorf("hitsToSARIF() first location = %+v, want src/main.go line 12 relative to %s", location, sarifSrcRoot)
+	}
+
+	second := run.Results[1]
+	if second.RuleIndex != 3 || second.Level != sarifLevelError || second.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("hitsToSARIF() filename result = %+v, want rule index 3 at level %s without a region", second, sarifLevelError)
+	}
+}
+
+func Test_sarifLevel(t *testing.T) {
+	tests := []struct {
+		name       string
+		severityID int
+		want       string
+	}{
+		{name: "critical", severityID: 1, want: sarifLevelError},
+		{name: "high", severityID: 2, want: sarifLevelError},
+		{name: "medium", severityID: 3, want: sarifLevelWarning},
+		{name: "low", severityID: 4, want: sarifLevelNote},
+		{name: "info", severityID: 5, want: sarifLevelNote},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sarifLevel(tt.severityID); got != tt.want {
+				t.Errorf("sarifLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}